@@ -0,0 +1,318 @@
+// Command authpolicy checks a basicauth config against a table of requests and explains its decisions.
+//
+//	authpolicy test -config config.json -cases cases.json
+//	authpolicy explain -config config.json -user admin -password secret GET https://admin.example.com/users
+//
+// The config is the JSON of basicauth.Config, the same for the gin and gorilla middlewares.
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golanguzb70/middleware/gorilla/basicauth"
+)
+
+// Case is a request and the status the config is expected to answer it with.
+type Case struct {
+	Name   string `json:"name"`
+	Method string `json:"method"`
+	// Path of the request, it may have a query.
+	Path string `json:"path"`
+	// Host of the request. Default is localhost.
+	Host string `json:"host"`
+	// Scheme is http (default) or https.
+	Scheme   string            `json:"scheme"`
+	User     string            `json:"user"`
+	Password string            `json:"password"`
+	Headers  map[string]string `json:"headers"`
+	// RemoteAddr is the address of the peer. Default is 192.0.2.1:1234.
+	RemoteAddr string `json:"remote_addr"`
+	// Status is the expected status: 200, 401, 403 or 407.
+	Status int `json:"status"`
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: authpolicy test|explain [flags]")
+		return 2
+	}
+
+	switch args[0] {
+	case "test":
+		return runTest(args[1:], stdout, stderr)
+	case "explain":
+		return runExplain(args[1:], stdout, stderr)
+	}
+	fmt.Fprintf(stderr, "authpolicy: unknown command %q\n", args[0])
+	return 2
+}
+
+func runTest(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", "", "basicauth config `file`")
+	casesPath := flags.String("cases", "", "test cases `file`")
+	now := flags.String("now", "", "evaluate access windows at this RFC 3339 `time`")
+	verbose := flags.Bool("v", false, "print passing cases too")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := loadConfig(*configPath, *now)
+	if err != nil {
+		fmt.Fprintln(stderr, "authpolicy:", err)
+		return 2
+	}
+	var cases []Case
+	if err := readJSON(*casesPath, &cases); err != nil {
+		fmt.Fprintln(stderr, "authpolicy:", err)
+		return 2
+	}
+
+	failed := 0
+	for i, c := range cases {
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprintf("case %d", i)
+		}
+		r, err := c.request(cfg.ProxyAuth)
+		if err != nil {
+			fmt.Fprintf(stderr, "authpolicy: %s: %v\n", name, err)
+			return 2
+		}
+
+		d := cfg.Decide(r)
+		if d.Status != c.Status {
+			failed++
+			fmt.Fprintf(stdout, "FAIL %s: %s %s%s: want %d, got %d (%s)\n", name, r.Method, r.Host, r.URL.RequestURI(), c.Status, d.Status, why(d))
+		} else if *verbose {
+			fmt.Fprintf(stdout, "ok   %s: %s %s%s: %d\n", name, r.Method, r.Host, r.URL.RequestURI(), d.Status)
+		}
+	}
+
+	fmt.Fprintf(stdout, "%d cases, %d failed\n", len(cases), failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func runExplain(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("explain", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", "", "basicauth config `file`")
+	now := flags.String("now", "", "evaluate access windows at this RFC 3339 `time`")
+	c := Case{}
+	flags.StringVar(&c.User, "user", "", "user `name` of Basic credentials")
+	flags.StringVar(&c.Password, "password", "", "`password` of Basic credentials")
+	flags.StringVar(&c.RemoteAddr, "remote-addr", "", "`address` of the peer")
+	headers := headerFlag{}
+	flags.Var(headers, "header", "request `header` as Name: value, may be repeated")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		fmt.Fprintln(stderr, "usage: authpolicy explain -config file [flags] METHOD URL")
+		return 2
+	}
+
+	cfg, err := loadConfig(*configPath, *now)
+	if err != nil {
+		fmt.Fprintln(stderr, "authpolicy:", err)
+		return 2
+	}
+
+	c.Method = flags.Arg(0)
+	c.Headers = headers
+	if err := c.setURL(flags.Arg(1)); err != nil {
+		fmt.Fprintln(stderr, "authpolicy:", err)
+		return 2
+	}
+	r, err := c.request(cfg.ProxyAuth)
+	if err != nil {
+		fmt.Fprintln(stderr, "authpolicy:", err)
+		return 2
+	}
+
+	explain(stdout, cfg, r, cfg.Decide(r))
+	return 0
+}
+
+// explain prints the decision with the parts of the config it is based on.
+func explain(w io.Writer, cfg basicauth.Config, r *http.Request, d *basicauth.Decision) {
+	fmt.Fprintf(w, "request:     %s %s://%s%s\n", r.Method, scheme(r), r.Host, r.URL.RequestURI())
+
+	if d.Realm != "" {
+		for _, realm := range cfg.Realms {
+			if realm.Name == d.Realm {
+				fmt.Fprintf(w, "realm:       %s, path prefixes %s\n", realm.Name, strings.Join(realm.PathPrefixes, ", "))
+			}
+		}
+	} else {
+		fmt.Fprintln(w, "realm:       none, users of the config")
+	}
+
+	switch {
+	case d.Restriction == "":
+		fmt.Fprintln(w, "restriction: none, the request need not be authenticated")
+	case strings.HasPrefix(d.Restriction, "restricted_rules["):
+		var i int
+		fmt.Sscanf(d.Restriction, "restricted_rules[%d]", &i)
+		fmt.Fprintf(w, "restriction: %s %s\n", d.Restriction, toJSON(cfg.RestrictedRules[i]))
+	default:
+		fmt.Fprintf(w, "restriction: %s\n", d.Restriction)
+	}
+
+	switch {
+	case d.User != "":
+		fmt.Fprintf(w, "user:        %s\n", d.User)
+	case d.Restriction != "" && d.Status != http.StatusOK:
+		fmt.Fprintf(w, "user:        not authenticated, %s\n", d.Reason)
+	default:
+		fmt.Fprintln(w, "user:        anonymous")
+	}
+
+	if d.Rule >= 0 {
+		fmt.Fprintf(w, "access rule: %d %s, closed at %s\n", d.Rule, toJSON(cfg.AccessRules[d.Rule]), cfg.Now().Format(time.RFC3339))
+	} else {
+		fmt.Fprintln(w, "access rule: none rejects the request")
+	}
+
+	fmt.Fprintf(w, "decision:    %s\n", d)
+}
+
+func why(d *basicauth.Decision) string {
+	switch {
+	case d.Rule >= 0:
+		return fmt.Sprintf("access rule %d", d.Rule)
+	case d.Restriction == "":
+		return "not restricted"
+	case d.Status != http.StatusOK:
+		return d.Restriction + ": " + d.Reason
+	}
+	return d.Restriction + ": authenticated as " + d.User
+}
+
+func loadConfig(path, now string) (basicauth.Config, error) {
+	var cfg basicauth.Config
+	if err := readJSON(path, &cfg); err != nil {
+		return cfg, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+
+	t := time.Now()
+	if now != "" {
+		var err error
+		if t, err = time.Parse(time.RFC3339, now); err != nil {
+			return cfg, fmt.Errorf("invalid -now: %w", err)
+		}
+	}
+	cfg.Now = func() time.Time { return t }
+	return cfg, nil
+}
+
+func readJSON(path string, v interface{}) error {
+	if path == "" {
+		return errors.New("missing file name")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Case) setURL(raw string) error {
+	if strings.HasPrefix(raw, "/") {
+		c.Path = raw
+		return nil
+	}
+	scheme, rest, ok := strings.Cut(raw, "://")
+	if !ok {
+		return fmt.Errorf("invalid url %q", raw)
+	}
+	c.Scheme = scheme
+	c.Host, c.Path, _ = strings.Cut(rest, "/")
+	c.Path = "/" + c.Path
+	return nil
+}
+
+// request builds the request of the case. Credentials are sent in Proxy-Authorization header for proxy configs.
+func (c *Case) request(proxy bool) (*http.Request, error) {
+	scheme, host, remoteAddr := c.Scheme, c.Host, c.RemoteAddr
+	if scheme == "" {
+		scheme = "http"
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	if remoteAddr == "" {
+		remoteAddr = "192.0.2.1:1234"
+	}
+
+	r, err := http.NewRequest(c.Method, scheme+"://"+host+c.Path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if scheme == "https" {
+		r.TLS = &tls.ConnectionState{}
+	}
+	r.RemoteAddr = remoteAddr
+	if c.User != "" || c.Password != "" {
+		r.SetBasicAuth(c.User, c.Password)
+		if proxy {
+			r.Header.Set("Proxy-Authorization", r.Header.Get("Authorization"))
+			r.Header.Del("Authorization")
+		}
+	}
+	for name, value := range c.Headers {
+		r.Header.Set(name, value)
+	}
+	return r, nil
+}
+
+func scheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+func toJSON(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// headerFlag collects -header flags.
+type headerFlag map[string]string
+
+func (h headerFlag) String() string {
+	return ""
+}
+
+func (h headerFlag) Set(value string) error {
+	name, v, ok := strings.Cut(value, ":")
+	if !ok {
+		return fmt.Errorf("header %q is not Name: value", value)
+	}
+	h[strings.TrimSpace(name)] = strings.TrimSpace(v)
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestTest(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"test", "-config", "testdata/config.json", "-cases", "testdata/cases.json", "-now", "2025-03-03T20:00:00Z"}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stdout.String()+stderr.String())
+	assert.Equal(t, "8 cases, 0 failed\n", stdout.String())
+
+	stdout.Reset()
+	code = run([]string{"test", "-config", "testdata/config.json", "-cases", "testdata/regression.json"}, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "FAIL reports are public: GET localhost/reports/1: want 200, got 401 (restricted_urls /reports/*: auth: no credentials)\n"+
+		"2 cases, 1 failed\n", stdout.String())
+
+	code = run([]string{"test", "-config", "testdata/missing.json", "-cases", "testdata/cases.json"}, &stdout, &stderr)
+	assert.Equal(t, 2, code)
+}
+
+func TestExplain(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{
+		"explain", "-config", "testdata/config.json", "-now", "2025-03-03T20:00:00Z",
+		"-user", "contractor", "-password", "secret", "GET", "http://localhost/reports/1",
+	}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, `request:     GET http://localhost/reports/1
+realm:       none, users of the config
+restriction: restricted_urls /reports/*
+user:        contractor
+access rule: 0 {"urls":["/reports/*"],"roles":["contractor"],"window":{"times":["09:00-18:00"]}}, closed at 2025-03-03T20:00:00Z
+decision:    deny status=403 reason="auth: forbidden"
+`, stdout.String())
+
+	stdout.Reset()
+	code = run([]string{"explain", "-config", "testdata/config.json", "-user", "admin", "-password", "secret", "GET", "/billing/invoices"}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, `request:     GET http://localhost/billing/invoices
+realm:       Billing, path prefixes /billing
+restriction: realm Billing
+user:        not authenticated, auth: invalid credentials
+access rule: none rejects the request
+decision:    deny status=401 reason="auth: invalid credentials"
+`, stdout.String())
+
+	stdout.Reset()
+	code = run([]string{"explain", "-config", "testdata/config.json", "-header", "X-Trace: 1", "GET", "https://internal.example.com/"}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Assert(t, bytes.Contains(stdout.Bytes(), []byte(`restriction: restricted_rules[0] {"hosts":["internal.example.com"]}`)))
+
+	assert.Equal(t, 2, run([]string{"explain", "-config", "testdata/config.json", "GET"}, &stdout, &stderr))
+}
@@ -0,0 +1,302 @@
+// Command authusers manages the users of basicauth in htpasswd and JSON files.
+//
+//	echo "$PASSWORD" | authusers add -file users.htpasswd admin
+//	echo "$PASSWORD" | authusers verify -file users.htpasswd admin
+//	authusers disable -file users.htpasswd admin
+//
+// Passwords are read from the first line of standard input, so they do not show up in the shell history
+// or the process list. Files with the .json extension hold a JSON array of basicauth users, other files are
+// htpasswd files of bcrypt and argon2id hashes.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/basic"
+	"github.com/golanguzb70/middleware/internal/passwd"
+	"github.com/golanguzb70/middleware/internal/userstore"
+)
+
+const usage = "usage: authusers hash|add|set|remove|disable|enable|verify|check [flags] [USER]"
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+
+	c := &command{name: args[0], stdin: stdin, stdout: stdout, stderr: stderr}
+	switch c.name {
+	case "hash":
+		return c.hash(args[1:])
+	case "add", "set":
+		return c.add(args[1:])
+	case "remove", "disable", "enable":
+		return c.edit(args[1:])
+	case "verify":
+		return c.verify(args[1:])
+	case "check":
+		return c.check(args[1:])
+	}
+	fmt.Fprintf(stderr, "authusers: unknown command %q\n", c.name)
+	return 2
+}
+
+type command struct {
+	name   string
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// hash prints the hash of the password.
+func (c *command) hash(args []string) int {
+	flags := c.flags()
+	alg, params := hashFlags(flags)
+	if !c.parse(flags, args, 0) {
+		return 2
+	}
+
+	password, err := c.password()
+	if err != nil {
+		return c.fail(err)
+	}
+	hash, err := passwd.Hash(*alg, password, *params)
+	if err != nil {
+		return c.fail(err)
+	}
+	fmt.Fprintln(c.stdout, hash)
+	return 0
+}
+
+// add adds a user, set changes the password of a user. The password must pass the strength check unless -force is given.
+func (c *command) add(args []string) int {
+	flags := c.flags()
+	file := fileFlag(flags)
+	alg, params := hashFlags(flags)
+	roles := flags.String("roles", "", "comma separated `roles` of the user, JSON files only")
+	force := flags.Bool("force", false, "accept weak passwords")
+	breached := breachedFlag(flags)
+	if !c.parse(flags, args, 1) {
+		return 2
+	}
+	name := flags.Arg(0)
+	if err := userstore.ValidName(name); err != nil {
+		return c.fail(err)
+	}
+
+	users, err := load(*file, c.name == "add")
+	if err != nil {
+		return c.fail(err)
+	}
+	i := find(users, name)
+	switch {
+	case c.name == "add" && i >= 0:
+		return c.fail(fmt.Errorf("user %q exists", name))
+	case c.name == "set" && i < 0:
+		return c.fail(fmt.Errorf("no user %q", name))
+	case i < 0:
+		users = append(users, basic.User{UserName: name})
+		i = len(users) - 1
+	}
+
+	password, err := c.password()
+	if err != nil {
+		return c.fail(err)
+	}
+	if !*force {
+		if err := (passwd.Policy{BreachedRanges: *breached}).Check(name, password); err != nil {
+			return c.fail(fmt.Errorf("weak password, use -force to accept it:\n%w", err))
+		}
+	}
+	if users[i].Password, err = passwd.Hash(*alg, password, *params); err != nil {
+		return c.fail(err)
+	}
+	if *roles != "" {
+		users[i].Roles = strings.Split(*roles, ",")
+	}
+
+	if err := userstore.Save(*file, users); err != nil {
+		return c.fail(err)
+	}
+	return 0
+}
+
+// edit removes, disables or enables a user.
+func (c *command) edit(args []string) int {
+	flags := c.flags()
+	file := fileFlag(flags)
+	if !c.parse(flags, args, 1) {
+		return 2
+	}
+	name := flags.Arg(0)
+
+	users, err := load(*file, false)
+	if err != nil {
+		return c.fail(err)
+	}
+	i := find(users, name)
+	if i < 0 {
+		return c.fail(fmt.Errorf("no user %q", name))
+	}
+	switch c.name {
+	case "remove":
+		users = append(users[:i], users[i+1:]...)
+	case "disable":
+		users[i].Disabled = true
+	case "enable":
+		users[i].Disabled = false
+	}
+
+	if err := userstore.Save(*file, users); err != nil {
+		return c.fail(err)
+	}
+	return 0
+}
+
+// verify checks the password of a user. It exits with status 1 if the password does not match or the user is disabled.
+func (c *command) verify(args []string) int {
+	flags := c.flags()
+	file := fileFlag(flags)
+	if !c.parse(flags, args, 1) {
+		return 2
+	}
+	name := flags.Arg(0)
+
+	users, err := load(*file, false)
+	if err != nil {
+		return c.fail(err)
+	}
+	password, err := c.password()
+	if err != nil {
+		return c.fail(err)
+	}
+
+	now := time.Now()
+	i := find(users, name)
+	switch {
+	case i < 0:
+		fmt.Fprintf(c.stdout, "%s: no such user\n", name)
+	case !users[i].Active(now):
+		fmt.Fprintf(c.stdout, "%s: disabled or expired\n", name)
+	case !users[i].CheckPassword(password, now):
+		fmt.Fprintf(c.stdout, "%s: wrong password\n", name)
+	default:
+		fmt.Fprintf(c.stdout, "%s: ok\n", name)
+		return 0
+	}
+	return 1
+}
+
+// check checks the strength of the password. It exits with status 1 if the password is weak.
+func (c *command) check(args []string) int {
+	flags := c.flags()
+	user := flags.String("user", "", "user `name` the password must not contain")
+	policy := passwd.Policy{}
+	flags.IntVar(&policy.MinLength, "min-length", 0, "minimum `length` (default 12)")
+	flags.Float64Var(&policy.MinEntropy, "min-entropy", 0, "minimum entropy in `bits` (default 50)")
+	breached := breachedFlag(flags)
+	if !c.parse(flags, args, 0) {
+		return 2
+	}
+	policy.BreachedRanges = *breached
+
+	password, err := c.password()
+	if err != nil {
+		return c.fail(err)
+	}
+	if err := policy.Check(*user, password); err != nil {
+		fmt.Fprintln(c.stdout, err)
+		return 1
+	}
+	fmt.Fprintf(c.stdout, "ok, entropy %.0f bits\n", passwd.Entropy(password))
+	return 0
+}
+
+func (c *command) flags() *flag.FlagSet {
+	flags := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	return flags
+}
+
+// parse parses the flags and checks the number of arguments.
+func (c *command) parse(flags *flag.FlagSet, args []string, n int) bool {
+	if err := flags.Parse(args); err != nil {
+		return false
+	}
+	if flags.NArg() != n {
+		if n == 0 {
+			fmt.Fprintf(c.stderr, "usage: authusers %s [flags]\n", c.name)
+		} else {
+			fmt.Fprintf(c.stderr, "usage: authusers %s [flags] USER\n", c.name)
+		}
+		return false
+	}
+	return true
+}
+
+// password reads the password from the first line of standard input.
+func (c *command) password() (string, error) {
+	line, err := bufio.NewReader(c.stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return "", errors.New("no password on standard input")
+	}
+	return line, nil
+}
+
+func (c *command) fail(err error) int {
+	fmt.Fprintln(c.stderr, "authusers:", err)
+	return 1
+}
+
+func fileFlag(flags *flag.FlagSet) *string {
+	return flags.String("file", "", "htpasswd or JSON users `file`")
+}
+
+func breachedFlag(flags *flag.FlagSet) *string {
+	return flags.String("breached", "", "breached password hashes, a `path` of range files or a hash file")
+}
+
+func hashFlags(flags *flag.FlagSet) (*string, *passwd.Params) {
+	alg := flags.String("alg", passwd.Bcrypt, "hash `algorithm`: bcrypt or argon2id")
+	params := &passwd.Params{}
+	flags.IntVar(&params.BcryptCost, "cost", 0, "bcrypt `cost` (default 10)")
+	return alg, params
+}
+
+// load reads the users of the file. A missing file has no users if create is set.
+func load(file string, create bool) ([]basic.User, error) {
+	if file == "" {
+		return nil, errors.New("missing -file")
+	}
+	users, err := userstore.Load(file)
+	if create && errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return users, err
+}
+
+func find(users []basic.User, name string) int {
+	for i, u := range users {
+		if u.UserName == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golanguzb70/middleware/internal/userstore"
+	"gotest.tools/assert"
+)
+
+const strong = "correct-Horse-battery-9"
+
+func authusers(t *testing.T, stdin string, args ...string) (int, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String() + stderr.String()
+}
+
+func TestHtpasswdFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "users.htpasswd")
+
+	code, out := authusers(t, "password\n", "add", "-file", file, "-cost", "4", "admin")
+	assert.Equal(t, 1, code)
+	assert.Assert(t, strings.Contains(out, "weak password"), out)
+
+	code, out = authusers(t, strong+"\n", "add", "-file", file, "-cost", "4", "admin")
+	assert.Equal(t, 0, code, out)
+	code, out = authusers(t, strong+"\n", "add", "-file", file, "-alg", "argon2id", "ops")
+	assert.Equal(t, 0, code, out)
+	code, out = authusers(t, strong+"\n", "add", "-file", file, "admin")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "authusers: user \"admin\" exists\n", out)
+
+	b, err := os.ReadFile(file)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasPrefix(string(b), "admin:$2a$04$"), string(b))
+	assert.Assert(t, strings.Contains(string(b), "\nops:$argon2id$v=19$"), string(b))
+
+	code, out = authusers(t, strong+"\n", "verify", "-file", file, "admin")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "admin: ok\n", out)
+	code, out = authusers(t, strong+"\n", "verify", "-file", file, "ops")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "ops: ok\n", out)
+	code, out = authusers(t, "wrong\n", "verify", "-file", file, "admin")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "admin: wrong password\n", out)
+
+	code, out = authusers(t, "", "disable", "-file", file, "admin")
+	assert.Equal(t, 0, code, out)
+	code, out = authusers(t, strong+"\n", "verify", "-file", file, "admin")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "admin: disabled or expired\n", out)
+	code, out = authusers(t, "", "enable", "-file", file, "admin")
+	assert.Equal(t, 0, code, out)
+
+	code, out = authusers(t, "another-Strong-pass-42\n", "set", "-file", file, "-cost", "4", "admin")
+	assert.Equal(t, 0, code, out)
+	code, _ = authusers(t, strong+"\n", "verify", "-file", file, "admin")
+	assert.Equal(t, 1, code)
+
+	code, out = authusers(t, "", "remove", "-file", file, "ops")
+	assert.Equal(t, 0, code, out)
+	code, out = authusers(t, strong+"\n", "verify", "-file", file, "ops")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "ops: no such user\n", out)
+
+	// names that would break the file
+	for _, name := range []string{"a:b", "a\nb", "#admin"} {
+		code, out = authusers(t, strong+"\n", "add", "-file", file, "-cost", "4", name)
+		assert.Equal(t, 1, code)
+		assert.Assert(t, strings.Contains(out, "user "), out)
+	}
+	_, err = userstore.Load(file)
+	assert.NilError(t, err)
+
+	// htpasswd files have no roles
+	code, out = authusers(t, strong+"\n", "set", "-file", file, "-cost", "4", "-roles", "admin", "admin")
+	assert.Equal(t, 1, code)
+	assert.Assert(t, strings.Contains(out, "use a JSON file"), out)
+}
+
+func TestJSONFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "users.json")
+
+	code, out := authusers(t, "password\n", "add", "-file", file, "-cost", "4", "-force", "-roles", "admin,ops", "admin")
+	assert.Equal(t, 0, code, out)
+	code, out = authusers(t, "password\n", "verify", "-file", file, "admin")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "admin: ok\n", out)
+
+	b, err := os.ReadFile(file)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(b), "\"roles\": [\n      \"admin\",\n      \"ops\"\n    ]"), string(b))
+}
+
+func TestHashAndCheck(t *testing.T) {
+	code, out := authusers(t, strong+"\n", "hash", "-cost", "4")
+	assert.Equal(t, 0, code, out)
+	assert.Assert(t, strings.HasPrefix(out, "$2a$04$"), out)
+
+	code, out = authusers(t, strong+"\n", "hash", "-alg", "md5")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "authusers: passwd: unsupported hash: algorithm \"md5\"\n", out)
+
+	code, out = authusers(t, strong+"\n", "check")
+	assert.Equal(t, 0, code, out)
+	assert.Assert(t, strings.HasPrefix(out, "ok, entropy "), out)
+
+	code, out = authusers(t, "admin12345\n", "check", "-user", "admin")
+	assert.Equal(t, 1, code)
+	assert.Assert(t, strings.Contains(out, "at least 12 are required"), out)
+	assert.Assert(t, strings.Contains(out, "contains the user name"), out)
+
+	hashes := filepath.Join(t.TempDir(), "hashes.txt")
+	assert.NilError(t, os.WriteFile(hashes, []byte("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:9659365\n"), 0o600))
+	code, out = authusers(t, "password\n", "check", "-breached", hashes, "-min-length", "1", "-min-entropy", "1")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "passwd: password is a common password\npasswd: password appears in breached passwords 9659365 times\n", out)
+
+	code, _ = authusers(t, "", "check")
+	assert.Equal(t, 1, code)
+	code, _ = authusers(t, "", "verify", "admin")
+	assert.Equal(t, 1, code)
+	code, _ = authusers(t, "", "frobnicate")
+	assert.Equal(t, 2, code)
+	code, _ = authusers(t, "", "remove", "-file", "users.htpasswd")
+	assert.Equal(t, 2, code)
+}
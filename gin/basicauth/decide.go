@@ -0,0 +1,26 @@
+package basicauth
+
+import "net/http"
+
+// Decide returns the decision the middleware would make about the request without serving it,
+// e.g. to test the config in CI. Session cookies are not refreshed.
+func (cfg *Config) Decide(r *http.Request) *Decision {
+	return cfg.decide(r, nil)
+}
+
+// decide decides about the request without enforcing it or refreshing sessions.
+// The request is in the realm with the name of the enforced realm if that realm has no path prefixes.
+func (cfg *Config) decide(r *http.Request, enforcedRealm *Realm) *Decision {
+	authenticator, restriction, _ := cfg.route(r)
+	if enforcedRealm != nil && len(enforcedRealm.PathPrefixes) == 0 {
+		for i := range cfg.Realms {
+			if cfg.Realms[i].Name == enforcedRealm.Name {
+				authenticator, restriction = cfg.realmAuthenticator(&cfg.Realms[i]), "realm "+enforcedRealm.Name
+				break
+			}
+		}
+	}
+	// a decision that is not enforced does not upgrade password hashes
+	authenticator.Rehash = nil
+	return authenticator.Decide(nil, r, restriction)
+}
@@ -1,7 +1,9 @@
 package basicauth
 
 import (
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -137,3 +139,419 @@ func RestrictByUrlRouter() *gin.Engine {
 
 	return router
 }
+
+func ProxyAuthRouter() *gin.Engine {
+	router := gin.Default()
+
+	// This configuration makes the middleware authenticate clients of a forward proxy
+	cfg := Config{
+		Users: []User{
+			{
+				UserName: "UserName1",
+				Password: "Password1",
+			},
+		},
+		RequireAuthForAll: true,
+		ProxyAuth:         true,
+	}
+
+	router.Use(cfg.Middleware)
+
+	// a real proxy forwards the request here, e.g. with httputil.ReverseProxy
+	router.Any("/*path", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{
+			"proxy_authorization": ctx.GetHeader("Proxy-Authorization"),
+		})
+	})
+
+	return router
+}
+
+func SessionRouter() *gin.Engine {
+	router := gin.Default()
+
+	// Browsers log in once and send the session cookie, API clients keep using Basic credentials
+	cfg := Config{
+		Users: []User{
+			{
+				UserName: "UserName1",
+				Password: "Password1",
+				Roles:    []string{"admin"},
+			},
+		},
+		RestrictedUrls: []string{"/admin/*"},
+		Session: &SessionConfig{
+			Secrets:           []string{"session-secret"},
+			IdleTimeout:       30 * time.Minute,
+			AbsoluteTimeout:   8 * time.Hour,
+			SuppressChallenge: true,
+		},
+	}
+
+	router.Use(cfg.Middleware)
+
+	router.POST("/login", cfg.LoginHandler)
+	router.POST("/logout", cfg.LogoutHandler)
+
+	router.GET("/admin/whoami", func(ctx *gin.Context) {
+		principal, _ := GetPrincipal(ctx)
+		ctx.JSON(http.StatusOK, gin.H{
+			"name":   principal.Name,
+			"scheme": principal.Scheme,
+		})
+	})
+
+	return router
+}
+
+func TokenRouter() *gin.Engine {
+	router := gin.Default()
+
+	// Clients exchange Basic credentials for a short-lived access token once and then send it as a bearer token
+	cfg := Config{
+		Users: []User{
+			{
+				UserName: "UserName1",
+				Password: "Password1",
+				Roles:    []string{"admin"},
+			},
+		},
+		RestrictedUrls: []string{"/api/*"},
+		Tokens: &TokenConfig{
+			Keys:       map[string]string{"2025": "token-secret"},
+			Issuer:     "example",
+			AccessTTL:  5 * time.Minute,
+			RefreshTTL: 24 * time.Hour,
+		},
+	}
+
+	router.Use(cfg.Middleware)
+
+	router.POST("/oauth/token", cfg.TokenHandler)
+	router.POST("/oauth/revoke", cfg.RevokeTokenHandler)
+
+	router.GET("/api/whoami", func(ctx *gin.Context) {
+		principal, _ := GetPrincipal(ctx)
+		ctx.JSON(http.StatusOK, gin.H{
+			"name":   principal.Name,
+			"scheme": principal.Scheme,
+		})
+	})
+
+	return router
+}
+
+func TOTPRouter() *gin.Engine {
+	router := gin.Default()
+
+	// The admin must send a one-time code of an authenticator app too, the reporter uses only the password
+	cfg := Config{
+		Users: []User{
+			{
+				UserName:   "Admin",
+				Password:   "AdminPassword",
+				Roles:      []string{"admin"},
+				TOTPSecret: "JBSWY3DPEHPK3PXP",
+			},
+			{
+				UserName: "Reporter",
+				Password: "ReporterPassword",
+			},
+		},
+		RestrictedUrls: []string{"/admin/*"},
+		TOTP: &TOTPConfig{
+			Header: "X-OTP",
+			Window: 1,
+		},
+	}
+
+	router.Use(cfg.Middleware)
+
+	router.GET("/admin/whoami", func(ctx *gin.Context) {
+		principal, _ := GetPrincipal(ctx)
+		ctx.String(http.StatusOK, principal.Name)
+	})
+
+	return router
+}
+
+func PasswordRotationRouter() *gin.Engine {
+	router := gin.Default()
+
+	// The password of the billing service is being rotated: the old one works until the end of the day,
+	// so clients can be redeployed with the new one without downtime.
+	endOfDay := time.Now().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	cfg := Config{
+		Users: []User{
+			{
+				UserName: "billing",
+				Credentials: []Credential{
+					{Password: "OldPassword", NotAfter: endOfDay},
+					{Password: "NewPassword"},
+				},
+			},
+			{
+				UserName:  "Contractor",
+				Password:  "ContractorPassword",
+				ExpiresAt: time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			{
+				UserName: "FormerEmployee",
+				Password: "Password1",
+				Disabled: true,
+			},
+		},
+		RequireAuthForAll: true,
+	}
+
+	router.Use(cfg.Middleware)
+
+	router.GET("/invoices", func(ctx *gin.Context) {
+		principal, _ := GetPrincipal(ctx)
+		ctx.String(http.StatusOK, "invoices for "+principal.Name)
+	})
+
+	return router
+}
+
+func NetworkRestrictedRouter() *gin.Engine {
+	router := gin.Default()
+
+	// The deploy account works only from the CI network, even with the right password.
+	// The service runs behind a load balancer in 10.0.0.0/8 that sets X-Forwarded-For.
+	cfg := Config{
+		Users: []User{
+			{
+				UserName:     "Deployer",
+				Password:     "DeployerPassword",
+				AllowedCIDRs: []string{"192.168.100.0/24", "2001:db8:100::/48"},
+			},
+			{
+				UserName: "UserName1",
+				Password: "Password1",
+			},
+		},
+		RestrictedUrls:  []string{"/deploy"},
+		TrustedProxies:  []string{"10.0.0.0/8"},
+		ForwardedHeader: "X-Forwarded-For",
+	}
+
+	router.Use(cfg.Middleware)
+
+	router.POST("/deploy", func(ctx *gin.Context) {
+		principal, _ := GetPrincipal(ctx)
+		ctx.String(http.StatusOK, "deployed by "+principal.Name)
+	})
+
+	return router
+}
+
+func BusinessHoursRouter() *gin.Engine {
+	router := gin.Default()
+
+	businessHours := &AccessWindow{
+		Days:     []string{"mon", "tue", "wed", "thu", "fri"},
+		Times:    []string{"09:00-18:00"},
+		Timezone: "Asia/Tashkent",
+	}
+
+	// The contractor may log in only during the contract and business hours,
+	// and reports are closed for everybody during the nightly rebuild
+	cfg := Config{
+		Users: []User{
+			{
+				UserName:     "Contractor",
+				Password:     "ContractorPassword",
+				Roles:        []string{"contractor"},
+				AccessWindow: businessHours,
+			},
+			{
+				UserName: "UserName1",
+				Password: "Password1",
+				Roles:    []string{"admin"},
+			},
+		},
+		RestrictedUrls: []string{"/reports/*", "/billing/*"},
+		AccessRules: []AccessRule{
+			{
+				Condition: RestrictedRule{Urls: []string{"/billing/*"}},
+				Roles:     []string{"contractor"},
+				Window: &AccessWindow{
+					Dates: []string{"2025-01-01/2025-12-31"},
+				},
+			},
+			{
+				Condition: RestrictedRule{Urls: []string{"/reports/*"}},
+				Window: &AccessWindow{
+					Times:    []string{"03:00-23:00"},
+					Timezone: "Asia/Tashkent",
+				},
+			},
+		},
+	}
+
+	router.Use(cfg.Middleware)
+
+	router.GET("/reports/:id", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "report "+ctx.Param("id"))
+	})
+
+	router.GET("/billing/:id", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "invoice "+ctx.Param("id"))
+	})
+
+	return router
+}
+
+func VirtualHostsRouter() *gin.Engine {
+	router := gin.Default()
+
+	// www.example.com is public, every admin.example.com request and every plain HTTP request to the api
+	// is authenticated, and calls of internal services are recognized by their header
+	cfg := Config{
+		Users: []User{
+			{
+				UserName: "UserName1",
+				Password: "Password1",
+			},
+		},
+		RestrictedRules: []RestrictedRule{
+			{Hosts: []string{"admin.example.com"}},
+			{Hosts: []string{"*.api.example.com"}, Schemes: []string{"http"}},
+			{Headers: map[string]string{"X-Internal-Call": "*"}},
+			{Urls: []string{"/export"}, Query: map[string]string{"format": "csv"}},
+		},
+		TrustedProxies: []string{"10.0.0.0/8"},
+	}
+
+	router.Use(cfg.Middleware)
+
+	router.GET("/*path", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "Hello from "+ctx.Request.Host)
+	})
+
+	return router
+}
+
+func MountedAppsRouter() *gin.Engine {
+	router := gin.Default()
+
+	// /admin and /billing have their own users, the ops group uses the ops realm explicitly
+	// and /account is for the users of the config
+	cfg := &Config{
+		Users: []User{
+			{
+				UserName: "UserName1",
+				Password: "Password1",
+			},
+		},
+		RestrictedUrls: []string{"/account/*"},
+		Realms: []Realm{
+			{
+				Name:         "Admin",
+				PathPrefixes: []string{"/admin"},
+				Users:        []User{{UserName: "Admin", Password: "AdminPassword"}},
+			},
+			{
+				Name:         "Billing",
+				PathPrefixes: []string{"/billing"},
+				Users:        []User{{UserName: "Accountant", Password: "AccountantPassword"}},
+			},
+			{
+				Name:  "Ops",
+				Users: []User{{UserName: "Operator", Password: "OperatorPassword"}},
+				UnauthorizedHandler: func(ctx *gin.Context) {
+					ctx.JSON(http.StatusUnauthorized, gin.H{"error": "ops credentials are required"})
+				},
+			},
+		},
+	}
+
+	router.Use(cfg.Middleware)
+
+	hello := func(ctx *gin.Context) {
+		principal, _ := GetPrincipal(ctx)
+		ctx.String(http.StatusOK, "Hello "+principal.Name)
+	}
+
+	router.GET("/admin/*path", hello)
+	router.GET("/billing/*path", hello)
+	router.GET("/account/*path", hello)
+
+	ops := router.Group("/ops", cfg.RealmMiddleware("Ops"))
+	ops.GET("/status", hello)
+
+	return router
+}
+
+func ReportOnlyRouter() *gin.Engine {
+	router := gin.Default()
+
+	// RequireAuthForAll is going to be turned on, until then the requests that would be blocked are only logged
+	cfg := &Config{
+		Users: []User{
+			{
+				UserName: "UserName1",
+				Password: "Password1",
+			},
+		},
+		RequireAuthForAll: true,
+		ReportOnly:        true,
+		OnDecision: func(d *Decision) {
+			if !d.Allowed() {
+				log.Printf("basicauth: would block %s %s: %s", d.Method, d.Path, d)
+			}
+		},
+	}
+
+	router.Use(cfg.Middleware)
+
+	router.GET("/", func(ctx *gin.Context) {
+		name := "anonymous"
+		if principal, ok := GetPrincipal(ctx); ok {
+			name = principal.Name
+		}
+		ctx.String(http.StatusOK, "Hello "+name)
+	})
+
+	return router
+}
+
+func MigrationRouter() *gin.Engine {
+	router := gin.Default()
+
+	// the old config is enforced while the new one, which also protects reports, runs in the shadow
+	cfg := &Config{
+		Users: []User{
+			{
+				UserName: "UserName1",
+				Password: "Password1",
+			},
+		},
+		RestrictedUrls: []string{"/admin/*"},
+		Shadow: &Config{
+			Users: []User{
+				{
+					UserName: "UserName1",
+					Password: "Password1",
+				},
+			},
+			RestrictedUrls: []string{"/admin/*", "/reports/*"},
+		},
+		ShadowRecorder: &ShadowRecorder{
+			OnDivergence: func(d *Divergence) {
+				log.Printf("basicauth: shadow config decides %s %s: %s", d.Shadow.Method, d.Shadow.Path, d.Shadow)
+			},
+		},
+	}
+
+	router.Use(cfg.Middleware)
+
+	router.GET("/admin/shadow", cfg.ShadowSummaryHandler)
+
+	router.GET("/reports/:id", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "report "+ctx.Param("id"))
+	})
+
+	return router
+}
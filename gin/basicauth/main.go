@@ -1,7 +1,21 @@
 package basicauth
 
 import (
+	"fmt"
+	"sync"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/basic"
+	"github.com/golanguzb70/middleware/internal/clientip"
+	"github.com/golanguzb70/middleware/internal/passwd"
+	"github.com/golanguzb70/middleware/internal/rules"
+	"github.com/golanguzb70/middleware/internal/schedule"
+	"github.com/golanguzb70/middleware/internal/session"
+	"github.com/golanguzb70/middleware/internal/tokens"
+	"github.com/golanguzb70/middleware/internal/totp"
+	"github.com/golanguzb70/middleware/internal/userstore"
 )
 
 // This is configuration struct of Basic Auth
@@ -21,15 +35,137 @@ type Config struct {
 	// If this field is set to true, all the requests are authenticated
 	// If this field is not set or set to true, other fields are checked such as, RestrictedMethods and RestrictedUrls
 	RequireAuthForAll bool `json:"require_auth_for_all"`
+	// Restricted rules are conditions on host, scheme, query parameters and headers besides method and url.
+	// Requests that match every field of one of the rules are authenticated, e.g. every request to *.internal.example.com.
+	RestrictedRules []RestrictedRule `json:"restricted_rules"`
+	// If this field is set to true, the middleware works as forward proxy authentication:
+	// credentials are read from Proxy-Authorization header, 407 status is returned with Proxy-Authenticate header,
+	// and Proxy-Authorization header is removed from the request before it is passed to the next handler.
+	ProxyAuth bool `json:"proxy_auth"`
+	// Session enables cookie sessions: LoginHandler checks the credentials of Users and issues a session cookie,
+	// and the middleware accepts either the cookie or Basic credentials.
+	Session *SessionConfig `json:"session"`
+	// Tokens enables the token endpoint: TokenHandler exchanges Basic credentials of Users for a short-lived
+	// access token and a refresh token, and the middleware accepts the access tokens as bearer tokens.
+	Tokens *TokenConfig `json:"tokens"`
+	// TOTP configures one-time codes of users with TOTPSecret: the header they are sent in, digits, time step and window.
+	// Users without a secret are not affected. Default values are used if it is not set.
+	TOTP *TOTPConfig `json:"totp"`
+	// TrustedProxies are CIDRs of the reverse proxies in front of the service, e.g. 10.0.0.0/8.
+	// AllowedCIDRs of users are checked against the client address the trusted proxies forward.
+	// Without trusted proxies, the address of the direct peer is checked. Invalid entries never match.
+	// X-Forwarded-Proto of trusted proxies is the scheme rules are checked against.
+	TrustedProxies []string `json:"trusted_proxies"`
+	// ForwardedHeader is the header trusted proxies forward the client address in: X-Forwarded-For (default) or Forwarded.
+	ForwardedHeader string `json:"forwarded_header"`
+	// AccessRules limit when matching requests are allowed, e.g. endpoints that contractors may call only during
+	// business hours. Requests outside the window of a matching rule get 403 status.
+	AccessRules []AccessRule `json:"access_rules"`
+	// PasswordPolicy makes Validate reject users with weak plain passwords: too short, low entropy, common,
+	// containing the user name or found in a local copy of breached password hashes.
+	// Hashed passwords can not be checked, check them with the authusers command before they are hashed.
+	PasswordPolicy *PasswordPolicy `json:"password_policy"`
+	// PasswordHashing upgrades password hashes of users on successful login: bcrypt and argon2id hashes made with
+	// another algorithm or other parameters are hashed again and handed to its Store, e.g. after the bcrypt cost is raised.
+	// The new hash is made and stored in the background, so the login does not wait for it; call its Wait method
+	// on shutdown to finish the upgrades in progress. Handlers made of the config share the upgrades through it.
+	// Report-only mode, Decide and Shadow never upgrade hashes.
+	PasswordHashing *PasswordHashingConfig `json:"password_hashing"`
+	// Shadow is a new config that is evaluated on the same requests without being enforced, e.g. during a migration.
+	// Requests it decides differently are recorded in ShadowRecorder. Realms without path prefixes are matched by name.
+	// Shadow must not share TOTP config with this config, as a one-time code is accepted only once.
+	// Validate checks Shadow too; call it before use, invalid networks and windows of Shadow never match.
+	Shadow *Config `json:"shadow"`
+	// ShadowRecorder records the divergences of Shadow and serves their summary as JSON. A recorder is created if it is not set.
+	ShadowRecorder *ShadowRecorder `json:"-"`
+	// Realms are independent lists of users for apps mounted under path prefixes, e.g. /admin, /billing and /ops.
+	// Every request under a prefix of a realm is authenticated against the users of the realm only;
+	// other requests are handled by the fields above.
+	Realms []Realm `json:"realms"`
+	// ReportOnly makes the middleware let every request through, e.g. to see who would be blocked before
+	// RequireAuthForAll or new RestrictedUrls are turned on. The decision it would make is sent in
+	// the X-Auth-Decision response header and passed to OnDecision. Valid credentials still set the principal.
+	// Call Validate before use as in enforcing mode: invalid networks and windows never match and skew the decisions.
+	ReportOnly bool `json:"report_only"`
+	// OnDecision is called with the decision about every request, enforced or not, e.g. to log it or count it in metrics.
+	OnDecision func(*Decision) `json:"-"`
+	// Now is used instead of time.Now to evaluate access windows, credential validity and account expiry when set.
+	Now func() time.Time `json:"-"`
 	// Using this field any data can be given to the function
-	Map               map[string]interface{}
-}
+	Map map[string]interface{}
 
-type User struct {
-	UserName string `json:"user_name"`
-	Password string `json:"password"`
+	once     sync.Once
+	sessions *session.Manager
+	tokens   *tokens.Service
+	totp     *totp.Verifier
+	clientIP clientip.Resolver
+
+	shadowOnce sync.Once
+	recorder   *ShadowRecorder
 }
 
+// User is a user name and password pair with roles.
+type User = basic.User
+
+// Credential is a password of a user valid in a time window.
+type Credential = basic.Credential
+
+// SessionConfig configures session cookies: secrets, cookie name, idle and absolute timeouts.
+type SessionConfig = session.Config
+
+// TokenConfig configures issued tokens: signing keys, lifetimes and the refresh token store.
+type TokenConfig = tokens.Config
+
+// TokenStore keeps refresh tokens. Implement it to share refresh tokens between instances of the service.
+type TokenStore = tokens.Store
+
+// RefreshToken is the record of a refresh token in TokenStore.
+type RefreshToken = tokens.RefreshToken
+
+// RestrictedRule is a condition on method, url, host, scheme, query parameters and headers of requests.
+type RestrictedRule = rules.Condition
+
+// AccessRule limits when requests to some urls and methods are allowed, for every caller or some users and roles.
+// Its Condition selects the requests it applies to.
+type AccessRule = rules.Rule
+
+// AccessWindow is a recurring window of week days, times of day and date ranges in a timezone.
+type AccessWindow = schedule.Window
+
+// PasswordPolicy is the minimum strength of plain passwords of users, see Config.PasswordPolicy.
+type PasswordPolicy = passwd.Policy
+
+// PasswordHashing is the algorithm, bcrypt or argon2id, and cost parameters of password hashes.
+type PasswordHashing = passwd.Hasher
+
+// PasswordHashingConfig is the algorithm and cost parameters password hashes should have, bcrypt with cost 10
+// by default, the CredentialStore the upgraded hashes are handed to and OnError, which is called with the errors
+// of an upgrade in its goroutine. A failed upgrade is tried again on the next login.
+type PasswordHashingConfig = basic.Rehasher
+
+// PasswordParams are the cost parameters of PasswordHashing.
+type PasswordParams = passwd.Params
+
+// CredentialStore keeps the password hashes of users. Implement it to upgrade hashes in a database.
+type CredentialStore = basic.CredentialStore
+
+// UsersFile is the htpasswd or JSON users file of LoadUsers as a CredentialStore.
+type UsersFile = userstore.File
+
+// TOTPConfig configures one-time codes (RFC 6238) of users with a TOTP secret.
+type TOTPConfig = totp.Config
+
+// Decision is what the middleware decides about a request: the restriction that requires authentication,
+// the user, the access rule that rejected the request and the response status.
+type Decision = basic.Decision
+
+// DecisionHeader is the response header the decision is sent in in report-only mode.
+const DecisionHeader = basic.DecisionHeader
+
+// Principal is the authenticated caller of a request.
+// It is the same type for every auth middleware of this module.
+type Principal = auth.Principal
+
 type Auth interface {
 	Middleware(c *gin.Context)
 }
@@ -38,3 +174,56 @@ type Auth interface {
 func New(conf *Config) Auth {
 	return conf
 }
+
+// LoadUsers reads users from an htpasswd file of bcrypt and argon2id hashes, or a JSON array of users
+// if the file name ends with .json. The files are managed with the authusers command.
+func LoadUsers(path string) ([]User, error) {
+	return userstore.Load(path)
+}
+
+// Validate checks the trusted proxy networks; the password hashes, password policy, TOTP secrets, allowed networks
+// and access windows of users; the TOTP config; the password hashing; the access windows of access rules;
+// the path prefixes and users of realms; and Shadow.
+func (cfg *Config) Validate() error {
+	if _, err := clientip.ParseSet(cfg.TrustedProxies); err != nil {
+		return err
+	}
+	for _, user := range cfg.Users {
+		if err := user.Validate(cfg.PasswordPolicy); err != nil {
+			return fmt.Errorf("basicauth: user %q: %w", user.UserName, err)
+		}
+	}
+	if cfg.TOTP != nil {
+		if err := cfg.TOTP.Validate(); err != nil {
+			return fmt.Errorf("basicauth: %w", err)
+		}
+	}
+	if cfg.PasswordHashing != nil {
+		if err := cfg.PasswordHashing.Validate(); err != nil {
+			return fmt.Errorf("basicauth: password hashing: %w", err)
+		}
+	}
+	for i, rule := range cfg.AccessRules {
+		if rule.Window != nil {
+			if err := rule.Window.Validate(); err != nil {
+				return fmt.Errorf("basicauth: access rule %d: %w", i, err)
+			}
+		}
+	}
+	if err := cfg.validateRealms(); err != nil {
+		return err
+	}
+	if cfg.Shadow != nil {
+		if err := cfg.Shadow.Validate(); err != nil {
+			return fmt.Errorf("basicauth: shadow: %w", err)
+		}
+	}
+	return nil
+}
+
+func (cfg *Config) resolver() clientip.Resolver {
+	return clientip.Resolver{
+		TrustedProxies: clientip.ParseValid(cfg.TrustedProxies),
+		Header:         cfg.ForwardedHeader,
+	}
+}
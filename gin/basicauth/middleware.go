@@ -1,75 +1,122 @@
 package basicauth
 
 import (
-	"encoding/base64"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/basic"
+	"github.com/golanguzb70/middleware/internal/rules"
+	"github.com/golanguzb70/middleware/internal/session"
+	"github.com/golanguzb70/middleware/internal/tokens"
+	"github.com/golanguzb70/middleware/internal/totp"
 )
 
 // method for checking authorization
 func (cfg *Config) Middleware(ctx *gin.Context) {
-	var (
-		authRequired = cfg.RequireAuthForAll
-		url          = ctx.Request.URL.Path
-		method       = ctx.Request.Method
-		authHeader   = ctx.GetHeader("Authorization")
-	)
+	authenticator, restriction, realm := cfg.route(ctx.Request)
+	cfg.serve(ctx, authenticator, restriction, realm)
+}
 
-	if strings.Contains(strings.Join(cfg.RestrictedMethods, ","), method) {
-		authRequired = true
+// route returns the authenticator of the request, why the request must be authenticated and the realm it is in.
+func (cfg *Config) route(r *http.Request) (*basic.Authenticator, string, *Realm) {
+	if realm := cfg.realmFor(r.URL.Path); realm != nil {
+		return cfg.realmAuthenticator(realm), "realm " + realm.Name, realm
 	}
+	return cfg.authenticator(), cfg.policy().Explain(r), nil
+}
 
-	if !authRequired && len(cfg.RestrictedUrls) > 0 {
-		for _, e := range cfg.RestrictedUrls {
-			if strings.Contains(e, "*") && strings.Contains(url, strings.TrimSuffix(e, "/*")) {
-				authRequired = true
-				break
-			} else if strings.Contains(e, "{") && string(e[:strings.LastIndex(e, "/")]) == string(url[:strings.LastIndex(url, "/")]) {
-				authRequired = true
-				break
-			} else if e == url {
-				authRequired = true
-			}
-		}
+// serve enforces the decision about the request, or only reports it in report-only mode.
+func (cfg *Config) serve(ctx *gin.Context, authenticator *basic.Authenticator, restriction string, realm *Realm) {
+	decision := authenticator.Decide(ctx.Writer, ctx.Request, restriction)
+	if cfg.OnDecision != nil {
+		cfg.OnDecision(decision)
+	}
+	if cfg.Shadow != nil {
+		cfg.shadowRecorder().Record(decision, cfg.Shadow.decide(ctx.Request, realm))
+	}
+	if decision.Principal != nil {
+		ctx.Request = ctx.Request.WithContext(auth.NewContext(ctx.Request.Context(), decision.Principal))
 	}
 
-	if authRequired {
-		for _, u := range cfg.Users {
-			if authHeader == "" {
-				ctx.Header("WWW-Authenticate", "Basic realm=Authorization Required")
-				ctx.AbortWithStatus(http.StatusUnauthorized)
-				return
-			}
+	switch {
+	case cfg.ReportOnly:
+		ctx.Header(DecisionHeader, decision.String())
+	case decision.Status == http.StatusForbidden:
+		ctx.AbortWithStatus(http.StatusForbidden)
+		return
+	case !decision.Allowed():
+		if challenge := authenticator.Challenge(decision.Err); challenge != "" {
+			ctx.Header(authenticator.ChallengeHeader(), challenge)
+		}
+		if realm != nil && realm.UnauthorizedHandler != nil {
+			realm.UnauthorizedHandler(ctx)
+			ctx.Abort()
+			return
+		}
+		ctx.AbortWithStatus(decision.Status)
+		return
+	}
 
-			credentials := strings.SplitN(authHeader, " ", 2)
-			if len(credentials) != 2 {
-				ctx.Header("WWW-Authenticate", "Basic realm=Authorization Required")
-				ctx.AbortWithStatus(http.StatusUnauthorized)
-				return
-			}
+	// proxy credentials must not be forwarded to the upstream server
+	if cfg.ProxyAuth {
+		ctx.Request.Header.Del("Proxy-Authorization")
+	}
+	ctx.Next()
+}
 
-			decodedCredentials, err := base64.StdEncoding.DecodeString(credentials[1])
-			if err != nil {
-				ctx.Header("WWW-Authenticate", "Basic realm=Authorization Required")
-				ctx.AbortWithStatus(http.StatusUnauthorized)
-				return
-			}
+// Authenticator returns the basic auth scheme of the config to be combined with other schemes in chain package.
+func (cfg *Config) Authenticator() auth.Authenticator {
+	return cfg.authenticator()
+}
 
-			credentialsPair := strings.SplitN(string(decodedCredentials), ":", 2)
-			if len(credentialsPair) != 2 {
-				ctx.Header("WWW-Authenticate", "Basic realm=Authorization Required")
-				ctx.AbortWithStatus(http.StatusUnauthorized)
-				return
-			}
+// GetPrincipal returns the caller the request was authenticated as.
+func GetPrincipal(ctx *gin.Context) (*Principal, bool) {
+	return auth.FromContext(ctx.Request.Context())
+}
 
-			if credentialsPair[0] != u.UserName || credentialsPair[1] != u.Password {
-				ctx.Header("WWW-Authenticate", "Basic realm=Authorization Required")
-				ctx.AbortWithStatus(http.StatusUnauthorized)
-				return
-			}
+func (cfg *Config) authenticator() *basic.Authenticator {
+	cfg.once.Do(func() {
+		if cfg.Session != nil {
+			cfg.sessions = &session.Manager{Config: *cfg.Session}
+		}
+		if cfg.Tokens != nil {
+			cfg.tokens = &tokens.Service{Config: cfg.Tokens}
 		}
+		totpConfig := cfg.TOTP
+		if totpConfig == nil {
+			totpConfig = &totp.Config{}
+		}
+		cfg.totp = &totp.Verifier{Config: totpConfig}
+		cfg.clientIP = cfg.resolver()
+	})
+	return &basic.Authenticator{
+		Users:    cfg.Users,
+		Proxy:    cfg.ProxyAuth,
+		Sessions: cfg.sessions,
+		Tokens:   cfg.tokens,
+		TOTP:     cfg.totp,
+		ClientIP: cfg.clientIP,
+		Rules:    cfg.AccessRules,
+		Rehash:   cfg.rehasher(),
+		Now:      cfg.Now,
+	}
+}
+
+// rehasher returns the password hash upgrades of the config, none in report-only mode, which only evaluates requests.
+func (cfg *Config) rehasher() *basic.Rehasher {
+	if cfg.PasswordHashing == nil || cfg.PasswordHashing.Store == nil || cfg.ReportOnly {
+		return nil
+	}
+	return cfg.PasswordHashing
+}
+
+func (cfg *Config) policy() rules.Policy {
+	return rules.Policy{
+		RequireAuthForAll: cfg.RequireAuthForAll,
+		RestrictedMethods: cfg.RestrictedMethods,
+		RestrictedUrls:    cfg.RestrictedUrls,
+		RestrictedRules:   cfg.RestrictedRules,
+		TrustedProxies:    cfg.authenticator().ClientIP.TrustedProxies,
 	}
-	ctx.Next()
 }
@@ -0,0 +1,81 @@
+package basicauth
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/middleware/internal/basic"
+	"github.com/golanguzb70/middleware/internal/rules"
+)
+
+// Realm is an independent list of users for an app mounted on the router, e.g. under /admin.
+type Realm struct {
+	// Name is sent in the challenge of 401 responses: Basic realm="<Name>".
+	Name string `json:"name"`
+	// PathPrefixes are the paths the realm applies to. /admin matches /admin and /admin/users but not /administrator.
+	// If prefixes of several realms match a request, the longest one wins.
+	PathPrefixes []string `json:"path_prefixes"`
+	// Users is list of users that have access to the realm. Users of the config and other realms have no access.
+	Users []User `json:"users"`
+	// UnauthorizedHandler responds to requests without valid credentials of the realm users instead of 401 status.
+	// The challenge header is already set when it is called, and the context is aborted after it.
+	UnauthorizedHandler gin.HandlerFunc `json:"-"`
+}
+
+// RealmMiddleware authenticates every request against the users of the realm with the name.
+// Use it for route groups instead of PathPrefixes: router.Group("/admin", cfg.RealmMiddleware("admin")).
+// It panics if there is no such realm.
+func (cfg *Config) RealmMiddleware(name string) gin.HandlerFunc {
+	for i := range cfg.Realms {
+		if cfg.Realms[i].Name == name {
+			realm := &cfg.Realms[i]
+			return func(ctx *gin.Context) {
+				cfg.serve(ctx, cfg.realmAuthenticator(realm), "realm "+realm.Name, realm)
+			}
+		}
+	}
+	panic(fmt.Sprintf("basicauth: unknown realm %q", name))
+}
+
+func (cfg *Config) realmAuthenticator(realm *Realm) *basic.Authenticator {
+	authenticator := cfg.authenticator()
+	authenticator.Users = realm.Users
+	authenticator.Realm = realm.Name
+	// sessions and tokens are not bound to a realm, so they are accepted only outside the realms
+	authenticator.Sessions = nil
+	authenticator.Tokens = nil
+	return authenticator
+}
+
+// realmFor returns the realm with the longest prefix of the path, or nil.
+func (cfg *Config) realmFor(path string) *Realm {
+	var (
+		found   *Realm
+		longest = -1
+	)
+	for i := range cfg.Realms {
+		for _, prefix := range cfg.Realms[i].PathPrefixes {
+			if len(prefix) > longest && rules.MatchPrefix(prefix, path) {
+				found, longest = &cfg.Realms[i], len(prefix)
+			}
+		}
+	}
+	return found
+}
+
+func (cfg *Config) validateRealms() error {
+	for _, realm := range cfg.Realms {
+		for _, prefix := range realm.PathPrefixes {
+			if !strings.HasPrefix(prefix, "/") {
+				return fmt.Errorf("basicauth: realm %q: path prefix %q does not start with /", realm.Name, prefix)
+			}
+		}
+		for _, user := range realm.Users {
+			if err := user.Validate(cfg.PasswordPolicy); err != nil {
+				return fmt.Errorf("basicauth: realm %q: user %q: %w", realm.Name, user.UserName, err)
+			}
+		}
+	}
+	return nil
+}
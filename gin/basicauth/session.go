@@ -0,0 +1,30 @@
+package basicauth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/middleware/internal/basic"
+)
+
+// LoginHandler checks the credentials of the request against Users and starts a session.
+// Credentials are read from the Authorization header, a JSON body with user_name and password fields,
+// or username and password form fields. It responds 204 on success and 401 otherwise.
+func (cfg *Config) LoginHandler(ctx *gin.Context) {
+	_, err := cfg.authenticator().Login(ctx.Writer, ctx.Request)
+	switch {
+	case errors.Is(err, basic.ErrNoSessions):
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+	case err != nil:
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+	default:
+		ctx.Status(http.StatusNoContent)
+	}
+}
+
+// LogoutHandler ends the session of the request. It responds 204.
+func (cfg *Config) LogoutHandler(ctx *gin.Context) {
+	cfg.authenticator().Logout(ctx.Writer)
+	ctx.Status(http.StatusNoContent)
+}
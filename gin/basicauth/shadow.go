@@ -0,0 +1,38 @@
+package basicauth
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/middleware/internal/shadow"
+)
+
+// ShadowRecorder records the requests Shadow decides differently from the enforced config.
+type ShadowRecorder = shadow.Recorder
+
+// Divergence is a request with the decisions of the enforced config and Shadow.
+type Divergence = shadow.Divergence
+
+// ShadowKind groups divergences with the same statuses, restrictions and access rules.
+type ShadowKind = shadow.Kind
+
+// ShadowSummary counts the compared requests and the divergences by kind, and holds the latest divergences.
+type ShadowSummary = shadow.Summary
+
+// ShadowSummary returns the requests Shadow has decided differently so far.
+func (cfg *Config) ShadowSummary() ShadowSummary {
+	return cfg.shadowRecorder().Summary()
+}
+
+// ShadowSummaryHandler writes the summary of Shadow divergences as JSON. Protect it like other admin endpoints.
+func (cfg *Config) ShadowSummaryHandler(ctx *gin.Context) {
+	cfg.shadowRecorder().ServeHTTP(ctx.Writer, ctx.Request)
+}
+
+func (cfg *Config) shadowRecorder() *ShadowRecorder {
+	cfg.shadowOnce.Do(func() {
+		cfg.recorder = cfg.ShadowRecorder
+		if cfg.recorder == nil {
+			cfg.recorder = &ShadowRecorder{}
+		}
+	})
+	return cfg.recorder
+}
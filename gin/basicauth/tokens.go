@@ -0,0 +1,52 @@
+package basicauth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/basic"
+	"github.com/golanguzb70/middleware/internal/tokens"
+)
+
+// TokenHandler is an OAuth 2.0 style token endpoint. A form with grant_type client_credentials and Basic credentials
+// of Users, or grant_type refresh_token and a refresh_token field, is exchanged for a new access token and refresh token.
+// A refresh token works only once; when a used one is sent again, every token rotated from the same login is revoked.
+func (cfg *Config) TokenHandler(ctx *gin.Context) {
+	issued, err := cfg.authenticator().ExchangeToken(ctx.Request)
+
+	var tokenErr *tokens.Error
+	switch {
+	case errors.As(err, &tokenErr):
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, tokenErr)
+	case errors.Is(err, auth.ErrNoCredentials), errors.Is(err, auth.ErrInvalidCredentials):
+		ctx.Header("WWW-Authenticate", basic.Challenge)
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+	case err != nil:
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+	default:
+		ctx.Header("Cache-Control", "no-store")
+		ctx.JSON(http.StatusOK, issued)
+	}
+}
+
+// RevokeTokenHandler revokes the refresh token in the token form field and the tokens rotated from the same login.
+// It responds 200 for unknown tokens too.
+func (cfg *Config) RevokeTokenHandler(ctx *gin.Context) {
+	if err := cfg.authenticator().RevokeToken(ctx.Request); err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// RevokeUser revokes every refresh token of the user, e.g. after the password was changed.
+// Access tokens already issued stay valid until they expire.
+func (cfg *Config) RevokeUser(username string) error {
+	authenticator := cfg.authenticator()
+	if authenticator.Tokens == nil {
+		return basic.ErrNoTokens
+	}
+	return authenticator.Tokens.RevokeUser(username)
+}
@@ -0,0 +1,82 @@
+package chain
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/middleware/gin/basicauth"
+	"github.com/golanguzb70/middleware/gin/jwtauth"
+	"github.com/golanguzb70/middleware/gin/mtlsauth"
+)
+
+func BasicOrTokenRouter() *gin.Engine {
+	router := gin.Default()
+
+	basic := basicauth.Config{
+		Users: []basicauth.User{
+			{
+				UserName: "UserName1",
+				Password: "Password1",
+			},
+		},
+	}
+	token := jwtauth.Config{
+		Secrets: map[string]string{
+			"": "secret",
+		},
+	}
+
+	// Humans use basic auth and machines use tokens on the same routes
+	cfg := Config{
+		Authenticator:     AnyOf(basic.Authenticator(), token.Authenticator()),
+		RequireAuthForAll: true,
+	}
+
+	router.Use(cfg.Middleware)
+
+	router.GET("/whoami", func(ctx *gin.Context) {
+		principal, _ := GetPrincipal(ctx)
+		ctx.JSON(http.StatusOK, gin.H{
+			"name":   principal.Name,
+			"scheme": principal.Scheme,
+		})
+	})
+
+	return router
+}
+
+func CertificateAndBasicRouter() *gin.Engine {
+	router := gin.Default()
+
+	certificate := mtlsauth.Config{
+		AllowedIssuers: []string{"Internal CA"},
+	}
+	basic := basicauth.Config{
+		Users: []basicauth.User{
+			{
+				UserName: "admin",
+				Password: "Password1",
+				Roles:    []string{"admin"},
+			},
+		},
+	}
+
+	// Admin routes require both a client certificate and a password
+	cfg := Config{
+		Authenticator:  AllOf(certificate.Authenticator(), basic.Authenticator()),
+		RestrictedUrls: []string{"/admin/*"},
+	}
+
+	router.Use(cfg.Middleware)
+
+	router.GET("/admin/whoami", func(ctx *gin.Context) {
+		principal, _ := GetPrincipal(ctx)
+		ctx.JSON(http.StatusOK, gin.H{
+			"name":   principal.Name,
+			"scheme": principal.Scheme,
+			"roles":  principal.Roles,
+		})
+	})
+
+	return router
+}
@@ -0,0 +1,38 @@
+package chain
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/rules"
+)
+
+// method for checking authorization
+func (cfg *Config) Middleware(ctx *gin.Context) {
+	authRequired := rules.Policy{
+		RequireAuthForAll: cfg.RequireAuthForAll,
+		RestrictedMethods: cfg.RestrictedMethods,
+		RestrictedUrls:    cfg.RestrictedUrls,
+	}.Required(ctx.Request.Method, ctx.Request.URL.Path)
+
+	if !authRequired {
+		ctx.Next()
+		return
+	}
+
+	principal, err := cfg.Authenticator.Authenticate(ctx.Request)
+	if err != nil {
+		if challenge := cfg.Authenticator.Challenge(err); challenge != "" {
+			ctx.Header("WWW-Authenticate", challenge)
+		}
+		ctx.AbortWithStatus(auth.Status(err))
+		return
+	}
+
+	ctx.Request = ctx.Request.WithContext(auth.NewContext(ctx.Request.Context(), principal))
+	ctx.Next()
+}
+
+// GetPrincipal returns the caller the request was authenticated as.
+func GetPrincipal(ctx *gin.Context) (*Principal, bool) {
+	return auth.FromContext(ctx.Request.Context())
+}
@@ -0,0 +1,87 @@
+package csrf
+
+import (
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/middleware/gin/basicauth"
+)
+
+var profileForm = template.Must(template.New("profile").Parse(`<form method="POST" action="/profile">
+	{{ .csrfField }}
+	<input name="name">
+	<button>Save</button>
+</form>`))
+
+func FormRouter() *gin.Engine {
+	router := gin.Default()
+
+	// The token is kept in a signed cookie and must be sent back in a form field or X-CSRF-Token header
+	cfg := &Config{
+		Secret:     "csrf-secret",
+		ExemptUrls: []string{"/webhooks/*"},
+	}
+
+	router.Use(cfg.Middleware)
+
+	router.GET("/profile", func(ctx *gin.Context) {
+		ctx.Header("Content-Type", "text/html; charset=utf-8")
+		profileForm.Execute(ctx.Writer, gin.H{
+			"csrfField": TemplateField(ctx),
+		})
+	})
+
+	router.POST("/profile", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "saved "+ctx.PostForm("name"))
+	})
+
+	router.POST("/webhooks/payments", func(ctx *gin.Context) {
+		ctx.Status(http.StatusNoContent)
+	})
+
+	return router
+}
+
+func SessionRouter() *gin.Engine {
+	router := gin.Default()
+
+	auth := &basicauth.Config{
+		Users: []basicauth.User{
+			{
+				UserName: "UserName1",
+				Password: "Password1",
+			},
+		},
+		RestrictedUrls: []string{"/account/*"},
+		Session: &basicauth.SessionConfig{
+			Secrets:           []string{"session-secret"},
+			IdleTimeout:       30 * time.Minute,
+			SuppressChallenge: true,
+		},
+	}
+
+	// Synchronizer tokens are bound to the logged in user, so the csrf middleware runs after the auth middleware
+	cfg := &Config{
+		Mode:       Synchronizer,
+		Secret:     "csrf-secret",
+		ExemptUrls: []string{"/login"},
+	}
+
+	router.Use(auth.Middleware, cfg.Middleware)
+
+	router.POST("/login", auth.LoginHandler)
+
+	router.GET("/account/token", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{
+			"token": Token(ctx),
+		})
+	})
+
+	router.POST("/account/email", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "email changed")
+	})
+
+	return router
+}
@@ -0,0 +1,44 @@
+package csrf
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/middleware/internal/csrf"
+)
+
+// Keys the token and its hidden form input are stored in gin context with.
+const (
+	TokenKey = "csrf.token"
+	FieldKey = "csrf.field"
+)
+
+// method for checking csrf tokens
+func (cfg *Config) Middleware(ctx *gin.Context) {
+	protector := cfg.getProtector()
+	token, err := protector.Protect(ctx.Writer, ctx.Request)
+
+	field := protector.TemplateField(token)
+	ctx.Set(TokenKey, token)
+	ctx.Set(FieldKey, field)
+	ctx.Request = ctx.Request.WithContext(csrf.NewContext(ctx.Request.Context(), token, field))
+
+	if err != nil {
+		ctx.AbortWithError(http.StatusForbidden, err)
+		return
+	}
+	ctx.Next()
+}
+
+// Token returns the token to send with unsafe requests, e.g. for a meta tag read by JavaScript.
+func Token(ctx *gin.Context) string {
+	return ctx.GetString(TokenKey)
+}
+
+// TemplateField returns a hidden form input with the token to put in HTML forms.
+func TemplateField(ctx *gin.Context) template.HTML {
+	v, _ := ctx.Get(FieldKey)
+	field, _ := v.(template.HTML)
+	return field
+}
@@ -0,0 +1,56 @@
+package headerauth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RestrictAllRouter() *gin.Engine {
+	router := gin.Default()
+
+	// Only the SSO gateway in 10.0.0.0/8 may tell who the user is
+	cfg := Config{
+		TrustedProxies:    []string{"10.0.0.0/8"},
+		RequireAuthForAll: true,
+	}
+
+	router.Use(cfg.Middleware)
+
+	router.GET("/whoami", func(ctx *gin.Context) {
+		principal, _ := GetPrincipal(ctx)
+		ctx.JSON(http.StatusOK, gin.H{
+			"name":   principal.Name,
+			"groups": principal.Roles,
+		})
+	})
+
+	return router
+}
+
+func SignedHeadersRouter() *gin.Engine {
+	router := gin.Default()
+
+	// The gateway signs identity headers, so they can pass through other proxies
+	cfg := Config{
+		SignatureSecrets: []string{"gateway-secret"},
+		RestrictedUrls:   []string{"/admin/*"},
+	}
+
+	router.Use(cfg.Middleware)
+
+	router.GET("/admin/whoami", func(ctx *gin.Context) {
+		principal, _ := GetPrincipal(ctx)
+		ctx.JSON(http.StatusOK, gin.H{
+			"name": principal.Name,
+		})
+	})
+
+	router.GET("/openurl", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{
+			"user": ctx.GetHeader("X-Forwarded-User"),
+		})
+	})
+
+	return router
+}
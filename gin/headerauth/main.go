@@ -0,0 +1,81 @@
+package headerauth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/clientip"
+	"github.com/golanguzb70/middleware/internal/proxyheader"
+)
+
+// This is configuration struct of trusted reverse proxy header auth.
+// Identity headers are accepted only if the direct peer is a trusted proxy or the headers are signed,
+// otherwise they are removed from the request.
+type Config struct {
+	// UserHeader carries the user name. Default is X-Forwarded-User.
+	UserHeader string `json:"user_header"`
+	// GroupsHeader carries comma separated groups that become roles of the principal. Default is X-Forwarded-Groups.
+	GroupsHeader string `json:"groups_header"`
+	// TrustedProxies are CIDRs of the proxies that may set identity headers, e.g. 10.0.0.0/8.
+	// Invalid entries never match.
+	TrustedProxies []string `json:"trusted_proxies"`
+	// SignatureSecrets verify "t=<unix time>,v1=<hex hmac-sha256>" signature of the identity headers,
+	// the method and the target of the request.
+	// Several secrets may be given while a secret is rotated.
+	SignatureSecrets []string `json:"signature_secrets"`
+	// SignatureHeader carries the signature. Default is X-Forwarded-Signature.
+	SignatureHeader string `json:"signature_header"`
+	// MaxSignatureAge limits the age of a signature, and how far its time may be ahead of the clock. Default is five minutes.
+	MaxSignatureAge time.Duration `json:"max_signature_age"`
+	// Restricted Method means that the middleware only applies for method are given.
+	RestrictedMethods []string `json:"restricted_methods"`
+	// Restricted urls are the urls that are authoriztion is required.
+	// The patterns are the same as in basicauth: /v1/user, /v1/user/{key}, /v1/user/*
+	RestrictedUrls []string `json:"restricted_urls"`
+	// If this field is set to true, all the requests are authenticated
+	RequireAuthForAll bool `json:"require_auth_for_all"`
+
+	once          sync.Once
+	authenticator *proxyheader.Authenticator
+}
+
+// Principal is the authenticated caller of a request.
+// It is the same type for every auth middleware of this module.
+type Principal = auth.Principal
+
+type Auth interface {
+	Middleware(c *gin.Context)
+}
+
+// turning struct into a interface
+func New(conf *Config) Auth {
+	return conf
+}
+
+// Sign returns the signature header value for an identity sent with a request. Use it in a gateway written in Go.
+// The target is the path and query of the request as the service receives it, e.g. /v1/users?page=2.
+func Sign(secret, method, target, user, groups string, t time.Time) string {
+	return proxyheader.Sign(secret, method, target, user, groups, t)
+}
+
+// Validate checks the trusted proxy networks.
+func (cfg *Config) Validate() error {
+	_, err := clientip.ParseSet(cfg.TrustedProxies)
+	return err
+}
+
+func (cfg *Config) getAuthenticator() *proxyheader.Authenticator {
+	cfg.once.Do(func() {
+		cfg.authenticator = &proxyheader.Authenticator{
+			UserHeader:      cfg.UserHeader,
+			GroupsHeader:    cfg.GroupsHeader,
+			SignatureHeader: cfg.SignatureHeader,
+			TrustedProxies:  clientip.ParseValid(cfg.TrustedProxies),
+			Secrets:         cfg.SignatureSecrets,
+			MaxAge:          cfg.MaxSignatureAge,
+		}
+	})
+	return cfg.authenticator
+}
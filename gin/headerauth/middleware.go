@@ -0,0 +1,42 @@
+package headerauth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/rules"
+)
+
+// method for checking authorization
+func (cfg *Config) Middleware(ctx *gin.Context) {
+	// identity headers are always checked, so untrusted ones are removed even on open urls
+	principal, err := cfg.getAuthenticator().Authenticate(ctx.Request)
+	if err == nil {
+		ctx.Request = ctx.Request.WithContext(auth.NewContext(ctx.Request.Context(), principal))
+		ctx.Next()
+		return
+	}
+
+	authRequired := rules.Policy{
+		RequireAuthForAll: cfg.RequireAuthForAll,
+		RestrictedMethods: cfg.RestrictedMethods,
+		RestrictedUrls:    cfg.RestrictedUrls,
+	}.Required(ctx.Request.Method, ctx.Request.URL.Path)
+
+	if authRequired {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	ctx.Next()
+}
+
+// Authenticator returns the identity header scheme of the config to be combined with other schemes in chain package.
+func (cfg *Config) Authenticator() auth.Authenticator {
+	return cfg.getAuthenticator()
+}
+
+// GetPrincipal returns the caller the request was authenticated as.
+func GetPrincipal(ctx *gin.Context) (*Principal, bool) {
+	return auth.FromContext(ctx.Request.Context())
+}
@@ -0,0 +1,73 @@
+package headerauth
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gotest.tools/assert"
+)
+
+func TestTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := RestrictAllRouter()
+
+	req := httptest.NewRequest("GET", "/whoami", nil)
+	req.RemoteAddr = "10.0.0.5:5000"
+	req.Header.Set("X-Forwarded-User", "alice")
+	req.Header.Set("X-Forwarded-Groups", "admins,devs")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, `{"groups":["admins","devs"],"name":"alice"}`, w.Body.String())
+
+	req = httptest.NewRequest("GET", "/whoami", nil)
+	req.RemoteAddr = "203.0.113.9:5000"
+	req.Header.Set("X-Forwarded-User", "alice")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+
+	req = httptest.NewRequest("GET", "/whoami", nil)
+	req.RemoteAddr = "10.0.0.5:5000"
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+}
+
+func TestSignedHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := SignedHeadersRouter()
+
+	req := httptest.NewRequest("GET", "/admin/whoami", nil)
+	req.Header.Set("X-Forwarded-User", "alice")
+	req.Header.Set("X-Forwarded-Signature", Sign("gateway-secret", "GET", "/admin/whoami", "alice", "", time.Now()))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, `{"name":"alice"}`, w.Body.String())
+
+	req = httptest.NewRequest("GET", "/admin/whoami", nil)
+	req.Header.Set("X-Forwarded-User", "mallory")
+	req.Header.Set("X-Forwarded-Signature", Sign("gateway-secret", "GET", "/admin/whoami", "alice", "", time.Now()))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+
+	// a signature is valid only for the request it was made for
+	req = httptest.NewRequest("GET", "/admin/whoami?debug=1", nil)
+	req.Header.Set("X-Forwarded-User", "alice")
+	req.Header.Set("X-Forwarded-Signature", Sign("gateway-secret", "GET", "/admin/whoami", "alice", "", time.Now()))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+
+	// forged headers are removed even on open urls
+	req = httptest.NewRequest("GET", "/openurl", nil)
+	req.Header.Set("X-Forwarded-User", "mallory")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, `{"user":""}`, w.Body.String())
+}
@@ -0,0 +1,42 @@
+package hmacauth
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RestrictByUrlRouter() *gin.Engine {
+	router := gin.Default()
+
+	// Partners call the webhook with signed requests
+	cfg := Config{
+		Keys: map[string]string{
+			"partner1":      "partner1-secret",
+			"partner2-2024": "partner2-old-secret",
+			"partner2-2025": "partner2-new-secret",
+		},
+		SignedHeaders:  []string{"host", "content-type"},
+		RestrictedUrls: []string{"/partner/*"},
+	}
+
+	router.Use(cfg.Middleware)
+
+	router.POST("/partner/orders", func(ctx *gin.Context) {
+		principal, _ := GetPrincipal(ctx)
+		body, _ := io.ReadAll(ctx.Request.Body)
+		ctx.JSON(http.StatusOK, gin.H{
+			"key_id": principal.Name,
+			"body":   string(body),
+		})
+	})
+
+	router.GET("/openurl", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "open",
+		})
+	})
+
+	return router
+}
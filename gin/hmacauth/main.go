@@ -0,0 +1,72 @@
+package hmacauth
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/hmacsig"
+)
+
+// This is configuration struct of HMAC request signing auth.
+// Partners sign method, path, query, selected headers, body digest, timestamp and nonce of a request
+// with a shared secret, see the README for the format.
+type Config struct {
+	// Keys maps key ids to shared secrets. Several keys of a partner may be active while a secret is rotated.
+	Keys map[string]string `json:"keys"`
+	// SignedHeaders must be signed by every request, e.g. host and content-type.
+	SignedHeaders []string `json:"signed_headers"`
+	// MaxClockSkew is the maximum difference between the timestamp of a request and the server time.
+	// Default is five minutes.
+	MaxClockSkew time.Duration `json:"max_clock_skew"`
+	// MaxBodySize limits the body read to check its digest. Default is 10 MB.
+	MaxBodySize int64 `json:"max_body_size"`
+	// NonceStore remembers used nonces. Default is an in-memory store, use a shared one if the service has several instances.
+	NonceStore NonceStore `json:"-"`
+	// Restricted Method means that the middleware only applies for method are given.
+	RestrictedMethods []string `json:"restricted_methods"`
+	// Restricted urls are the urls that are authoriztion is required.
+	// The patterns are the same as in basicauth: /v1/user, /v1/user/{key}, /v1/user/*
+	RestrictedUrls []string `json:"restricted_urls"`
+	// If this field is set to true, all the requests are authenticated
+	RequireAuthForAll bool `json:"require_auth_for_all"`
+
+	once     sync.Once
+	verifier *hmacsig.Verifier
+}
+
+// NonceStore remembers used nonces to block replayed requests.
+type NonceStore = hmacsig.NonceStore
+
+// Principal is the authenticated caller of a request. Its name is the key id.
+// It is the same type for every auth middleware of this module.
+type Principal = auth.Principal
+
+type Auth interface {
+	Middleware(c *gin.Context)
+}
+
+// turning struct into a interface
+func New(conf *Config) Auth {
+	return conf
+}
+
+// Sign adds the signature headers to a request. Host is always signed, signedHeaders are signed as well.
+func Sign(r *http.Request, keyID, secret string, signedHeaders []string) error {
+	return hmacsig.Sign(r, keyID, secret, signedHeaders, time.Now())
+}
+
+func (cfg *Config) getVerifier() *hmacsig.Verifier {
+	cfg.once.Do(func() {
+		cfg.verifier = &hmacsig.Verifier{
+			Keys:            cfg.Keys,
+			RequiredHeaders: cfg.SignedHeaders,
+			Window:          cfg.MaxClockSkew,
+			Nonces:          cfg.NonceStore,
+			MaxBodySize:     cfg.MaxBodySize,
+		}
+	})
+	return cfg.verifier
+}
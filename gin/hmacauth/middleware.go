@@ -0,0 +1,44 @@
+package hmacauth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/rules"
+)
+
+// method for checking authorization
+func (cfg *Config) Middleware(ctx *gin.Context) {
+	authRequired := rules.Policy{
+		RequireAuthForAll: cfg.RequireAuthForAll,
+		RestrictedMethods: cfg.RestrictedMethods,
+		RestrictedUrls:    cfg.RestrictedUrls,
+	}.Required(ctx.Request.Method, ctx.Request.URL.Path)
+
+	if !authRequired {
+		ctx.Next()
+		return
+	}
+
+	verifier := cfg.getVerifier()
+	principal, err := verifier.Authenticate(ctx.Request)
+	if err != nil {
+		ctx.Header("WWW-Authenticate", verifier.Challenge(err))
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	ctx.Request = ctx.Request.WithContext(auth.NewContext(ctx.Request.Context(), principal))
+	ctx.Next()
+}
+
+// Authenticator returns the request signing scheme of the config to be combined with other schemes in chain package.
+func (cfg *Config) Authenticator() auth.Authenticator {
+	return cfg.getVerifier()
+}
+
+// GetPrincipal returns the caller the request was authenticated as. Name of the principal is the key id.
+func GetPrincipal(ctx *gin.Context) (*Principal, bool) {
+	return auth.FromContext(ctx.Request.Context())
+}
@@ -0,0 +1,39 @@
+package httpsigauth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RestrictByUrlRouter() *gin.Engine {
+	router := gin.Default()
+
+	// Partners sign the method, target uri and body digest of every call to /partner/*
+	cfg := Config{
+		Secrets:              map[string]string{"partner-hmac": "partner-secret"},
+		RequiredComponents:   []string{"@method", "@target-uri"},
+		RequireContentDigest: true,
+		RestrictedUrls:       []string{"/partner/*"},
+	}
+
+	router.Use(cfg.Middleware)
+
+	router.POST("/partner/orders", func(ctx *gin.Context) {
+		principal, _ := GetPrincipal(ctx)
+		sig, _ := GetSignature(ctx)
+		ctx.JSON(http.StatusOK, gin.H{
+			"key_id":     principal.Name,
+			"algorithm":  sig.Algorithm,
+			"components": len(sig.Components),
+		})
+	})
+
+	router.GET("/openurl", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "open",
+		})
+	})
+
+	return router
+}
@@ -0,0 +1,60 @@
+package httpsigauth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/httpsig"
+	"github.com/golanguzb70/middleware/internal/rules"
+)
+
+// SignatureKey is the key the verified signature is stored in gin context with.
+const SignatureKey = "httpsigauth.signature"
+
+// method for checking authorization
+func (cfg *Config) Middleware(ctx *gin.Context) {
+	authRequired := rules.Policy{
+		RequireAuthForAll: cfg.RequireAuthForAll,
+		RestrictedMethods: cfg.RestrictedMethods,
+		RestrictedUrls:    cfg.RestrictedUrls,
+	}.Required(ctx.Request.Method, ctx.Request.URL.Path)
+
+	if !authRequired {
+		ctx.Next()
+		return
+	}
+
+	verifier := cfg.getVerifier()
+	sig, err := verifier.Verify(ctx.Request)
+	if err != nil {
+		ctx.Header("WWW-Authenticate", verifier.Challenge(err))
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	ctx.Set(SignatureKey, sig)
+	reqCtx := httpsig.NewContext(ctx.Request.Context(), sig)
+	ctx.Request = ctx.Request.WithContext(auth.NewContext(reqCtx, httpsig.Principal(sig)))
+	ctx.Next()
+}
+
+// Authenticator returns the message signature scheme of the config to be combined with other schemes in chain package.
+func (cfg *Config) Authenticator() auth.Authenticator {
+	return cfg.getVerifier()
+}
+
+// GetSignature returns the verified signature of the request.
+func GetSignature(ctx *gin.Context) (*Signature, bool) {
+	v, ok := ctx.Get(SignatureKey)
+	if !ok {
+		return nil, false
+	}
+	sig, ok := v.(*Signature)
+	return sig, ok
+}
+
+// GetPrincipal returns the caller the request was authenticated as. Name of the principal is the key id.
+func GetPrincipal(ctx *gin.Context) (*Principal, bool) {
+	return auth.FromContext(ctx.Request.Context())
+}
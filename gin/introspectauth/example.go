@@ -0,0 +1,45 @@
+package introspectauth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RestrictByScopeRouter(introspectionURL string) *gin.Engine {
+	router := gin.Default()
+
+	// Every /v1 request requires an active token, deleting users requires "user:write" scope in addition.
+	cfg := Config{
+		IntrospectionURL: introspectionURL,
+		ClientID:         "resource-server",
+		ClientSecret:     "secret",
+		RequiredScopes: map[string][]string{
+			"/v1/*":                {"user:read"},
+			"DELETE /v1/user/{id}": {"user:write"},
+		},
+	}
+
+	router.Use(cfg.Middleware)
+
+	router.GET("/v1/user/:id", func(ctx *gin.Context) {
+		resp, _ := GetIntrospection(ctx)
+		ctx.JSON(http.StatusOK, gin.H{
+			"subject": resp.Subject,
+		})
+	})
+
+	router.DELETE("/v1/user/:id", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "You have been asked a token with user:write scope to delete user " + ctx.Param("id"),
+		})
+	})
+
+	router.GET("/openurl", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "You gen call me without any token because I am not restricted url.",
+		})
+	})
+
+	return router
+}
@@ -0,0 +1,64 @@
+package introspectauth
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/introspect"
+	"github.com/golanguzb70/middleware/internal/rules"
+)
+
+// IntrospectionKey is the key the introspection response is stored in gin context with.
+const IntrospectionKey = "introspectauth.introspection"
+
+// method for checking authorization
+func (cfg *Config) Middleware(ctx *gin.Context) {
+	var (
+		method = ctx.Request.Method
+		url    = ctx.Request.URL.Path
+	)
+
+	authRequired := len(introspect.RequiredScopes(cfg.RequiredScopes, method, url)) > 0 || rules.Policy{
+		RequireAuthForAll: cfg.RequireAuthForAll,
+		RestrictedMethods: cfg.RestrictedMethods,
+		RestrictedUrls:    cfg.RestrictedUrls,
+	}.Required(method, url)
+
+	if !authRequired {
+		ctx.Next()
+		return
+	}
+
+	// endpoint errors are treated as invalid token, requests are never let through without an answer
+	authenticator := cfg.getAuthenticator()
+	resp, err := authenticator.Check(ctx.Request)
+	if err != nil {
+		ctx.Header("WWW-Authenticate", authenticator.Challenge(err))
+		ctx.AbortWithStatus(auth.Status(err))
+		return
+	}
+
+	ctx.Set(IntrospectionKey, resp)
+	reqCtx := introspect.NewContext(ctx.Request.Context(), resp)
+	ctx.Request = ctx.Request.WithContext(auth.NewContext(reqCtx, introspect.Principal(resp)))
+	ctx.Next()
+}
+
+// Authenticator returns the bearer token scheme of the config to be combined with other schemes in chain package.
+func (cfg *Config) Authenticator() auth.Authenticator {
+	return cfg.getAuthenticator()
+}
+
+// GetIntrospection returns the introspection response of the token the request was authenticated with.
+func GetIntrospection(ctx *gin.Context) (*Introspection, bool) {
+	v, ok := ctx.Get(IntrospectionKey)
+	if !ok {
+		return nil, false
+	}
+	resp, ok := v.(*Introspection)
+	return resp, ok
+}
+
+// GetPrincipal returns the caller the request was authenticated as.
+func GetPrincipal(ctx *gin.Context) (*Principal, bool) {
+	return auth.FromContext(ctx.Request.Context())
+}
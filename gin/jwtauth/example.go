@@ -0,0 +1,60 @@
+package jwtauth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RestrictAllRouter() *gin.Engine {
+	router := gin.Default()
+
+	// This configuration checks for all incoming requests for a HS256 token signed with "secret"
+	cfg := Config{
+		Secrets: map[string]string{
+			"": "secret",
+		},
+		Issuers:           []string{"https://issuer.example.com"},
+		Audiences:         []string{"api"},
+		RequireAuthForAll: true,
+	}
+
+	router.Use(cfg.Middleware)
+
+	router.GET("/me", func(ctx *gin.Context) {
+		claims, _ := GetClaims(ctx)
+		ctx.JSON(http.StatusOK, gin.H{
+			"subject": claims.Subject,
+		})
+	})
+
+	return router
+}
+
+func RestrictByUrlRouter() *gin.Engine {
+	router := gin.Default()
+
+	cfg := Config{
+		Secrets: map[string]string{
+			"": "secret",
+		},
+		RestrictedUrls: []string{"/admin/*"},
+	}
+
+	router.Use(cfg.Middleware)
+
+	router.GET("/admin/stats", func(ctx *gin.Context) {
+		claims, _ := GetClaims(ctx)
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Hello " + claims.Subject + ", you have been asked a token to see me.",
+		})
+	})
+
+	router.GET("/openurl", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "You gen call me without any token because I am not restricted url.",
+		})
+	})
+
+	return router
+}
@@ -0,0 +1,60 @@
+package jwtauth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/jwt"
+	"github.com/golanguzb70/middleware/internal/rules"
+)
+
+// ClaimsKey is the key claims of a verified token are stored in gin context with.
+const ClaimsKey = "jwtauth.claims"
+
+// method for checking authorization
+func (cfg *Config) Middleware(ctx *gin.Context) {
+	authRequired := rules.Policy{
+		RequireAuthForAll: cfg.RequireAuthForAll,
+		RestrictedMethods: cfg.RestrictedMethods,
+		RestrictedUrls:    cfg.RestrictedUrls,
+	}.Required(ctx.Request.Method, ctx.Request.URL.Path)
+
+	if !authRequired {
+		ctx.Next()
+		return
+	}
+
+	verifier := cfg.getVerifier()
+	claims, err := verifier.VerifyRequest(ctx.Request)
+	if err != nil {
+		ctx.Header("WWW-Authenticate", verifier.Challenge(err))
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	ctx.Set(ClaimsKey, claims)
+	reqCtx := jwt.NewContext(ctx.Request.Context(), claims)
+	ctx.Request = ctx.Request.WithContext(auth.NewContext(reqCtx, jwt.Principal(claims)))
+	ctx.Next()
+}
+
+// Authenticator returns the bearer token scheme of the config to be combined with other schemes in chain package.
+func (cfg *Config) Authenticator() auth.Authenticator {
+	return cfg.getVerifier()
+}
+
+// GetPrincipal returns the caller the request was authenticated as. Name of the principal is the sub claim.
+func GetPrincipal(ctx *gin.Context) (*Principal, bool) {
+	return auth.FromContext(ctx.Request.Context())
+}
+
+// GetClaims returns claims of the token the request was authenticated with.
+func GetClaims(ctx *gin.Context) (*Claims, bool) {
+	v, ok := ctx.Get(ClaimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := v.(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,98 @@
+package jwtauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gotest.tools/assert"
+)
+
+func token(claims map[string]interface{}, secret string) string {
+	h, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	p, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(p)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestRequireAuthorizationAll(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := RestrictAllRouter()
+
+	valid := map[string]interface{}{
+		"sub": "user1",
+		"iss": "https://issuer.example.com",
+		"aud": "api",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+
+	req := httptest.NewRequest("GET", "/me", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+	assert.Equal(t, `Bearer realm="Authorization Required"`, w.Header().Get("WWW-Authenticate"))
+
+	req = httptest.NewRequest("GET", "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token(valid, "secret"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, `{"subject":"user1"}`, w.Body.String())
+
+	req = httptest.NewRequest("GET", "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token(valid, "wrong"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+	assert.Equal(t, `Bearer realm="Authorization Required", error="invalid_token", error_description="invalid signature"`, w.Header().Get("WWW-Authenticate"))
+
+	expired := map[string]interface{}{}
+	for k, v := range valid {
+		expired[k] = v
+	}
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	req = httptest.NewRequest("GET", "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token(expired, "secret"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+
+	wrongAudience := map[string]interface{}{}
+	for k, v := range valid {
+		wrongAudience[k] = v
+	}
+	wrongAudience["aud"] = "other"
+	req = httptest.NewRequest("GET", "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token(wrongAudience, "secret"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+}
+
+func TestRequireForSpecificUrls(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := RestrictByUrlRouter()
+
+	req := httptest.NewRequest("GET", "/admin/stats", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+
+	req = httptest.NewRequest("GET", "/openurl", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	req = httptest.NewRequest("GET", "/admin/stats", nil)
+	req.Header.Set("Authorization", "Bearer "+token(map[string]interface{}{"sub": "admin"}, "secret"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+}
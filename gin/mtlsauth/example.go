@@ -0,0 +1,33 @@
+package mtlsauth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RestrictAllRouter() *gin.Engine {
+	router := gin.Default()
+
+	// Only the billing service and services of the dev namespace issued by "Internal CA" can call this server.
+	cfg := Config{
+		Identities: []Identity{
+			{URI: "spiffe://example.org/ns/prod/sa/billing", Name: "billing", Roles: []string{"billing"}},
+			{URI: "spiffe://example.org/ns/dev/*", Roles: []string{"dev"}},
+		},
+		AllowedIssuers:    []string{"Internal CA"},
+		RequireAuthForAll: true,
+	}
+
+	router.Use(cfg.Middleware)
+
+	router.GET("/whoami", func(ctx *gin.Context) {
+		principal, _ := GetPrincipal(ctx)
+		ctx.JSON(http.StatusOK, gin.H{
+			"name":  principal.Name,
+			"roles": principal.Roles,
+		})
+	})
+
+	return router
+}
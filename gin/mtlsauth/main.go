@@ -0,0 +1,63 @@
+package mtlsauth
+
+import (
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/mtls"
+)
+
+// This is configuration struct of mutual TLS auth.
+// The TLS server must verify client certificates itself, e.g. with tls.RequireAndVerifyClientCert,
+// the middleware maps the verified certificate to a principal and rejects certificates without a verified chain.
+type Config struct {
+	// Identities maps client certificates to principals by subject CN, DNS SAN or URI SAN (SPIFFE ID).
+	// If it is empty, every certificate is accepted and named by its first URI SAN or subject CN.
+	Identities []Identity `json:"identities"`
+	// AllowedIssuers are common names or full distinguished names of the CAs client certificates may be issued by.
+	// If it is empty, every CA the TLS server trusts is allowed.
+	AllowedIssuers []string `json:"allowed_issuers"`
+	// CRLFile is a path to a PEM or DER certificate revocation list. The file is checked for changes every minute.
+	// If the list can not be read or is outdated, all requests are rejected.
+	CRLFile string `json:"crl_file"`
+	// Restricted Method means that the middleware only applies for method are given.
+	RestrictedMethods []string `json:"restricted_methods"`
+	// Restricted urls are the urls that are authoriztion is required.
+	// The patterns are the same as in basicauth: /v1/user, /v1/user/{key}, /v1/user/*
+	RestrictedUrls []string `json:"restricted_urls"`
+	// If this field is set to true, all the requests are authenticated
+	RequireAuthForAll bool `json:"require_auth_for_all"`
+
+	once          sync.Once
+	authenticator *mtls.Authenticator
+}
+
+// Identity maps a client certificate to a principal.
+type Identity = mtls.Identity
+
+// Principal is the authenticated caller of a request.
+// It is the same type for every auth middleware of this module.
+type Principal = auth.Principal
+
+type Auth interface {
+	Middleware(c *gin.Context)
+}
+
+// turning struct into a interface
+func New(conf *Config) Auth {
+	return conf
+}
+
+func (cfg *Config) getAuthenticator() *mtls.Authenticator {
+	cfg.once.Do(func() {
+		cfg.authenticator = &mtls.Authenticator{
+			Identities:     cfg.Identities,
+			AllowedIssuers: cfg.AllowedIssuers,
+		}
+		if cfg.CRLFile != "" {
+			cfg.authenticator.CRL = &mtls.CRL{Path: cfg.CRLFile}
+		}
+	})
+	return cfg.authenticator
+}
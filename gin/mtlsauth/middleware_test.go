@@ -0,0 +1,40 @@
+package mtlsauth
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/middleware/internal/testcert"
+	"gotest.tools/assert"
+)
+
+func TestRequireClientCertificate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := RestrictAllRouter()
+	ca := testcert.NewCA("Internal CA")
+
+	req := httptest.NewRequest("GET", "/whoami", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+
+	req = httptest.NewRequest("GET", "/whoami", nil)
+	req.TLS = testcert.ConnectionState(ca.Issue(testcert.Leaf{URIs: []string{"spiffe://example.org/ns/prod/sa/billing"}}))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, `{"name":"billing","roles":["billing"]}`, w.Body.String())
+
+	req = httptest.NewRequest("GET", "/whoami", nil)
+	req.TLS = testcert.ConnectionState(ca.Issue(testcert.Leaf{URIs: []string{"spiffe://example.org/ns/prod/sa/orders"}}))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+
+	req = httptest.NewRequest("GET", "/whoami", nil)
+	req.TLS = testcert.ConnectionState(testcert.NewCA("Other CA").Issue(testcert.Leaf{URIs: []string{"spiffe://example.org/ns/dev/sa/api"}}))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+}
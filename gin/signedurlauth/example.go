@@ -0,0 +1,45 @@
+package signedurlauth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func DownloadRouter() *gin.Engine {
+	router := gin.Default()
+
+	cfg := &Config{
+		Keys: map[string]string{
+			"2024": "old-link-secret",
+			"2025": "new-link-secret",
+		},
+		SigningKeyID:   "2025",
+		RestrictedUrls: []string{"/downloads/*"},
+	}
+
+	router.Use(cfg.Middleware)
+
+	// Hands out a link that works for ten minutes and only once
+	router.GET("/links/:file", func(ctx *gin.Context) {
+		link, err := cfg.SignURL("/downloads/"+ctx.Param("file"), SignOptions{
+			TTL:     10 * time.Minute,
+			Method:  http.MethodGet,
+			OneTime: true,
+		})
+		if err != nil {
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"url": link,
+		})
+	})
+
+	router.GET("/downloads/:file", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "content of "+ctx.Param("file"))
+	})
+
+	return router
+}
@@ -0,0 +1,96 @@
+package signedurlauth
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/clientip"
+	"github.com/golanguzb70/middleware/internal/nonce"
+	"github.com/golanguzb70/middleware/internal/signedurl"
+)
+
+// This is configuration struct of signed, expiring URLs.
+// Links signed with SignURL work without credentials until they expire.
+type Config struct {
+	// Keys maps key ids to secrets. Links signed with any of the keys are accepted, so secrets can be rotated.
+	Keys map[string]string `json:"keys"`
+	// SigningKeyID is the key new links are signed with. It may be omitted if there is only one key.
+	SigningKeyID string `json:"signing_key_id"`
+	// UsedTokenStore remembers one-time links that were used. Default is an in-memory store,
+	// use a shared one if the service has several instances.
+	UsedTokenStore UsedTokenStore `json:"-"`
+	// TrustedProxies are CIDRs of the reverse proxies in front of the service, e.g. 10.0.0.0/8.
+	// Links restricted to an IP are checked against the client address the trusted proxies forward.
+	// Without trusted proxies, the address of the direct peer is checked. Invalid entries never match.
+	TrustedProxies []string `json:"trusted_proxies"`
+	// ForwardedHeader is the header trusted proxies forward the client address in: X-Forwarded-For (default) or Forwarded.
+	ForwardedHeader string `json:"forwarded_header"`
+	// Restricted Method means that the middleware only applies for method are given.
+	RestrictedMethods []string `json:"restricted_methods"`
+	// Restricted urls are the urls that are authoriztion is required.
+	// The patterns are the same as in basicauth: /v1/user, /v1/user/{key}, /v1/user/*
+	RestrictedUrls []string `json:"restricted_urls"`
+	// If this field is set to true, all the requests are authenticated
+	RequireAuthForAll bool `json:"require_auth_for_all"`
+
+	once     sync.Once
+	verifier *signedurl.Verifier
+}
+
+// SignOptions are the expiry and the restrictions of a link.
+type SignOptions = signedurl.Options
+
+// UsedTokenStore remembers tokens of one-time links.
+// The key id and the token identify a link, the token may be forgotten after expires.
+type UsedTokenStore = nonce.Store
+
+// Principal is the authenticated caller of a request. Its name is the key id of the link.
+// It is the same type for every auth middleware of this module.
+type Principal = auth.Principal
+
+type Auth interface {
+	Middleware(c *gin.Context)
+}
+
+// turning struct into a interface
+func New(conf *Config) Auth {
+	return conf
+}
+
+// SignURL returns the url with signature parameters. The url may be absolute or only a path with query.
+func (cfg *Config) SignURL(rawURL string, opts SignOptions) (string, error) {
+	keyID := cfg.SigningKeyID
+	if keyID == "" && len(cfg.Keys) == 1 {
+		for id := range cfg.Keys {
+			keyID = id
+		}
+	}
+	secret, ok := cfg.Keys[keyID]
+	if !ok {
+		return "", errors.New("signedurlauth: signing key is not set")
+	}
+	return signedurl.Sign(rawURL, keyID, secret, opts, time.Now())
+}
+
+// Validate checks the trusted proxy networks.
+func (cfg *Config) Validate() error {
+	_, err := clientip.ParseSet(cfg.TrustedProxies)
+	return err
+}
+
+func (cfg *Config) getVerifier() *signedurl.Verifier {
+	cfg.once.Do(func() {
+		cfg.verifier = &signedurl.Verifier{
+			Keys:       cfg.Keys,
+			UsedTokens: cfg.UsedTokenStore,
+			ClientIP: clientip.Resolver{
+				TrustedProxies: clientip.ParseValid(cfg.TrustedProxies),
+				Header:         cfg.ForwardedHeader,
+			},
+		}
+	})
+	return cfg.verifier
+}
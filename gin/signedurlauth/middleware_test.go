@@ -0,0 +1,69 @@
+package signedurlauth
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gotest.tools/assert"
+)
+
+func TestDownloadLink(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := DownloadRouter()
+
+	req := httptest.NewRequest("GET", "/links/report.pdf", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var body struct {
+		URL string `json:"url"`
+	}
+	assert.NilError(t, json.Unmarshal(w.Body.Bytes(), &body))
+
+	req = httptest.NewRequest("GET", body.URL, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "content of report.pdf", w.Body.String())
+
+	// one-time link
+	req = httptest.NewRequest("GET", body.URL, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+
+	req = httptest.NewRequest("GET", "/downloads/report.pdf", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+}
+
+func TestKeyRotation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := DownloadRouter()
+
+	// links signed with the old key still work until they expire
+	old := &Config{Keys: map[string]string{"2024": "old-link-secret"}}
+	link, err := old.SignURL("/downloads/report.pdf", SignOptions{TTL: time.Minute})
+	assert.NilError(t, err)
+
+	req := httptest.NewRequest("GET", link, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	link, err = old.SignURL("/downloads/report.pdf", SignOptions{TTL: -time.Minute})
+	assert.NilError(t, err)
+	req = httptest.NewRequest("GET", link, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+
+	unknown := &Config{Keys: map[string]string{"a": "1", "b": "2"}}
+	_, err = unknown.SignURL("/downloads/report.pdf", SignOptions{TTL: time.Minute})
+	assert.ErrorContains(t, err, "signing key is not set")
+}
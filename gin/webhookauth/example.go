@@ -0,0 +1,40 @@
+package webhookauth
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func WebhooksRouter() *gin.Engine {
+	router := gin.Default()
+
+	// Every provider has its own config, the middleware is added to its route only
+	github := Config{
+		Provider:          GitHub,
+		Secrets:           []string{"github-secret"},
+		RequireAuthForAll: true,
+	}
+	stripe := Config{
+		Provider: Stripe,
+		// the new secret is added before it is rolled in the Stripe dashboard
+		Secrets:           []string{"stripe-new-secret", "stripe-old-secret"},
+		RequireAuthForAll: true,
+	}
+
+	handler := func(ctx *gin.Context) {
+		principal, _ := GetPrincipal(ctx)
+		// the body is still readable after the signature check
+		body, _ := io.ReadAll(ctx.Request.Body)
+		ctx.JSON(http.StatusOK, gin.H{
+			"provider": principal.Name,
+			"body":     string(body),
+		})
+	}
+
+	router.POST("/webhooks/github", github.Middleware, handler)
+	router.POST("/webhooks/stripe", stripe.Middleware, handler)
+
+	return router
+}
@@ -0,0 +1,67 @@
+package basicauth
+
+import (
+	"net/http"
+
+	"github.com/golanguzb70/middleware/internal/basic"
+	"github.com/golanguzb70/middleware/internal/rules"
+	"github.com/golanguzb70/middleware/internal/totp"
+)
+
+// Decide returns the decision the middleware would make about the request without serving it,
+// e.g. to test the config in CI. Session cookies are not refreshed, and used one-time codes are remembered
+// only for the call, so use it in tools rather than in request handlers.
+func (cfg Config) Decide(r *http.Request) *Decision {
+	return newEvaluator(cfg).decide(r, nil)
+}
+
+// evaluator decides about requests with a config without enforcing the decisions.
+type evaluator struct {
+	cfg           Config
+	authenticator *basic.Authenticator
+	policy        rules.Policy
+	realms        []*basic.Authenticator
+}
+
+func newEvaluator(cfg Config) *evaluator {
+	e := &evaluator{
+		cfg:           cfg,
+		authenticator: cfg.authenticator(),
+		policy:        cfg.policy(),
+		realms:        make([]*basic.Authenticator, len(cfg.Realms)),
+	}
+	for i := range cfg.Realms {
+		e.realms[i] = cfg.realmAuthenticator(&cfg.Realms[i])
+	}
+	// decisions that are not enforced do not upgrade password hashes
+	e.authenticator.Rehash = nil
+	for _, realm := range e.realms {
+		realm.Rehash = nil
+	}
+	// without a TOTP config the evaluator remembers used codes by itself,
+	// so evaluating a request does not use up its code for the enforced config
+	if cfg.TOTP == nil {
+		verifier := &totp.Verifier{Config: &totp.Config{}}
+		e.authenticator.TOTP = verifier
+		for _, realm := range e.realms {
+			realm.TOTP = verifier
+		}
+	}
+	return e
+}
+
+// decide decides about the request without refreshing sessions.
+// The request is in the realm with the name of the enforced realm if that realm has no path prefixes.
+func (e *evaluator) decide(r *http.Request, enforcedRealm *Realm) *Decision {
+	if enforcedRealm != nil && len(enforcedRealm.PathPrefixes) == 0 {
+		for i := range e.cfg.Realms {
+			if e.cfg.Realms[i].Name == enforcedRealm.Name {
+				return e.realms[i].Decide(nil, r, "realm "+enforcedRealm.Name)
+			}
+		}
+	}
+	if i := e.cfg.realmFor(r.URL.Path); i >= 0 {
+		return e.realms[i].Decide(nil, r, "realm "+e.cfg.Realms[i].Name)
+	}
+	return e.authenticator.Decide(nil, r, e.policy.Explain(r))
+}
@@ -1,7 +1,9 @@
 package basicauth
 
 import (
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -123,3 +125,410 @@ func RestrictByMethodsRouter() *mux.Router {
 
 	return router
 }
+
+func ProxyAuthRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	// This configuration makes the middleware authenticate clients of a forward proxy
+	config := Config{
+		Users: []User{
+			{
+				UserName: "username",
+				Password: "password",
+			},
+		},
+		RequireAuthForAll: true,
+		ProxyAuth:         true,
+	}
+
+	router.Use(Middleware(config))
+
+	// a real proxy forwards the request here, e.g. with httputil.ReverseProxy
+	router.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Proxy-Authorization: " + r.Header.Get("Proxy-Authorization")))
+	})
+
+	return router
+}
+
+func SessionRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	// Browsers log in once and send the session cookie, API clients keep using Basic credentials
+	config := Config{
+		Users: []User{
+			{
+				UserName: "username",
+				Password: "password",
+				Roles:    []string{"admin"},
+			},
+		},
+		RestrictedUrls: []string{"/admin/*"},
+		Session: &SessionConfig{
+			Secrets:           []string{"session-secret"},
+			IdleTimeout:       30 * time.Minute,
+			AbsoluteTimeout:   8 * time.Hour,
+			SuppressChallenge: true,
+		},
+	}
+
+	router.Use(Middleware(config))
+
+	router.HandleFunc("/login", LoginHandler(config)).Methods("POST")
+	router.HandleFunc("/logout", LogoutHandler(config)).Methods("POST")
+
+	router.HandleFunc("/admin/whoami", func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		w.Write([]byte(principal.Name + " " + principal.Scheme))
+	}).Methods("GET")
+
+	return router
+}
+
+func TokenRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	// Clients exchange Basic credentials for a short-lived access token once and then send it as a bearer token
+	config := Config{
+		Users: []User{
+			{
+				UserName: "username",
+				Password: "password",
+				Roles:    []string{"admin"},
+			},
+		},
+		RestrictedUrls: []string{"/api/*"},
+		Tokens: &TokenConfig{
+			Keys:       map[string]string{"2025": "token-secret"},
+			Issuer:     "example",
+			AccessTTL:  5 * time.Minute,
+			RefreshTTL: 24 * time.Hour,
+		},
+	}
+
+	router.Use(Middleware(config))
+
+	router.HandleFunc("/oauth/token", TokenHandler(config)).Methods("POST")
+	router.HandleFunc("/oauth/revoke", RevokeTokenHandler(config)).Methods("POST")
+
+	router.HandleFunc("/api/whoami", func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		w.Write([]byte(principal.Name + " " + principal.Scheme))
+	}).Methods("GET")
+
+	return router
+}
+
+func TOTPRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	// The admin must send a one-time code of an authenticator app too, the reporter uses only the password
+	config := Config{
+		Users: []User{
+			{
+				UserName:   "admin",
+				Password:   "admin-password",
+				Roles:      []string{"admin"},
+				TOTPSecret: "JBSWY3DPEHPK3PXP",
+			},
+			{
+				UserName: "reporter",
+				Password: "reporter-password",
+			},
+		},
+		RestrictedUrls: []string{"/admin/*"},
+		TOTP: &TOTPConfig{
+			Header: "X-OTP",
+			Window: 1,
+		},
+	}
+
+	router.Use(Middleware(config))
+
+	router.HandleFunc("/admin/whoami", func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		w.Write([]byte(principal.Name))
+	}).Methods("GET")
+
+	return router
+}
+
+func PasswordRotationRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	// The password of the billing service is being rotated: the old one works until the end of the day,
+	// so clients can be redeployed with the new one without downtime.
+	endOfDay := time.Now().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	config := Config{
+		Users: []User{
+			{
+				UserName: "billing",
+				Credentials: []Credential{
+					{Password: "old-password", NotAfter: endOfDay},
+					{Password: "new-password"},
+				},
+			},
+			{
+				UserName:  "contractor",
+				Password:  "contractor-password",
+				ExpiresAt: time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			{
+				UserName: "former-employee",
+				Password: "password",
+				Disabled: true,
+			},
+		},
+		RequireAuthForAll: true,
+	}
+
+	router.Use(Middleware(config))
+
+	router.HandleFunc("/invoices", func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		w.Write([]byte("invoices for " + principal.Name))
+	}).Methods("GET")
+
+	return router
+}
+
+func NetworkRestrictedRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	// The deploy account works only from the CI network, even with the right password.
+	// The service runs behind a load balancer in 10.0.0.0/8 that sets X-Forwarded-For.
+	config := Config{
+		Users: []User{
+			{
+				UserName:     "deployer",
+				Password:     "deployer-password",
+				AllowedCIDRs: []string{"192.168.100.0/24", "2001:db8:100::/48"},
+			},
+			{
+				UserName: "username",
+				Password: "password",
+			},
+		},
+		RestrictedUrls:  []string{"/deploy"},
+		TrustedProxies:  []string{"10.0.0.0/8"},
+		ForwardedHeader: "X-Forwarded-For",
+	}
+
+	router.Use(Middleware(config))
+
+	router.HandleFunc("/deploy", func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		w.Write([]byte("deployed by " + principal.Name))
+	}).Methods("POST")
+
+	return router
+}
+
+func BusinessHoursRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	businessHours := &AccessWindow{
+		Days:     []string{"mon", "tue", "wed", "thu", "fri"},
+		Times:    []string{"09:00-18:00"},
+		Timezone: "Asia/Tashkent",
+	}
+
+	// The contractor may log in only during the contract and business hours,
+	// and reports are closed for everybody during the nightly rebuild
+	config := Config{
+		Users: []User{
+			{
+				UserName:     "contractor",
+				Password:     "contractor-password",
+				Roles:        []string{"contractor"},
+				AccessWindow: businessHours,
+			},
+			{
+				UserName: "username",
+				Password: "password",
+				Roles:    []string{"admin"},
+			},
+		},
+		RestrictedUrls: []string{"/reports/*", "/billing/*"},
+		AccessRules: []AccessRule{
+			{
+				Condition: RestrictedRule{Urls: []string{"/billing/*"}},
+				Roles:     []string{"contractor"},
+				Window: &AccessWindow{
+					Dates: []string{"2025-01-01/2025-12-31"},
+				},
+			},
+			{
+				Condition: RestrictedRule{Urls: []string{"/reports/*"}},
+				Window: &AccessWindow{
+					Times:    []string{"03:00-23:00"},
+					Timezone: "Asia/Tashkent",
+				},
+			},
+		},
+	}
+
+	router.Use(Middleware(config))
+
+	router.HandleFunc("/reports/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("report " + mux.Vars(r)["id"]))
+	}).Methods("GET")
+
+	router.HandleFunc("/billing/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("invoice " + mux.Vars(r)["id"]))
+	}).Methods("GET")
+
+	return router
+}
+
+func VirtualHostsRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	// www.example.com is public, every admin.example.com request and every plain HTTP request to the api
+	// is authenticated, and calls of internal services are recognized by their header
+	config := Config{
+		Users: []User{
+			{
+				UserName: "username",
+				Password: "password",
+			},
+		},
+		RestrictedRules: []RestrictedRule{
+			{Hosts: []string{"admin.example.com"}},
+			{Hosts: []string{"*.api.example.com"}, Schemes: []string{"http"}},
+			{Headers: map[string]string{"X-Internal-Call": "*"}},
+			{Urls: []string{"/export"}, Query: map[string]string{"format": "csv"}},
+		},
+		TrustedProxies: []string{"10.0.0.0/8"},
+	}
+
+	router.Use(Middleware(config))
+
+	router.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello from " + r.Host))
+	})
+
+	return router
+}
+
+func MountedAppsRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	// /admin and /billing have their own users, the ops subrouter uses the ops realm explicitly
+	// and /account is for the users of the config
+	config := Config{
+		Users: []User{
+			{
+				UserName: "username",
+				Password: "password",
+			},
+		},
+		RestrictedUrls: []string{"/account/*"},
+		Realms: []Realm{
+			{
+				Name:         "Admin",
+				PathPrefixes: []string{"/admin"},
+				Users:        []User{{UserName: "admin", Password: "adminpassword"}},
+			},
+			{
+				Name:         "Billing",
+				PathPrefixes: []string{"/billing"},
+				Users:        []User{{UserName: "accountant", Password: "accountantpassword"}},
+			},
+			{
+				Name:  "Ops",
+				Users: []User{{UserName: "operator", Password: "operatorpassword"}},
+				UnauthorizedHandler: func(w http.ResponseWriter, r *http.Request) {
+					http.Error(w, "ops credentials are required", http.StatusUnauthorized)
+				},
+			},
+		},
+	}
+	router.Use(Middleware(config))
+
+	hello := func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		w.Write([]byte("Hello " + principal.Name))
+	}
+
+	router.PathPrefix("/admin").HandlerFunc(hello)
+	router.PathPrefix("/billing").HandlerFunc(hello)
+	router.PathPrefix("/account").HandlerFunc(hello)
+
+	ops := router.PathPrefix("/ops").Subrouter()
+	ops.Use(RealmMiddleware(config, "Ops"))
+	ops.HandleFunc("/status", hello)
+
+	return router
+}
+
+func ReportOnlyRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	// RequireAuthForAll is going to be turned on, until then the requests that would be blocked are only logged
+	config := Config{
+		Users: []User{
+			{
+				UserName: "username",
+				Password: "password",
+			},
+		},
+		RequireAuthForAll: true,
+		ReportOnly:        true,
+		OnDecision: func(d *Decision) {
+			if !d.Allowed() {
+				log.Printf("basicauth: would block %s %s: %s", d.Method, d.Path, d)
+			}
+		},
+	}
+	router.Use(Middleware(config))
+
+	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		name := "anonymous"
+		if principal, ok := PrincipalFromContext(r.Context()); ok {
+			name = principal.Name
+		}
+		w.Write([]byte("Hello " + name))
+	})
+
+	return router
+}
+
+func MigrationRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	// the old config is enforced while the new one, which also protects reports, runs in the shadow
+	recorder := &ShadowRecorder{
+		OnDivergence: func(d *Divergence) {
+			log.Printf("basicauth: shadow config decides %s %s: %s", d.Shadow.Method, d.Shadow.Path, d.Shadow)
+		},
+	}
+	config := Config{
+		Users: []User{
+			{
+				UserName: "username",
+				Password: "password",
+			},
+		},
+		RestrictedUrls: []string{"/admin/*"},
+		Shadow: &Config{
+			Users: []User{
+				{
+					UserName: "username",
+					Password: "password",
+				},
+			},
+			RestrictedUrls: []string{"/admin/*", "/reports/*"},
+		},
+		ShadowRecorder: recorder,
+	}
+	router.Use(Middleware(config))
+
+	router.Handle("/admin/shadow", recorder)
+
+	router.HandleFunc("/reports/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("report " + mux.Vars(r)["id"]))
+	})
+
+	return router
+}
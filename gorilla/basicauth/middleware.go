@@ -1,97 +1,166 @@
 package basicauth
 
 import (
-	"encoding/base64"
+	"context"
 	"net/http"
-	"strings"
 
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/basic"
+	"github.com/golanguzb70/middleware/internal/clientip"
+	"github.com/golanguzb70/middleware/internal/rules"
+	"github.com/golanguzb70/middleware/internal/session"
+	"github.com/golanguzb70/middleware/internal/tokens"
+	"github.com/golanguzb70/middleware/internal/totp"
 	"github.com/gorilla/mux"
 )
 
 // method for checking authorization
 func Middleware(cfg Config) mux.MiddlewareFunc {
+	authenticator := cfg.authenticator()
+	policy := cfg.policy()
+	unauthorized := cfg.UnauthorizedHandler
+	if unauthorized == nil {
+		unauthorized = func(w http.ResponseWriter, r *http.Request) {
+			status := authenticator.UnauthorizedStatus()
+			http.Error(w, http.StatusText(status), status)
+		}
+	}
+	shadow := newShadowEvaluator(cfg)
+	e := &enforcer{
+		cfg:           cfg,
+		authenticator: authenticator,
+		unauthorized:  unauthorized,
+		forbidden:     cfg.forbiddenHandler(),
+		shadow:        shadow,
+	}
+
+	realms := make([]mux.MiddlewareFunc, len(cfg.Realms))
+	for i := range cfg.Realms {
+		realms[i] = realmMiddleware(cfg, &cfg.Realms[i], shadow)
+	}
+
 	return func(next http.Handler) http.Handler {
+		realmHandlers := make([]http.Handler, len(realms))
+		for i, realm := range realms {
+			realmHandlers[i] = realm(next)
+		}
+
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			var (
-				authRequired = cfg.RequireAuthForAll
-				url          = r.URL.Path
-				method       = r.Method
-				authHeader   = r.Header.Get("Authorization")
-			)
-
-			if contains(cfg.RestrictedMethods, method) {
-				authRequired = true
+			if i := cfg.realmFor(r.URL.Path); i >= 0 {
+				realmHandlers[i].ServeHTTP(w, r)
+				return
 			}
+			e.serve(w, r, next, policy.Explain(r))
+		})
+	}
+}
 
-			if !authRequired && len(cfg.RestrictedUrls) > 0 {
-				for _, e := range cfg.RestrictedUrls {
-					if strings.Contains(e, "*") && strings.Contains(url, strings.TrimSuffix(e, "/*")) {
-						authRequired = true
-						break
-					} else if strings.Contains(e, "{") && e[:strings.LastIndex(e, "/")] == url[:strings.LastIndex(url, "/")] {
-						authRequired = true
-						break
-					} else if e == url {
-						authRequired = true
-					}
-				}
-			}
+// enforcer serves the requests of the config or one of its realms.
+type enforcer struct {
+	cfg           Config
+	realm         *Realm
+	authenticator *basic.Authenticator
+	unauthorized  http.HandlerFunc
+	forbidden     http.HandlerFunc
+	shadow        *shadowEvaluator
+}
 
-			if authRequired {
-				if authHeader == "" {
-					w.Header().Set("WWW-Authenticate", "Basic realm=Authorization Required")
-					cfg.UnauthorizedHandler(w, r)
-					return
-				}
-
-				credentials := strings.SplitN(authHeader, " ", 2)
-				if len(credentials) != 2 {
-					w.Header().Set("WWW-Authenticate", "Basic realm=Authorization Required")
-					cfg.UnauthorizedHandler(w, r)
-					return
-				}
-
-				decodedCredentials, err := base64.StdEncoding.DecodeString(credentials[1])
-				if err != nil {
-					w.Header().Set("WWW-Authenticate", "Basic realm=Authorization Required")
-					cfg.UnauthorizedHandler(w, r)
-					return
-				}
-				credentialsPair := strings.SplitN(string(decodedCredentials), ":", 2)
-				if len(credentialsPair) != 2 {
-					w.Header().Set("WWW-Authenticate", "Basic realm=Authorization Required")
-					cfg.UnauthorizedHandler(w, r)
-					return
-				}
-
-				authorized := false
-				
-				for i := 0; i < len(cfg.Users); i++ {
-					if credentialsPair[0] == cfg.Users[i].UserName && credentialsPair[1] == cfg.Users[i].Password {
-						authorized = true
-						break
-					}
-				}
-
-				if !authorized {
-					w.Header().Set("WWW-Authenticate", "Basic realm=Authorization Required")
-					cfg.UnauthorizedHandler(w, r)
-					return
-				}
+// serve enforces the decision about the request, or only reports it in report-only mode.
+func (e *enforcer) serve(w http.ResponseWriter, r *http.Request, next http.Handler, restriction string) {
+	decision := e.authenticator.Decide(w, r, restriction)
+	if e.cfg.OnDecision != nil {
+		e.cfg.OnDecision(decision)
+	}
+	if e.shadow != nil {
+		e.shadow.compare(r, decision, e.realm)
+	}
+	if decision.Principal != nil {
+		r = r.WithContext(auth.NewContext(r.Context(), decision.Principal))
+	}
 
-			}
+	switch {
+	case e.cfg.ReportOnly:
+		w.Header().Set(DecisionHeader, decision.String())
+	case decision.Status == http.StatusForbidden:
+		e.forbidden(w, r)
+		return
+	case !decision.Allowed():
+		if challenge := e.authenticator.Challenge(decision.Err); challenge != "" {
+			w.Header().Set(e.authenticator.ChallengeHeader(), challenge)
+		}
+		e.unauthorized(w, r)
+		return
+	}
 
-			// Call the next handler in the chain
-			next.ServeHTTP(w, r)
-		})
+	// proxy credentials must not be forwarded to the upstream server
+	if e.cfg.ProxyAuth {
+		r.Header.Del("Proxy-Authorization")
 	}
+
+	// Call the next handler in the chain
+	next.ServeHTTP(w, r)
 }
 
-func contains(arr []string, val string) bool {
-	for _, item := range arr {
-		if item == val {
-			return true
-		}
+// Authenticator returns the basic auth scheme of the config to be combined with other schemes in chain package.
+func (cfg Config) Authenticator() auth.Authenticator {
+	return cfg.authenticator()
+}
+
+// PrincipalFromContext returns the caller the request was authenticated as.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	return auth.FromContext(ctx)
+}
+
+func (cfg Config) forbiddenHandler() http.HandlerFunc {
+	if cfg.ForbiddenHandler != nil {
+		return cfg.ForbiddenHandler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	}
+}
+
+func (cfg Config) authenticator() *basic.Authenticator {
+	authenticator := &basic.Authenticator{
+		Users:    cfg.Users,
+		Proxy:    cfg.ProxyAuth,
+		ClientIP: cfg.resolver(),
+		Rules:    cfg.AccessRules,
+		Now:      cfg.Now,
+	}
+	if cfg.Session != nil {
+		authenticator.Sessions = &session.Manager{Config: *cfg.Session}
+	}
+	if cfg.Tokens != nil {
+		// refresh tokens are kept in the token config, so handlers made of the same config share them
+		authenticator.Tokens = &tokens.Service{Config: cfg.Tokens}
+	}
+	// used codes are kept in the TOTP config, so handlers made of the same config share them.
+	// Without one, which Validate rejects for users with a secret, the handler keeps them by itself.
+	totpConfig := cfg.TOTP
+	if totpConfig == nil {
+		totpConfig = &totp.Config{}
+	}
+	authenticator.TOTP = &totp.Verifier{Config: totpConfig}
+	authenticator.Rehash = cfg.rehasher()
+	return authenticator
+}
+
+// rehasher returns the password hash upgrades of the config, none in report-only mode, which only evaluates requests.
+// The upgrades in progress are kept in the PasswordHashing config, so handlers made of the same config share them.
+func (cfg Config) rehasher() *basic.Rehasher {
+	if cfg.PasswordHashing == nil || cfg.PasswordHashing.Store == nil || cfg.ReportOnly {
+		return nil
+	}
+	return cfg.PasswordHashing
+}
+
+func (cfg Config) policy() rules.Policy {
+	return rules.Policy{
+		RequireAuthForAll: cfg.RequireAuthForAll,
+		RestrictedMethods: cfg.RestrictedMethods,
+		RestrictedUrls:    cfg.RestrictedUrls,
+		RestrictedRules:   cfg.RestrictedRules,
+		TrustedProxies:    clientip.ParseValid(cfg.TrustedProxies),
 	}
-	return false
 }
@@ -2,9 +2,17 @@ package basicauth
 
 import (
 	"encoding/base64"
+	"encoding/json"
+	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/golanguzb70/middleware/internal/totp"
+	"github.com/gorilla/mux"
 	"gotest.tools/assert"
 )
 
@@ -216,3 +224,644 @@ func TestRequireForSpecificUrls(t *testing.T) {
 	router.ServeHTTP(w, req)
 	assert.Equal(t, 200, w.Result().StatusCode)
 }
+
+func TestPrincipal(t *testing.T) {
+	cfg := Config{
+		Users: []User{
+			{UserName: "username", Password: "password"},
+			{UserName: "admin", Password: "secret", Roles: []string{"admin"}},
+		},
+		RequireAuthForAll: true,
+		UnauthorizedHandler: func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		},
+	}
+	router := mux.NewRouter()
+	router.Use(Middleware(cfg))
+	router.HandleFunc("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		w.Write([]byte(principal.Name + " " + strings.Join(principal.Roles, ",")))
+	})
+
+	req := httptest.NewRequest("GET", "/whoami", nil)
+	req.SetBasicAuth("admin", "secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "admin admin", w.Body.String())
+
+	req = httptest.NewRequest("GET", "/whoami", nil)
+	req.SetBasicAuth("admin", "password")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+}
+
+func TestProxyAuth(t *testing.T) {
+	router := ProxyAuthRouter()
+	base64Auth := base64.StdEncoding.EncodeToString([]byte("username:password"))
+
+	req := httptest.NewRequest("GET", "http://example.com/page", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 407, w.Result().StatusCode)
+	assert.Equal(t, `Basic realm="Authorization Required"`, w.Header().Get("Proxy-Authenticate"))
+	assert.Equal(t, "", w.Header().Get("WWW-Authenticate"))
+
+	// credentials for the origin server are not proxy credentials
+	req = httptest.NewRequest("GET", "http://example.com/page", nil)
+	req.Header.Set("Authorization", "Basic "+base64Auth)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 407, w.Result().StatusCode)
+
+	req = httptest.NewRequest("GET", "http://example.com/page", nil)
+	req.Header.Set("Proxy-Authorization", "Basic "+base64Auth)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "Proxy-Authorization: ", w.Body.String())
+}
+
+func TestSession(t *testing.T) {
+	router := SessionRouter()
+
+	// no browser prompt for unauthenticated requests
+	req := httptest.NewRequest("GET", "/admin/whoami", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+	assert.Equal(t, "", w.Header().Get("WWW-Authenticate"))
+
+	req = httptest.NewRequest("POST", "/login", strings.NewReader("username=username&password=wrong"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+
+	req = httptest.NewRequest("POST", "/login", strings.NewReader("username=username&password=password"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Result().StatusCode)
+	cookie := w.Result().Cookies()[0]
+	assert.Assert(t, cookie.HttpOnly)
+
+	req = httptest.NewRequest("GET", "/admin/whoami", nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "username session", w.Body.String())
+
+	// Basic credentials are accepted as well
+	req = httptest.NewRequest("GET", "/admin/whoami", nil)
+	req.SetBasicAuth("username", "password")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "username basic", w.Body.String())
+
+	req = httptest.NewRequest("POST", "/logout", nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Result().StatusCode)
+	assert.Equal(t, "", w.Result().Cookies()[0].Value)
+}
+
+func TestTokens(t *testing.T) {
+	router := TokenRouter()
+
+	form := func(body string) *http.Request {
+		req := httptest.NewRequest("POST", "/oauth/token", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req
+	}
+
+	req := form("grant_type=client_credentials")
+	req.SetBasicAuth("username", "wrong")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+	assert.Equal(t, `Basic realm="Authorization Required"`, w.Header().Get("WWW-Authenticate"))
+
+	req = form("grant_type=client_credentials")
+	req.SetBasicAuth("username", "password")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	var issued struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int64  `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	assert.NilError(t, json.Unmarshal(w.Body.Bytes(), &issued))
+	assert.Equal(t, "Bearer", issued.TokenType)
+	assert.Equal(t, int64(300), issued.ExpiresIn)
+
+	req = httptest.NewRequest("GET", "/api/whoami", nil)
+	req.Header.Set("Authorization", "Bearer "+issued.AccessToken)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "username token", w.Body.String())
+
+	req = httptest.NewRequest("GET", "/api/whoami", nil)
+	req.Header.Set("Authorization", "Bearer "+issued.RefreshToken)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+	assert.Equal(t, `Bearer realm="Authorization Required", error="invalid_token"`, w.Header().Get("WWW-Authenticate"))
+
+	// the refresh token is rotated
+	req = form("grant_type=refresh_token&refresh_token=" + issued.RefreshToken)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	refreshed := issued
+	assert.NilError(t, json.Unmarshal(w.Body.Bytes(), &refreshed))
+
+	// reuse of the old refresh token revokes the new one too
+	req = form("grant_type=refresh_token&refresh_token=" + issued.RefreshToken)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+	assert.Equal(t, `{"error":"invalid_grant","error_description":"refresh token was already used"}`, strings.TrimSpace(w.Body.String()))
+
+	req = form("grant_type=refresh_token&refresh_token=" + refreshed.RefreshToken)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+
+	// revocation
+	req = form("")
+	req.SetBasicAuth("username", "password")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.NilError(t, json.Unmarshal(w.Body.Bytes(), &issued))
+
+	req = httptest.NewRequest("POST", "/oauth/revoke", strings.NewReader("token="+issued.RefreshToken))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	req = form("grant_type=refresh_token&refresh_token=" + issued.RefreshToken)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestTOTP(t *testing.T) {
+	router := TOTPRouter()
+
+	code, err := totp.Generate("JBSWY3DPEHPK3PXP", time.Now(), 30*time.Second, 6)
+	assert.NilError(t, err)
+
+	req := httptest.NewRequest("GET", "/admin/whoami", nil)
+	req.SetBasicAuth("admin", "admin-password")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+
+	// code in the header
+	req.Header.Set("X-OTP", code)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "admin", w.Body.String())
+
+	// a code works only once
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+
+	// code appended to the password
+	next, err := totp.Generate("JBSWY3DPEHPK3PXP", time.Now().Add(30*time.Second), 30*time.Second, 6)
+	assert.NilError(t, err)
+	req = httptest.NewRequest("GET", "/admin/whoami", nil)
+	req.SetBasicAuth("admin", "admin-password"+next)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	// users without a secret are not affected
+	req = httptest.NewRequest("GET", "/admin/whoami", nil)
+	req.SetBasicAuth("reporter", "reporter-password")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+}
+
+func TestTOTPSharedByHandlers(t *testing.T) {
+	cfg := Config{
+		Users:             []User{{UserName: "totp-shared", Password: "password", TOTPSecret: "JBSWY3DPEHPK3PXP"}},
+		TOTP:              &TOTPConfig{},
+		RequireAuthForAll: true,
+	}
+	assert.NilError(t, cfg.Validate())
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	first, second := mux.NewRouter(), mux.NewRouter()
+	first.Use(Middleware(cfg))
+	first.HandleFunc("/", handler)
+	second.Use(Middleware(cfg))
+	second.HandleFunc("/", handler)
+
+	code, err := totp.Generate("JBSWY3DPEHPK3PXP", time.Now(), 30*time.Second, 6)
+	assert.NilError(t, err)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("totp-shared", "password")
+	req.Header.Set("X-OTP", code)
+
+	w := httptest.NewRecorder()
+	first.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	// the code can not be replayed on another handler of the config
+	w = httptest.NewRecorder()
+	second.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+}
+
+func TestPasswordRotation(t *testing.T) {
+	router := PasswordRotationRouter()
+
+	for _, tc := range []struct {
+		user, password string
+		status         int
+	}{
+		{"billing", "old-password", 200},
+		{"billing", "new-password", 200},
+		{"billing", "", 401},
+		{"contractor", "contractor-password", 200},
+		{"former-employee", "password", 401},
+	} {
+		req := httptest.NewRequest("GET", "/invoices", nil)
+		req.SetBasicAuth(tc.user, tc.password)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.status, w.Result().StatusCode, tc.user+":"+tc.password)
+	}
+}
+
+func TestAllowedCIDRs(t *testing.T) {
+	router := NetworkRestrictedRouter()
+
+	for _, tc := range []struct {
+		user, password, remoteAddr, forwardedFor string
+		status                                   int
+	}{
+		{"deployer", "deployer-password", "192.168.100.7:5000", "", 200},
+		{"deployer", "deployer-password", "[2001:db8:100::7]:5000", "", 200},
+		{"deployer", "deployer-password", "203.0.113.9:5000", "", 401},
+		// a forged header of an untrusted peer is ignored
+		{"deployer", "deployer-password", "203.0.113.9:5000", "192.168.100.7", 401},
+		// the load balancer forwards the address of the client
+		{"deployer", "deployer-password", "10.1.1.1:5000", "192.168.100.7", 200},
+		{"deployer", "deployer-password", "10.1.1.1:5000", "192.168.100.7, 203.0.113.9", 401},
+		// users without networks are not affected
+		{"username", "password", "203.0.113.9:5000", "", 200},
+	} {
+		req := httptest.NewRequest("POST", "/deploy", nil)
+		req.SetBasicAuth(tc.user, tc.password)
+		req.RemoteAddr = tc.remoteAddr
+		if tc.forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", tc.forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.status, w.Result().StatusCode, tc.remoteAddr+" "+tc.forwardedFor)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Config{Users: []User{{UserName: "username", AllowedCIDRs: []string{"10.0.0.0/33"}}}}
+	assert.ErrorContains(t, cfg.Validate(), "invalid network")
+
+	cfg = Config{TrustedProxies: []string{"proxy"}}
+	assert.ErrorContains(t, cfg.Validate(), "invalid address")
+
+	cfg = Config{Users: []User{{UserName: "UserName1", TOTPSecret: "not base32!"}}}
+	assert.ErrorContains(t, cfg.Validate(), `user "UserName1": totp: invalid secret`)
+
+	cfg = Config{Realms: []Realm{{Name: "admin", Users: []User{{UserName: "UserName1", TOTPSecret: "JBSWY3DPEHPK3PXP"}}}}}
+	assert.ErrorContains(t, cfg.Validate(), "basicauth: totp is required")
+
+	// "period": 30 in JSON is 30 nanoseconds
+	cfg = Config{Shadow: &Config{TrustedProxies: []string{"proxy"}}}
+	assert.ErrorContains(t, cfg.Validate(), "basicauth: shadow: ")
+
+	cfg = Config{Shadow: &Config{}}
+	assert.ErrorContains(t, cfg.Validate(), "basicauth: shadow recorder is required")
+	cfg.ShadowRecorder = &ShadowRecorder{}
+	assert.NilError(t, cfg.Validate())
+
+	cfg = Config{TOTP: &TOTPConfig{Period: 30}}
+	assert.ErrorContains(t, cfg.Validate(), "basicauth: totp: period 30ns is not a whole number of seconds")
+}
+
+func TestAccessWindows(t *testing.T) {
+	// 10:00 on monday in Tashkent
+	now := time.Date(2025, 3, 3, 5, 0, 0, 0, time.UTC)
+	cfg := Config{
+		Users: []User{
+			{
+				UserName: "contractor",
+				Password: "pass",
+				Roles:    []string{"contractor"},
+				AccessWindow: &AccessWindow{
+					Days:     []string{"mon", "tue", "wed", "thu", "fri"},
+					Timezone: "Asia/Tashkent",
+				},
+			},
+			{UserName: "admin", Password: "pass"},
+		},
+		RequireAuthForAll: true,
+		AccessRules: []AccessRule{
+			{
+				Condition: RestrictedRule{Urls: []string{"/reports/*"}},
+				Roles:     []string{"contractor"},
+				Window:    &AccessWindow{Times: []string{"09:00-18:00"}, Timezone: "Asia/Tashkent"},
+			},
+		},
+		Now: func() time.Time { return now },
+	}
+	assert.NilError(t, cfg.Validate())
+	router := mux.NewRouter()
+	router.Use(Middleware(cfg))
+	router.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	status := func(user, path string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.SetBasicAuth(user, "pass")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Result().StatusCode
+	}
+
+	assert.Equal(t, 200, status("contractor", "/reports/1"))
+
+	// 20:00 on monday in Tashkent
+	now = time.Date(2025, 3, 3, 15, 0, 0, 0, time.UTC)
+	assert.Equal(t, 403, status("contractor", "/reports/1"))
+	assert.Equal(t, 200, status("contractor", "/profile"))
+	assert.Equal(t, 200, status("admin", "/reports/1"))
+
+	// saturday
+	now = time.Date(2025, 3, 8, 5, 0, 0, 0, time.UTC)
+	assert.Equal(t, 401, status("contractor", "/profile"))
+
+	cfg.AccessRules[0].Window = &AccessWindow{Times: []string{"9-18"}}
+	assert.ErrorContains(t, cfg.Validate(), "access rule 0: schedule: invalid time range")
+}
+
+func TestRestrictedRules(t *testing.T) {
+	router := VirtualHostsRouter()
+
+	for _, tc := range []struct {
+		url        string
+		header     string
+		remoteAddr string
+		status     int
+	}{
+		{"http://www.example.com/", "", "", 200},
+		{"http://admin.example.com/", "", "", 401},
+		{"http://admin.example.com:8080/users", "", "", 401},
+		{"http://eu.api.example.com/orders", "", "", 401},
+		{"https://eu.api.example.com/orders", "", "", 200},
+		// TLS terminated by a trusted proxy
+		{"http://eu.api.example.com/orders", "X-Forwarded-Proto: https", "10.0.0.1:1234", 200},
+		{"http://eu.api.example.com/orders", "X-Forwarded-Proto: https", "203.0.113.9:1234", 401},
+		{"http://www.example.com/", "X-Internal-Call: billing", "", 401},
+		{"http://www.example.com/export?format=json", "", "", 200},
+		{"http://www.example.com/export?format=csv", "", "", 401},
+	} {
+		req := httptest.NewRequest("GET", tc.url, nil)
+		if name, value, ok := strings.Cut(tc.header, ": "); ok {
+			req.Header.Set(name, value)
+		}
+		if tc.remoteAddr != "" {
+			req.RemoteAddr = tc.remoteAddr
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.status, w.Result().StatusCode, tc.url+" "+tc.header+" "+tc.remoteAddr)
+	}
+
+	req := httptest.NewRequest("GET", "http://admin.example.com/", nil)
+	req.SetBasicAuth("username", "password")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+}
+
+func TestRealms(t *testing.T) {
+	router := MountedAppsRouter()
+
+	for _, tc := range []struct {
+		url      string
+		user     string
+		password string
+		status   int
+	}{
+		{"/admin/users", "", "", 401},
+		{"/admin/users", "admin", "adminpassword", 200},
+		{"/admin", "admin", "adminpassword", 200},
+		// users of other realms and of the config have no access
+		{"/admin/users", "accountant", "accountantpassword", 401},
+		{"/admin/users", "username", "password", 401},
+		{"/billing/invoices", "accountant", "accountantpassword", 200},
+		{"/billing/invoices", "admin", "adminpassword", 401},
+		{"/account/profile", "username", "password", 200},
+		{"/account/profile", "admin", "adminpassword", 401},
+		{"/ops/status", "operator", "operatorpassword", 200},
+		{"/ops/status", "username", "password", 401},
+	} {
+		req := httptest.NewRequest("GET", tc.url, nil)
+		if tc.user != "" {
+			req.SetBasicAuth(tc.user, tc.password)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.status, w.Result().StatusCode, tc.url+" "+tc.user)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/billing/invoices", nil))
+	assert.Equal(t, `Basic realm="Billing"`, w.Header().Get("WWW-Authenticate"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/account/profile", nil))
+	assert.Equal(t, `Basic realm="Authorization Required"`, w.Header().Get("WWW-Authenticate"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/ops/status", nil))
+	assert.Equal(t, `Basic realm="Ops"`, w.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, "ops credentials are required\n", w.Body.String())
+
+	cfg := Config{Realms: []Realm{{Name: "Admin", PathPrefixes: []string{"admin"}}}}
+	assert.ErrorContains(t, cfg.Validate(), `realm "Admin": path prefix "admin" does not start with /`)
+}
+
+func TestReportOnly(t *testing.T) {
+	router := ReportOnlyRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "Hello anonymous", w.Body.String())
+	assert.Equal(t, `deny status=401 reason="auth: no credentials"`, w.Header().Get(DecisionHeader))
+	assert.Equal(t, "", w.Header().Get("WWW-Authenticate"))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("username", "password")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "Hello username", w.Body.String())
+	assert.Equal(t, "allow", w.Header().Get(DecisionHeader))
+
+	// decisions are reported in enforcing mode too
+	var decisions []*Decision
+	now := time.Date(2025, 3, 3, 20, 0, 0, 0, time.UTC)
+	cfg := Config{
+		Users:          []User{{UserName: "username", Password: "password"}},
+		RestrictedUrls: []string{"/reports/*"},
+		AccessRules:    []AccessRule{{Condition: RestrictedRule{Urls: []string{"/reports/*"}}, Window: &AccessWindow{Times: []string{"09:00-18:00"}}}},
+		OnDecision:     func(d *Decision) { decisions = append(decisions, d) },
+		Now:            func() time.Time { return now },
+	}
+	router = mux.NewRouter()
+	router.Use(Middleware(cfg))
+	router.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	req = httptest.NewRequest("GET", "/reports/1", nil)
+	req.SetBasicAuth("username", "password")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 403, w.Result().StatusCode)
+	assert.Equal(t, "", w.Header().Get(DecisionHeader))
+
+	assert.Equal(t, 2, len(decisions))
+	assert.Equal(t, "", decisions[0].Restriction)
+	assert.Equal(t, 200, decisions[0].Status)
+	assert.Equal(t, "restricted_urls /reports/*", decisions[1].Restriction)
+	assert.Equal(t, "username", decisions[1].User)
+	assert.Equal(t, 0, decisions[1].Rule)
+	assert.Equal(t, 403, decisions[1].Status)
+}
+
+func TestShadow(t *testing.T) {
+	router := MigrationRouter()
+
+	// the old config is enforced
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/reports/1", nil))
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	req := httptest.NewRequest("GET", "/reports/2", nil)
+	req.SetBasicAuth("username", "password")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest("GET", "/admin/shadow", nil)
+	req.SetBasicAuth("username", "password")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var summary ShadowSummary
+	assert.NilError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, 3, summary.Requests)
+	assert.Equal(t, 1, summary.Divergences)
+	assert.DeepEqual(t, []ShadowKind{
+		{EnforcedStatus: 200, EnforcedRule: -1, ShadowStatus: 401, ShadowRestriction: "restricted_urls /reports/*", ShadowRule: -1, Count: 1},
+	}, summary.Kinds)
+	assert.Equal(t, "/reports/1", summary.Recent[0].Shadow.Path)
+	assert.Equal(t, "GET", summary.Recent[0].Enforced.Method)
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	cfg := Config{
+		Users: []User{
+			{UserName: "admin", Password: "vivid-Otter-glides-93"},
+			{UserName: "legacy", Password: "$2y$05$oopEP8g.9wUslDQcAtnuqOdFGeHRnJHMIcWH9xKSizs5yhumWMMnq"},
+		},
+		PasswordPolicy: &PasswordPolicy{},
+	}
+	assert.NilError(t, cfg.Validate())
+
+	cfg.Users[0].Password = "password"
+	assert.ErrorContains(t, cfg.Validate(), `user "admin": passwd: password has 8 characters, at least 12 are required`)
+	assert.ErrorContains(t, cfg.Validate(), "passwd: password is a common password")
+
+	cfg.Users[0].Password = "vivid-Otter-glides-93"
+	cfg.Users[0].Credentials = []Credential{{Password: "admin-Otter-glides-93"}}
+	assert.ErrorContains(t, cfg.Validate(), "passwd: password contains the user name")
+
+	cfg.Users[0].Credentials = nil
+	cfg.Realms = []Realm{{Name: "Ops", PathPrefixes: []string{"/ops"}, Users: []User{{UserName: "ops", Password: "ops"}}}}
+	assert.ErrorContains(t, cfg.Validate(), `realm "Ops": user "ops": passwd:`)
+
+	cfg.PasswordPolicy = nil
+	assert.NilError(t, cfg.Validate())
+}
+
+func TestRehash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.htpasswd")
+	// bcrypt hash of "password" with cost 5
+	old := "$2y$05$oopEP8g.9wUslDQcAtnuqOdFGeHRnJHMIcWH9xKSizs5yhumWMMnq"
+	assert.NilError(t, os.WriteFile(path, []byte("admin:"+old+"\n"), 0o600))
+	users, err := LoadUsers(path)
+	assert.NilError(t, err)
+
+	cfg := Config{
+		Users:             users,
+		RequireAuthForAll: true,
+		PasswordHashing: &PasswordHashingConfig{
+			Hasher:  PasswordHashing{Algorithm: "argon2id", Params: PasswordParams{Argon2Memory: 1024, Argon2Time: 1, Argon2Threads: 1}},
+			Store:   &UsersFile{Path: path},
+			OnError: func(err error) { t.Error(err) },
+		},
+	}
+	assert.NilError(t, cfg.Validate())
+
+	// decisions that are not enforced keep the hash
+	req := httptest.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("admin", "password")
+	assert.Assert(t, cfg.Decide(req).Allowed())
+	cfg.PasswordHashing.Wait()
+	kept, err := LoadUsers(path)
+	assert.NilError(t, err)
+	assert.Equal(t, old, kept[0].Password)
+	// the hash is upgraded once for both handlers of the config
+	routers := []*mux.Router{mux.NewRouter(), mux.NewRouter()}
+	for _, router := range routers {
+		router.Use(Middleware(cfg))
+		router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+
+	for _, router := range routers {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.SetBasicAuth("admin", "password")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		cfg.PasswordHashing.Wait()
+	}
+
+	upgraded, err := LoadUsers(path)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasPrefix(upgraded[0].Password, "$argon2id$v=19$m=1024,t=1,p=1$"), upgraded[0].Password)
+
+	cfg.PasswordHashing.Algorithm = "md5"
+	assert.ErrorContains(t, cfg.Validate(), `basicauth: password hashing: passwd: unsupported hash: algorithm "md5"`)
+	cfg.PasswordHashing = &PasswordHashingConfig{}
+	assert.ErrorContains(t, cfg.Validate(), "basicauth: password hashing: credential store is not set")
+}
@@ -0,0 +1,102 @@
+package basicauth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golanguzb70/middleware/internal/basic"
+	"github.com/golanguzb70/middleware/internal/rules"
+	"github.com/gorilla/mux"
+)
+
+// Realm is an independent list of users for an app mounted on the router, e.g. under /admin.
+type Realm struct {
+	// Name is sent in the challenge of 401 responses: Basic realm="<Name>".
+	Name string `json:"name"`
+	// PathPrefixes are the paths the realm applies to. /admin matches /admin and /admin/users but not /administrator.
+	// If prefixes of several realms match a request, the longest one wins.
+	PathPrefixes []string `json:"path_prefixes"`
+	// Users is list of users that have access to the realm. Users of the config and other realms have no access.
+	Users []User `json:"users"`
+	// UnauthorizedHandler is called when a request has no valid credentials of the realm users.
+	// The challenge header is already set when it is called. Default responds with 401 status.
+	UnauthorizedHandler http.HandlerFunc `json:"-"`
+}
+
+// RealmMiddleware authenticates every request against the users of the realm with the name.
+// Use it for subrouters instead of PathPrefixes: router.PathPrefix("/admin").Subrouter().Use(RealmMiddleware(cfg, "admin")).
+// It panics if there is no such realm.
+func RealmMiddleware(cfg Config, name string) mux.MiddlewareFunc {
+	for i := range cfg.Realms {
+		if cfg.Realms[i].Name == name {
+			return realmMiddleware(cfg, &cfg.Realms[i], newShadowEvaluator(cfg))
+		}
+	}
+	panic(fmt.Sprintf("basicauth: unknown realm %q", name))
+}
+
+func realmMiddleware(cfg Config, realm *Realm, shadow *shadowEvaluator) mux.MiddlewareFunc {
+	authenticator := cfg.realmAuthenticator(realm)
+	unauthorized := realm.UnauthorizedHandler
+	if unauthorized == nil {
+		unauthorized = func(w http.ResponseWriter, r *http.Request) {
+			status := authenticator.UnauthorizedStatus()
+			http.Error(w, http.StatusText(status), status)
+		}
+	}
+	e := &enforcer{
+		cfg:           cfg,
+		realm:         realm,
+		authenticator: authenticator,
+		unauthorized:  unauthorized,
+		forbidden:     cfg.forbiddenHandler(),
+		shadow:        shadow,
+	}
+	restriction := "realm " + realm.Name
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			e.serve(w, r, next, restriction)
+		})
+	}
+}
+
+func (cfg Config) realmAuthenticator(realm *Realm) *basic.Authenticator {
+	authenticator := cfg.authenticator()
+	authenticator.Users = realm.Users
+	authenticator.Realm = realm.Name
+	// sessions and tokens are not bound to a realm, so they are accepted only outside the realms
+	authenticator.Sessions = nil
+	authenticator.Tokens = nil
+	return authenticator
+}
+
+// realmFor returns the index of the realm with the longest prefix of the path, or -1.
+func (cfg Config) realmFor(path string) int {
+	found, longest := -1, -1
+	for i := range cfg.Realms {
+		for _, prefix := range cfg.Realms[i].PathPrefixes {
+			if len(prefix) > longest && rules.MatchPrefix(prefix, path) {
+				found, longest = i, len(prefix)
+			}
+		}
+	}
+	return found
+}
+
+func (cfg Config) validateRealms() error {
+	for _, realm := range cfg.Realms {
+		for _, prefix := range realm.PathPrefixes {
+			if !strings.HasPrefix(prefix, "/") {
+				return fmt.Errorf("basicauth: realm %q: path prefix %q does not start with /", realm.Name, prefix)
+			}
+		}
+		for _, user := range realm.Users {
+			if err := user.Validate(cfg.PasswordPolicy); err != nil {
+				return fmt.Errorf("basicauth: realm %q: user %q: %w", realm.Name, user.UserName, err)
+			}
+		}
+	}
+	return nil
+}
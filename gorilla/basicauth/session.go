@@ -0,0 +1,37 @@
+package basicauth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/golanguzb70/middleware/internal/basic"
+)
+
+// LoginHandler checks the credentials of the request against Users and starts a session.
+// Credentials are read from the Authorization header, a JSON body with user_name and password fields,
+// or username and password form fields. It responds 204 on success and 401 otherwise.
+func LoginHandler(cfg Config) http.HandlerFunc {
+	authenticator := cfg.authenticator()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, err := authenticator.Login(w, r)
+		switch {
+		case errors.Is(err, basic.ErrNoSessions):
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		case err != nil:
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
+
+// LogoutHandler ends the session of the request. It responds 204.
+func LogoutHandler(cfg Config) http.HandlerFunc {
+	authenticator := cfg.authenticator()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		authenticator.Logout(w)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,43 @@
+package basicauth
+
+import (
+	"net/http"
+
+	"github.com/golanguzb70/middleware/internal/shadow"
+)
+
+// ShadowRecorder records the requests Shadow decides differently from the enforced config.
+// It serves the summary as JSON: router.Handle("/admin/shadow", cfg.ShadowRecorder).
+type ShadowRecorder = shadow.Recorder
+
+// Divergence is a request with the decisions of the enforced config and Shadow.
+type Divergence = shadow.Divergence
+
+// ShadowKind groups divergences with the same statuses, restrictions and access rules.
+type ShadowKind = shadow.Kind
+
+// ShadowSummary counts the compared requests and the divergences by kind, and holds the latest divergences.
+type ShadowSummary = shadow.Summary
+
+// shadowEvaluator decides about requests with the Shadow of a config and records the divergences.
+type shadowEvaluator struct {
+	*evaluator
+	recorder *ShadowRecorder
+}
+
+func newShadowEvaluator(cfg Config) *shadowEvaluator {
+	if cfg.Shadow == nil {
+		return nil
+	}
+	s := &shadowEvaluator{evaluator: newEvaluator(*cfg.Shadow), recorder: cfg.ShadowRecorder}
+	// Validate rejects Shadow without a recorder, the divergences are dropped then
+	if s.recorder == nil {
+		s.recorder = &ShadowRecorder{}
+	}
+	return s
+}
+
+// compare records the request if the shadow config decides differently.
+func (s *shadowEvaluator) compare(r *http.Request, enforced *Decision, enforcedRealm *Realm) {
+	s.recorder.Record(enforced, s.decide(r, enforcedRealm))
+}
@@ -0,0 +1,65 @@
+package basicauth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/basic"
+	"github.com/golanguzb70/middleware/internal/tokens"
+)
+
+// TokenHandler is an OAuth 2.0 style token endpoint. A form with grant_type client_credentials and Basic credentials
+// of Users, or grant_type refresh_token and a refresh_token field, is exchanged for a new access token and refresh token.
+// A refresh token works only once; when a used one is sent again, every token rotated from the same login is revoked.
+func TokenHandler(cfg Config) http.HandlerFunc {
+	authenticator := cfg.authenticator()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		issued, err := authenticator.ExchangeToken(r)
+
+		var tokenErr *tokens.Error
+		switch {
+		case errors.As(err, &tokenErr):
+			writeJSON(w, http.StatusBadRequest, tokenErr)
+		case errors.Is(err, auth.ErrNoCredentials), errors.Is(err, auth.ErrInvalidCredentials):
+			w.Header().Set("WWW-Authenticate", basic.Challenge)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			w.Header().Set("Cache-Control", "no-store")
+			writeJSON(w, http.StatusOK, issued)
+		}
+	}
+}
+
+// RevokeTokenHandler revokes the refresh token in the token form field and the tokens rotated from the same login.
+// It responds 200 for unknown tokens too.
+func RevokeTokenHandler(cfg Config) http.HandlerFunc {
+	authenticator := cfg.authenticator()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := authenticator.RevokeToken(r); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// RevokeUser revokes every refresh token of the user, e.g. after the password was changed.
+// Access tokens already issued stay valid until they expire.
+func (cfg Config) RevokeUser(username string) error {
+	if cfg.Tokens == nil {
+		return basic.ErrNoTokens
+	}
+	return cfg.authenticator().Tokens.RevokeUser(username)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
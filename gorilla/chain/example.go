@@ -0,0 +1,79 @@
+package chain
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/golanguzb70/middleware/gorilla/basicauth"
+	"github.com/golanguzb70/middleware/gorilla/jwtauth"
+	"github.com/golanguzb70/middleware/gorilla/mtlsauth"
+	"github.com/gorilla/mux"
+)
+
+func BasicOrTokenRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	basic := basicauth.Config{
+		Users: []basicauth.User{
+			{
+				UserName: "username",
+				Password: "password",
+			},
+		},
+	}
+	token := jwtauth.Config{
+		Secrets: map[string]string{
+			"": "secret",
+		},
+	}
+
+	// Humans use basic auth and machines use tokens on the same routes
+	config := Config{
+		Authenticator:     AnyOf(basic.Authenticator(), token.Authenticator()),
+		RequireAuthForAll: true,
+		UnauthorizedHandler: func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		},
+	}
+
+	router.Use(Middleware(config))
+
+	router.HandleFunc("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		w.Write([]byte(principal.Name + " " + principal.Scheme))
+	}).Methods("GET")
+
+	return router
+}
+
+func CertificateAndBasicRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	certificate := mtlsauth.Config{
+		AllowedIssuers: []string{"Internal CA"},
+	}
+	basic := basicauth.Config{
+		Users: []basicauth.User{
+			{
+				UserName: "admin",
+				Password: "password",
+				Roles:    []string{"admin"},
+			},
+		},
+	}
+
+	// Admin routes require both a client certificate and a password
+	config := Config{
+		Authenticator:  AllOf(certificate.Authenticator(), basic.Authenticator()),
+		RestrictedUrls: []string{"/admin/*"},
+	}
+
+	router.Use(Middleware(config))
+
+	router.HandleFunc("/admin/whoami", func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		w.Write([]byte(principal.Name + " " + principal.Scheme + " " + strings.Join(principal.Roles, ",")))
+	}).Methods("GET")
+
+	return router
+}
@@ -0,0 +1,47 @@
+package chain
+
+import (
+	"net/http"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+)
+
+// This is configuration struct of chained authentication.
+// It accepts several schemes, e.g. basic auth for humans and tokens for machines, on the same routes.
+type Config struct {
+	// Authenticator is the combination of schemes built with AnyOf and AllOf.
+	// Schemes are taken from Authenticator method of basicauth, jwtauth, introspectauth, mtlsauth and headerauth configs.
+	Authenticator Authenticator `json:"-"`
+	// Restricted Method means that the middleware only applies for method are given.
+	RestrictedMethods []string `json:"restricted_methods"`
+	// Restricted urls are the urls that are authoriztion is required.
+	// The patterns are the same as in basicauth: /v1/user, /v1/user/{key}, /v1/user/*
+	RestrictedUrls []string `json:"restricted_urls"`
+	// If this field is set to true, all the requests are authenticated
+	RequireAuthForAll bool `json:"require_auth_for_all"`
+	// UnauthorizedHandler is an HTTP handler function that is called when a request is not authorized.
+	// If it is not set, 401 status is written.
+	UnauthorizedHandler http.HandlerFunc
+	// ForbiddenHandler is called when the caller is authenticated but not allowed, e.g. a token misses a scope.
+	// If it is not set, 403 status is written.
+	ForbiddenHandler http.HandlerFunc
+}
+
+// Authenticator authenticates requests with one scheme or a combination of schemes.
+type Authenticator = auth.Authenticator
+
+// Principal is the authenticated caller of a request.
+// It is the same type for every auth middleware of this module.
+type Principal = auth.Principal
+
+// AnyOf tries the schemes in order and accepts the request with the first one that succeeds.
+// If all of them fail, WWW-Authenticate lists the challenge of every scheme.
+func AnyOf(authenticators ...Authenticator) Authenticator {
+	return auth.AnyOf(authenticators...)
+}
+
+// AllOf accepts the request only if every scheme succeeds, e.g. mutual TLS plus basic auth.
+// The principal has the name of the first scheme, roles and attributes of all schemes.
+func AllOf(authenticators ...Authenticator) Authenticator {
+	return auth.AllOf(authenticators...)
+}
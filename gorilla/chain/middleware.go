@@ -0,0 +1,63 @@
+package chain
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/rules"
+	"github.com/gorilla/mux"
+)
+
+// method for checking authorization
+func Middleware(cfg Config) mux.MiddlewareFunc {
+	var (
+		policy = rules.Policy{
+			RequireAuthForAll: cfg.RequireAuthForAll,
+			RestrictedMethods: cfg.RestrictedMethods,
+			RestrictedUrls:    cfg.RestrictedUrls,
+		}
+		unauthorized = cfg.UnauthorizedHandler
+		forbidden    = cfg.ForbiddenHandler
+	)
+	if unauthorized == nil {
+		unauthorized = func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		}
+	}
+	if forbidden == nil {
+		forbidden = func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !policy.Required(r.Method, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			principal, err := cfg.Authenticator.Authenticate(r)
+			if err != nil {
+				if challenge := cfg.Authenticator.Challenge(err); challenge != "" {
+					w.Header().Set("WWW-Authenticate", challenge)
+				}
+				if auth.Status(err) == http.StatusForbidden {
+					forbidden(w, r)
+				} else {
+					unauthorized(w, r)
+				}
+				return
+			}
+
+			// Call the next handler in the chain
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+		})
+	}
+}
+
+// PrincipalFromContext returns the caller the request was authenticated as.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	return auth.FromContext(ctx)
+}
@@ -0,0 +1,75 @@
+package chain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golanguzb70/middleware/internal/testcert"
+	"gotest.tools/assert"
+)
+
+func token(payload, secret string) string {
+	signed := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256"}`)) + "." + base64.RawURLEncoding.EncodeToString([]byte(payload))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestAnyOf(t *testing.T) {
+	router := BasicOrTokenRouter()
+
+	req := httptest.NewRequest("GET", "/whoami", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+	assert.Equal(t, `Basic realm="Authorization Required", Bearer realm="Authorization Required"`, w.Header().Get("WWW-Authenticate"))
+
+	req = httptest.NewRequest("GET", "/whoami", nil)
+	req.SetBasicAuth("username", "password")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "username basic", w.Body.String())
+
+	req = httptest.NewRequest("GET", "/whoami", nil)
+	req.Header.Set("Authorization", "Bearer "+token(`{"sub":"robot"}`, "secret"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "robot bearer", w.Body.String())
+
+	req = httptest.NewRequest("GET", "/whoami", nil)
+	req.Header.Set("Authorization", "Bearer "+token(`{"sub":"robot"}`, "wrong"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+}
+
+func TestAllOf(t *testing.T) {
+	router := CertificateAndBasicRouter()
+	cert := testcert.NewCA("Internal CA").Issue(testcert.Leaf{CommonName: "laptop-42"})
+
+	req := httptest.NewRequest("GET", "/admin/whoami", nil)
+	req.SetBasicAuth("admin", "password")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+
+	req = httptest.NewRequest("GET", "/admin/whoami", nil)
+	req.TLS = testcert.ConnectionState(cert)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+	assert.Equal(t, `Basic realm="Authorization Required"`, w.Header().Get("WWW-Authenticate"))
+
+	req = httptest.NewRequest("GET", "/admin/whoami", nil)
+	req.TLS = testcert.ConnectionState(cert)
+	req.SetBasicAuth("admin", "password")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "laptop-42 mtls+basic admin", w.Body.String())
+}
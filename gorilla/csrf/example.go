@@ -0,0 +1,91 @@
+package csrf
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/golanguzb70/middleware/gorilla/basicauth"
+	"github.com/gorilla/mux"
+)
+
+var profileForm = template.Must(template.New("profile").Parse(`<form method="POST" action="/profile">
+	{{ .csrfField }}
+	<input name="name">
+	<button>Save</button>
+</form>`))
+
+func FormRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	// The token is kept in a signed cookie and must be sent back in a form field or X-CSRF-Token header
+	config := Config{
+		Secret:     "csrf-secret",
+		ExemptUrls: []string{"/webhooks/*"},
+		ForbiddenHandler: func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Forbidden: "+FailureReason(r).Error(), http.StatusForbidden)
+		},
+	}
+
+	router.Use(Middleware(config))
+
+	router.HandleFunc("/profile", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		profileForm.Execute(w, map[string]interface{}{
+			"csrfField": TemplateField(r),
+		})
+	}).Methods("GET")
+
+	router.HandleFunc("/profile", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("saved " + r.PostFormValue("name")))
+	}).Methods("POST")
+
+	router.HandleFunc("/webhooks/payments", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods("POST")
+
+	return router
+}
+
+func SessionRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	auth := basicauth.Config{
+		Users: []basicauth.User{
+			{
+				UserName: "username",
+				Password: "password",
+			},
+		},
+		RestrictedUrls: []string{"/account/*"},
+		Session: &basicauth.SessionConfig{
+			Secrets:           []string{"session-secret"},
+			IdleTimeout:       30 * time.Minute,
+			SuppressChallenge: true,
+		},
+	}
+
+	// Synchronizer tokens are bound to the logged in user, so the csrf middleware runs after the auth middleware
+	config := Config{
+		Mode:       Synchronizer,
+		Secret:     "csrf-secret",
+		ExemptUrls: []string{"/login"},
+	}
+
+	router.Use(basicauth.Middleware(auth), Middleware(config))
+
+	router.HandleFunc("/login", basicauth.LoginHandler(auth)).Methods("POST")
+
+	router.HandleFunc("/account/token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"token": Token(r),
+		})
+	}).Methods("GET")
+
+	router.HandleFunc("/account/email", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("email changed"))
+	}).Methods("POST")
+
+	return router
+}
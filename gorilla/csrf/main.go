@@ -0,0 +1,64 @@
+package csrf
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/csrf"
+)
+
+// Token patterns.
+const (
+	DoubleSubmit = csrf.DoubleSubmit
+	Synchronizer = csrf.Synchronizer
+)
+
+// This is configuration struct of CSRF protection for cookie authenticated routes.
+// GET, HEAD, OPTIONS and TRACE requests are never checked, other requests must send the token
+// in X-CSRF-Token header or csrf_token form field and come from the same origin.
+type Config struct {
+	// Mode is double_submit (default) or synchronizer.
+	// Double submit keeps the token in a cookie. Synchronizer tokens are bound to the authenticated user,
+	// so the middleware must run after the auth middleware.
+	Mode string `json:"mode"`
+	// Secret signs double submit cookies and synchronizer tokens. It is required for synchronizer tokens.
+	Secret string `json:"secret"`
+	// CookieName of the double submit cookie. Default is csrf_token.
+	CookieName string `json:"cookie_name"`
+	// HeaderName the token is read from. Default is X-CSRF-Token.
+	HeaderName string `json:"header_name"`
+	// FieldName of the form field the token is read from. Default is csrf_token.
+	FieldName string `json:"field_name"`
+	// TrustedOrigins may send requests besides the origin of the request host, e.g. https://app.example.com.
+	TrustedOrigins []string `json:"trusted_origins"`
+	// If this field is set to true, unsafe requests without Origin and Referer headers are rejected.
+	RequireOrigin bool `json:"require_origin"`
+	// ExemptUrls are not checked, e.g. webhooks. The patterns are the same as in basicauth: /v1/user, /v1/user/{key}, /v1/user/*
+	ExemptUrls []string `json:"exempt_urls"`
+	// ExemptAuthorizationSchemes are Authorization header schemes that exempt a request. Default is Bearer.
+	// Basic is not exempt by default, because browsers resend cached Basic credentials.
+	ExemptAuthorizationSchemes []string `json:"exempt_authorization_schemes"`
+	// MaxAge is the lifetime of tokens. Default is 12 hours.
+	MaxAge time.Duration `json:"max_age"`
+	// If this field is set to true, the double submit cookie is sent over plain HTTP too. Use it only in development.
+	InsecureCookie bool `json:"insecure_cookie"`
+	// ForbiddenHandler is an HTTP handler function that is called when a request fails the check.
+	// FailureReason returns the reason in it. If it is not set, 403 status is written.
+	ForbiddenHandler http.HandlerFunc
+}
+
+func (cfg Config) protector() *csrf.Protector {
+	return &csrf.Protector{
+		Mode:                       cfg.Mode,
+		Secret:                     cfg.Secret,
+		CookieName:                 cfg.CookieName,
+		HeaderName:                 cfg.HeaderName,
+		FieldName:                  cfg.FieldName,
+		TrustedOrigins:             cfg.TrustedOrigins,
+		RequireOrigin:              cfg.RequireOrigin,
+		ExemptUrls:                 cfg.ExemptUrls,
+		ExemptAuthorizationSchemes: cfg.ExemptAuthorizationSchemes,
+		MaxAge:                     cfg.MaxAge,
+		InsecureCookie:             cfg.InsecureCookie,
+	}
+}
@@ -0,0 +1,56 @@
+package csrf
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+
+	"github.com/golanguzb70/middleware/internal/csrf"
+	"github.com/gorilla/mux"
+)
+
+type failureKey struct{}
+
+// method for checking csrf tokens
+func Middleware(cfg Config) mux.MiddlewareFunc {
+	var (
+		protector = cfg.protector()
+		forbidden = cfg.ForbiddenHandler
+	)
+	if forbidden == nil {
+		forbidden = func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := protector.Protect(w, r)
+			r = r.WithContext(csrf.NewContext(r.Context(), token, protector.TemplateField(token)))
+
+			if err != nil {
+				forbidden(w, r.WithContext(context.WithValue(r.Context(), failureKey{}, err)))
+				return
+			}
+
+			// Call the next handler in the chain
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Token returns the token to send with unsafe requests, e.g. for a meta tag read by JavaScript.
+func Token(r *http.Request) string {
+	return csrf.FromContext(r.Context())
+}
+
+// TemplateField returns a hidden form input with the token to put in HTML forms.
+func TemplateField(r *http.Request) template.HTML {
+	return csrf.FieldFromContext(r.Context())
+}
+
+// FailureReason returns why the request was rejected. It is meant to be called in ForbiddenHandler.
+func FailureReason(r *http.Request) error {
+	err, _ := r.Context().Value(failureKey{}).(error)
+	return err
+}
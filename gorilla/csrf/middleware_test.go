@@ -0,0 +1,96 @@
+package csrf
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestDoubleSubmit(t *testing.T) {
+	router := FormRouter()
+
+	req := httptest.NewRequest("GET", "/profile", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Assert(t, strings.Contains(w.Body.String(), `name="csrf_token"`))
+
+	cookie := w.Result().Cookies()[0]
+	assert.Equal(t, "csrf_token", cookie.Name)
+
+	// without a token
+	req = httptest.NewRequest("POST", "/profile", strings.NewReader("name=user1"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 403, w.Result().StatusCode)
+
+	// token in the form field
+	form := url.Values{"name": {"user1"}, "csrf_token": {cookie.Value}}
+	req = httptest.NewRequest("POST", "/profile", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "saved user1", w.Body.String())
+
+	// token in the header from another site
+	req = httptest.NewRequest("POST", "/profile", nil)
+	req.Header.Set("X-CSRF-Token", cookie.Value)
+	req.Header.Set("Origin", "https://evil.example.org")
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 403, w.Result().StatusCode)
+	assert.Equal(t, "Forbidden: csrf: cross-origin request\n", w.Body.String())
+
+	// exempt url
+	req = httptest.NewRequest("POST", "/webhooks/payments", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Result().StatusCode)
+}
+
+func TestSynchronizer(t *testing.T) {
+	router := SessionRouter()
+
+	req := httptest.NewRequest("POST", "/login", nil)
+	req.SetBasicAuth("username", "password")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Result().StatusCode)
+	session := w.Result().Cookies()[0]
+
+	req = httptest.NewRequest("GET", "/account/token", nil)
+	req.AddCookie(session)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var body struct {
+		Token string `json:"token"`
+	}
+	assert.NilError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Assert(t, body.Token != "")
+
+	req = httptest.NewRequest("POST", "/account/email", nil)
+	req.AddCookie(session)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 403, w.Result().StatusCode)
+
+	req = httptest.NewRequest("POST", "/account/email", nil)
+	req.Header.Set("X-CSRF-Token", body.Token)
+	req.AddCookie(session)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, "email changed", w.Body.String())
+}
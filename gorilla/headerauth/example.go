@@ -0,0 +1,53 @@
+package headerauth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+func RestrictAllRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	// Only the SSO gateway in 10.0.0.0/8 may tell who the user is
+	config := Config{
+		TrustedProxies:    []string{"10.0.0.0/8"},
+		RequireAuthForAll: true,
+		UnauthorizedHandler: func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		},
+	}
+
+	router.Use(Middleware(config))
+
+	router.HandleFunc("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		w.Write([]byte(principal.Name + " " + strings.Join(principal.Roles, ",")))
+	}).Methods("GET")
+
+	return router
+}
+
+func SignedHeadersRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	// The gateway signs identity headers, so they can pass through other proxies
+	config := Config{
+		SignatureSecrets: []string{"gateway-secret"},
+		RestrictedUrls:   []string{"/admin/*"},
+	}
+
+	router.Use(Middleware(config))
+
+	router.HandleFunc("/admin/whoami", func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		w.Write([]byte(principal.Name))
+	}).Methods("GET")
+
+	router.HandleFunc("/openurl", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("user: " + r.Header.Get("X-Forwarded-User")))
+	}).Methods("GET")
+
+	return router
+}
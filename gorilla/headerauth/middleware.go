@@ -0,0 +1,52 @@
+package headerauth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/rules"
+	"github.com/gorilla/mux"
+)
+
+// method for checking authorization
+func Middleware(cfg Config) mux.MiddlewareFunc {
+	var (
+		authenticator = cfg.authenticator()
+		policy        = rules.Policy{
+			RequireAuthForAll: cfg.RequireAuthForAll,
+			RestrictedMethods: cfg.RestrictedMethods,
+			RestrictedUrls:    cfg.RestrictedUrls,
+		}
+		unauthorized = cfg.UnauthorizedHandler
+	)
+	if unauthorized == nil {
+		unauthorized = func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// identity headers are always checked, so untrusted ones are removed even on open urls
+			principal, err := authenticator.Authenticate(r)
+			if err == nil {
+				next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+				return
+			}
+
+			if policy.Required(r.Method, r.URL.Path) {
+				unauthorized(w, r)
+				return
+			}
+
+			// Call the next handler in the chain
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// PrincipalFromContext returns the caller the request was authenticated as.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	return auth.FromContext(ctx)
+}
@@ -0,0 +1,40 @@
+package hmacauth
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func RestrictByUrlRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	// Partners call the webhook with signed requests
+	config := Config{
+		Keys: map[string]string{
+			"partner1":      "partner1-secret",
+			"partner2-2024": "partner2-old-secret",
+			"partner2-2025": "partner2-new-secret",
+		},
+		SignedHeaders:  []string{"host", "content-type"},
+		RestrictedUrls: []string{"/partner/*"},
+		UnauthorizedHandler: func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		},
+	}
+
+	router.Use(Middleware(config))
+
+	router.HandleFunc("/partner/orders", func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte(principal.Name + " " + string(body)))
+	}).Methods("POST")
+
+	router.HandleFunc("/openurl", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("open"))
+	}).Methods("GET")
+
+	return router
+}
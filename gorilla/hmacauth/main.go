@@ -0,0 +1,70 @@
+package hmacauth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/hmacsig"
+)
+
+// This is configuration struct of HMAC request signing auth.
+// Partners sign method, path, query, selected headers, body digest, timestamp and nonce of a request
+// with a shared secret, see the README for the format.
+type Config struct {
+	// Keys maps key ids to shared secrets. Several keys of a partner may be active while a secret is rotated.
+	Keys map[string]string `json:"keys"`
+	// SignedHeaders must be signed by every request, e.g. host and content-type.
+	SignedHeaders []string `json:"signed_headers"`
+	// MaxClockSkew is the maximum difference between the timestamp of a request and the server time.
+	// Default is five minutes.
+	MaxClockSkew time.Duration `json:"max_clock_skew"`
+	// MaxBodySize limits the body read to check its digest. Default is 10 MB.
+	MaxBodySize int64 `json:"max_body_size"`
+	// NonceStore remembers used nonces. Default is an in-memory store of the handler, use a shared one if the service has several instances.
+	// It is required when more than one handler is built from the config, e.g. Middleware and Authenticator,
+	// otherwise a request can be replayed once against each of them. Use &MemoryNonceStore{} to share one in-memory store.
+	NonceStore NonceStore `json:"-"`
+	// Restricted Method means that the middleware only applies for method are given.
+	RestrictedMethods []string `json:"restricted_methods"`
+	// Restricted urls are the urls that are authoriztion is required.
+	// The patterns are the same as in basicauth: /v1/user, /v1/user/{key}, /v1/user/*
+	RestrictedUrls []string `json:"restricted_urls"`
+	// If this field is set to true, all the requests are authenticated
+	RequireAuthForAll bool `json:"require_auth_for_all"`
+	// UnauthorizedHandler is an HTTP handler function that is called when a request is not authorized.
+	// If it is not set, 401 status is written.
+	UnauthorizedHandler http.HandlerFunc
+}
+
+// NonceStore remembers used nonces to block replayed requests.
+type NonceStore = hmacsig.NonceStore
+
+// MemoryNonceStore is the in-memory NonceStore of a single process.
+type MemoryNonceStore = hmacsig.MemoryNonceStore
+
+// Principal is the authenticated caller of a request. Its name is the key id.
+// It is the same type for every auth middleware of this module.
+type Principal = auth.Principal
+
+// Sign adds the signature headers to a request. Host is always signed, signedHeaders are signed as well.
+func Sign(r *http.Request, keyID, secret string, signedHeaders []string) error {
+	return hmacsig.Sign(r, keyID, secret, signedHeaders, time.Now())
+}
+
+// Authenticator returns the request signing scheme of the config to be combined with other schemes in chain package.
+// The authenticator remembers nonces, so create it once and reuse it.
+// Set NonceStore if Middleware is built from the same config as well.
+func (cfg Config) Authenticator() auth.Authenticator {
+	return cfg.verifier()
+}
+
+func (cfg Config) verifier() *hmacsig.Verifier {
+	return &hmacsig.Verifier{
+		Keys:            cfg.Keys,
+		RequiredHeaders: cfg.SignedHeaders,
+		Window:          cfg.MaxClockSkew,
+		Nonces:          cfg.NonceStore,
+		MaxBodySize:     cfg.MaxBodySize,
+	}
+}
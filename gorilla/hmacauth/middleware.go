@@ -0,0 +1,53 @@
+package hmacauth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/rules"
+	"github.com/gorilla/mux"
+)
+
+// method for checking authorization
+func Middleware(cfg Config) mux.MiddlewareFunc {
+	var (
+		verifier = cfg.verifier()
+		policy   = rules.Policy{
+			RequireAuthForAll: cfg.RequireAuthForAll,
+			RestrictedMethods: cfg.RestrictedMethods,
+			RestrictedUrls:    cfg.RestrictedUrls,
+		}
+		unauthorized = cfg.UnauthorizedHandler
+	)
+	if unauthorized == nil {
+		unauthorized = func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !policy.Required(r.Method, r.URL.Path) {
+				// Call the next handler in the chain
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			principal, err := verifier.Authenticate(r)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", verifier.Challenge(err))
+				unauthorized(w, r)
+				return
+			}
+
+			// Call the next handler in the chain
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+		})
+	}
+}
+
+// PrincipalFromContext returns the caller the request was authenticated as. Name of the principal is the key id.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	return auth.FromContext(ctx)
+}
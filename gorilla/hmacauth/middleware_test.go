@@ -0,0 +1,79 @@
+package hmacauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestSignedRequest(t *testing.T) {
+	router := RestrictByUrlRouter()
+
+	req := httptest.NewRequest("POST", "/partner/orders", strings.NewReader(`{"id":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	assert.NilError(t, Sign(req, "partner2-2025", "partner2-new-secret", []string{"content-type"}))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, `partner2-2025 {"id":1}`, w.Body.String())
+
+	// replayed request
+	replay := httptest.NewRequest("POST", "/partner/orders", strings.NewReader(`{"id":1}`))
+	replay.Header = req.Header.Clone()
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, replay)
+	assert.Equal(t, 401, w.Result().StatusCode)
+
+	// tampered body
+	req = httptest.NewRequest("POST", "/partner/orders", strings.NewReader(`{"id":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	assert.NilError(t, Sign(req, "partner1", "partner1-secret", []string{"content-type"}))
+	tampered := httptest.NewRequest("POST", "/partner/orders", strings.NewReader(`{"id":2}`))
+	tampered.Header = req.Header.Clone()
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, tampered)
+	assert.Equal(t, 401, w.Result().StatusCode)
+	assert.Equal(t, `HMAC-SHA256 realm="Authorization Required"`, w.Header().Get("WWW-Authenticate"))
+}
+
+func TestRequiredHeaders(t *testing.T) {
+	router := RestrictByUrlRouter()
+
+	// content-type is not signed
+	req := httptest.NewRequest("POST", "/partner/orders", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	assert.NilError(t, Sign(req, "partner1", "partner1-secret", nil))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+
+	req = httptest.NewRequest("GET", "/openurl", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+}
+
+func TestSharedNonceStore(t *testing.T) {
+	cfg := Config{
+		Keys:              map[string]string{"partner1": "partner1-secret"},
+		NonceStore:        &MemoryNonceStore{},
+		RequireAuthForAll: true,
+	}
+	handler := Middleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	authenticator := cfg.Authenticator()
+
+	req := httptest.NewRequest("GET", "/partner/orders", nil)
+	assert.NilError(t, Sign(req, "partner1", "partner1-secret", nil))
+	_, err := authenticator.Authenticate(req)
+	assert.NilError(t, err)
+
+	// the request can not be replayed against the middleware
+	replay := httptest.NewRequest("GET", "/partner/orders", nil)
+	replay.Header = req.Header.Clone()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, replay)
+	assert.Equal(t, 401, w.Result().StatusCode)
+}
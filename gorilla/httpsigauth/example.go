@@ -0,0 +1,37 @@
+package httpsigauth
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func RestrictByUrlRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	// Partners sign the method, target uri and body digest of every call to /partner/*
+	config := Config{
+		Secrets:              map[string]string{"partner-hmac": "partner-secret"},
+		RequiredComponents:   []string{"@method", "@target-uri"},
+		RequireContentDigest: true,
+		RestrictedUrls:       []string{"/partner/*"},
+		UnauthorizedHandler: func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		},
+	}
+
+	router.Use(Middleware(config))
+
+	router.HandleFunc("/partner/orders", func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		sig, _ := SignatureFromContext(r.Context())
+		fmt.Fprintf(w, "%s %s %d", principal.Name, sig.Algorithm, len(sig.Components))
+	}).Methods("POST")
+
+	router.HandleFunc("/openurl", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("open"))
+	}).Methods("GET")
+
+	return router
+}
@@ -0,0 +1,76 @@
+package httpsigauth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/httpsig"
+	"github.com/golanguzb70/middleware/internal/jwt"
+)
+
+// This is configuration struct of HTTP Message Signatures (RFC 9421) auth.
+type Config struct {
+	// Secrets maps key ids to shared secrets used by hmac-sha256 signatures.
+	Secrets map[string]string `json:"secrets"`
+	// PEMFiles maps key ids to PEM files with RSA, ECDSA or Ed25519 public keys or certificates.
+	PEMFiles map[string]string `json:"pem_files"`
+	// JWKSFile is a path to a local JSON Web Key Set file. The file is reloaded when it changes.
+	JWKSFile string `json:"jwks_file"`
+	// Algorithms that are accepted. By default hmac-sha256, ed25519, ecdsa-p256-sha256, ecdsa-p384-sha384,
+	// rsa-pss-sha512 and rsa-v1_5-sha256 are accepted.
+	Algorithms []string `json:"algorithms"`
+	// Label selects the signature to verify when a request carries several. By default any valid signature is accepted.
+	Label string `json:"label"`
+	// RequiredComponents must be covered by the signature, e.g. "@method", "@target-uri" or "@query-param;name=id".
+	RequiredComponents []string `json:"required_components"`
+	// If this field is set to true, requests with a body must have a Content-Digest header covered by the signature.
+	// Content-Digest is always checked against the body when it is sent.
+	RequireContentDigest bool `json:"require_content_digest"`
+	// MaxAge rejects signatures created longer ago. If it is zero, created parameter is not required.
+	MaxAge time.Duration `json:"max_age"`
+	// ClockSkew is the leeway given when created and expires parameters are checked.
+	ClockSkew time.Duration `json:"clock_skew"`
+	// MaxBodySize limits the body read to check its digest. Default is 10 MB.
+	MaxBodySize int64 `json:"max_body_size"`
+	// Restricted Method means that the middleware only applies for method are given.
+	RestrictedMethods []string `json:"restricted_methods"`
+	// Restricted urls are the urls that are authoriztion is required.
+	// The patterns are the same as in basicauth: /v1/user, /v1/user/{key}, /v1/user/*
+	RestrictedUrls []string `json:"restricted_urls"`
+	// If this field is set to true, all the requests are authenticated
+	RequireAuthForAll bool `json:"require_auth_for_all"`
+	// UnauthorizedHandler is an HTTP handler function that is called when a request is not authorized.
+	// If it is not set, 401 status is written.
+	UnauthorizedHandler http.HandlerFunc
+}
+
+// Signature is a verified signature of a request.
+type Signature = httpsig.Signature
+
+// Principal is the authenticated caller of a request. Its name is the key id.
+// It is the same type for every auth middleware of this module.
+type Principal = auth.Principal
+
+// LoadKeys reads the configured key files. Keys are loaded on the first request anyway,
+// call it on startup to find configuration errors early.
+func (cfg Config) LoadKeys() error {
+	return cfg.verifier().Keys.Load()
+}
+
+func (cfg Config) verifier() *httpsig.Verifier {
+	return &httpsig.Verifier{
+		Keys: &jwt.Keyring{
+			Secrets:  cfg.Secrets,
+			PEMFiles: cfg.PEMFiles,
+			JWKSFile: cfg.JWKSFile,
+		},
+		Algorithms:           cfg.Algorithms,
+		Label:                cfg.Label,
+		RequiredComponents:   cfg.RequiredComponents,
+		RequireContentDigest: cfg.RequireContentDigest,
+		MaxAge:               cfg.MaxAge,
+		ClockSkew:            cfg.ClockSkew,
+		MaxBodySize:          cfg.MaxBodySize,
+	}
+}
@@ -0,0 +1,55 @@
+package httpsigauth
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/httpsig"
+	"gotest.tools/assert"
+)
+
+func TestSignedRequest(t *testing.T) {
+	router := RestrictByUrlRouter()
+
+	signer := httpsig.Signer{
+		KeyID:      "partner-hmac",
+		Algorithm:  httpsig.HMACSHA256,
+		Key:        []byte("partner-secret"),
+		Components: []string{"@method", "@target-uri", "content-digest"},
+	}
+
+	req := httptest.NewRequest("POST", "/partner/orders", strings.NewReader(`{"id":1}`))
+	assert.NilError(t, signer.Sign(req, time.Now()))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "partner-hmac hmac-sha256 3", w.Body.String())
+
+	// body does not match Content-Digest
+	tampered := httptest.NewRequest("POST", "/partner/orders", strings.NewReader(`{"id":2}`))
+	tampered.Header = req.Header.Clone()
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, tampered)
+	assert.Equal(t, 401, w.Result().StatusCode)
+	assert.Equal(t, `Signature realm="Authorization Required"`, w.Header().Get("WWW-Authenticate"))
+
+	// content-digest is not covered
+	signer.Components = []string{"@method", "@target-uri"}
+	req = httptest.NewRequest("POST", "/partner/orders", strings.NewReader(`{"id":1}`))
+	assert.NilError(t, signer.Sign(req, time.Now()))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+
+	req = httptest.NewRequest("POST", "/partner/orders", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+
+	req = httptest.NewRequest("GET", "/openurl", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+}
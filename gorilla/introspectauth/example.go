@@ -0,0 +1,44 @@
+package introspectauth
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func RestrictByScopeRouter(introspectionURL string) *mux.Router {
+	router := mux.NewRouter()
+
+	// Every /v1 request requires an active token, deleting users requires "user:write" scope in addition.
+	config := Config{
+		IntrospectionURL: introspectionURL,
+		ClientID:         "resource-server",
+		ClientSecret:     "secret",
+		RequiredScopes: map[string][]string{
+			"/v1/*":                {"user:read"},
+			"DELETE /v1/user/{id}": {"user:write"},
+		},
+		UnauthorizedHandler: func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		},
+	}
+
+	// Apply the introspection middleware to the router
+	router.Use(Middleware(config))
+
+	router.HandleFunc("/v1/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		resp, _ := IntrospectionFromContext(r.Context())
+		w.Write([]byte(resp.Subject))
+	}).Methods("GET")
+
+	router.HandleFunc("/v1/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		w.Write([]byte("You have been asked a token with user:write scope to delete user " + vars["id"]))
+	}).Methods("DELETE")
+
+	router.HandleFunc("/openurl", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("You gen call me without any token because I am not restricted url."))
+	}).Methods("GET")
+
+	return router
+}
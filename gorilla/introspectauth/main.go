@@ -0,0 +1,65 @@
+package introspectauth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/introspect"
+)
+
+// This is configuration struct of OAuth2 token introspection auth
+type Config struct {
+	// IntrospectionURL is the RFC 7662 introspection endpoint of the authorization server.
+	IntrospectionURL string `json:"introspection_url"`
+	// ClientID and ClientSecret are the credentials of this resource server at the authorization server.
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	// RequiredScopes maps url patterns to scopes the token must have.
+	// Patterns are the same as in RestrictedUrls and may be prefixed with a method, e.g. "DELETE /v1/user/{id}".
+	// Urls given here require a token even if they are not restricted.
+	RequiredScopes map[string][]string `json:"required_scopes"`
+	// MaxCacheTTL limits how long an active token is cached. By default it is cached until it expires.
+	MaxCacheTTL time.Duration `json:"max_cache_ttl"`
+	// HTTPClient is used to call the introspection endpoint. Default client has 10 seconds timeout.
+	HTTPClient *http.Client `json:"-"`
+	// Restricted Method means that the middleware only applies for method are given.
+	RestrictedMethods []string `json:"restricted_methods"`
+	// Restricted urls are the urls that are authoriztion is required.
+	// The patterns are the same as in basicauth: /v1/user, /v1/user/{key}, /v1/user/*
+	RestrictedUrls []string `json:"restricted_urls"`
+	// If this field is set to true, all the requests are authenticated
+	RequireAuthForAll bool `json:"require_auth_for_all"`
+	// UnauthorizedHandler is an HTTP handler function that is called when a request is not authorized.
+	// If it is not set, 401 status is written.
+	UnauthorizedHandler http.HandlerFunc
+	// ForbiddenHandler is called when the token does not have the required scopes.
+	// If it is not set, 403 status is written.
+	ForbiddenHandler http.HandlerFunc
+}
+
+// Introspection is the response of the introspection endpoint for an active token.
+type Introspection = introspect.Response
+
+// Principal is the authenticated caller of a request.
+// It is the same type for every auth middleware of this module.
+type Principal = auth.Principal
+
+// Authenticator returns the bearer token scheme of the config to be combined with other schemes in chain package.
+// Active tokens are cached per returned authenticator, so create it once and reuse it.
+func (cfg Config) Authenticator() auth.Authenticator {
+	return cfg.authenticator()
+}
+
+func (cfg Config) authenticator() *introspect.Authenticator {
+	return &introspect.Authenticator{
+		Client: &introspect.Client{
+			Endpoint:     cfg.IntrospectionURL,
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			HTTPClient:   cfg.HTTPClient,
+			MaxCacheTTL:  cfg.MaxCacheTTL,
+		},
+		RequiredScopes: cfg.RequiredScopes,
+	}
+}
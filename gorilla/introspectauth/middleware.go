@@ -0,0 +1,73 @@
+package introspectauth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/introspect"
+	"github.com/golanguzb70/middleware/internal/rules"
+	"github.com/gorilla/mux"
+)
+
+// method for checking authorization
+func Middleware(cfg Config) mux.MiddlewareFunc {
+	var (
+		authenticator = cfg.authenticator()
+		policy        = rules.Policy{
+			RequireAuthForAll: cfg.RequireAuthForAll,
+			RestrictedMethods: cfg.RestrictedMethods,
+			RestrictedUrls:    cfg.RestrictedUrls,
+		}
+		unauthorized = cfg.UnauthorizedHandler
+		forbidden    = cfg.ForbiddenHandler
+	)
+	if unauthorized == nil {
+		unauthorized = func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		}
+	}
+	if forbidden == nil {
+		forbidden = func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes := introspect.RequiredScopes(cfg.RequiredScopes, r.Method, r.URL.Path)
+			if len(scopes) == 0 && !policy.Required(r.Method, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// endpoint errors are treated as invalid token, requests are never let through without an answer
+			resp, err := authenticator.Check(r)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", authenticator.Challenge(err))
+				if auth.Status(err) == http.StatusForbidden {
+					forbidden(w, r)
+				} else {
+					unauthorized(w, r)
+				}
+				return
+			}
+
+			ctx := introspect.NewContext(r.Context(), resp)
+			ctx = auth.NewContext(ctx, introspect.Principal(resp))
+
+			// Call the next handler in the chain
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// IntrospectionFromContext returns the introspection response of the token the request was authenticated with.
+func IntrospectionFromContext(ctx context.Context) (*Introspection, bool) {
+	return introspect.FromContext(ctx)
+}
+
+// PrincipalFromContext returns the caller the request was authenticated as.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	return auth.FromContext(ctx)
+}
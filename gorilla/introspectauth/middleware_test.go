@@ -0,0 +1,86 @@
+package introspectauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func introspectionServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	tokens := map[string]map[string]interface{}{
+		"reader": {"active": true, "sub": "user1", "scope": "user:read", "exp": time.Now().Add(time.Hour).Unix()},
+		"writer": {"active": true, "sub": "user2", "scope": "user:read user:write", "exp": time.Now().Add(time.Hour).Unix()},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != "resource-server" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		resp, ok := tokens[r.PostFormValue("token")]
+		if !ok {
+			resp = map[string]interface{}{"active": false}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRequireScopes(t *testing.T) {
+	srv := introspectionServer(t)
+	router := RestrictByScopeRouter(srv.URL)
+
+	req := httptest.NewRequest("GET", "/v1/user/10", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+
+	req = httptest.NewRequest("GET", "/v1/user/10", nil)
+	req.Header.Set("Authorization", "Bearer unknown")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+	assert.Equal(t, `Bearer realm="Authorization Required", error="invalid_token"`, w.Header().Get("WWW-Authenticate"))
+
+	req = httptest.NewRequest("GET", "/v1/user/10", nil)
+	req.Header.Set("Authorization", "Bearer reader")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "user1", w.Body.String())
+
+	req = httptest.NewRequest("DELETE", "/v1/user/10", nil)
+	req.Header.Set("Authorization", "Bearer reader")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 403, w.Result().StatusCode)
+	assert.Equal(t, `Bearer realm="Authorization Required", error="insufficient_scope", scope="user:read user:write"`, w.Header().Get("WWW-Authenticate"))
+
+	req = httptest.NewRequest("DELETE", "/v1/user/10", nil)
+	req.Header.Set("Authorization", "Bearer writer")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	req = httptest.NewRequest("GET", "/openurl", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+}
+
+func TestFailClosed(t *testing.T) {
+	srv := introspectionServer(t)
+	router := RestrictByScopeRouter(srv.URL)
+	srv.Close()
+
+	req := httptest.NewRequest("GET", "/v1/user/10", nil)
+	req.Header.Set("Authorization", "Bearer reader")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+}
@@ -0,0 +1,59 @@
+package jwtauth
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func RestrictAllRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	// This configuration checks for all incoming requests for a HS256 token signed with "secret"
+	config := Config{
+		Secrets: map[string]string{
+			"": "secret",
+		},
+		Issuers:           []string{"https://issuer.example.com"},
+		Audiences:         []string{"api"},
+		RequireAuthForAll: true,
+		UnauthorizedHandler: func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		},
+	}
+
+	// Apply the JWT Auth middleware to the router
+	router.Use(Middleware(config))
+
+	router.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := ClaimsFromContext(r.Context())
+		w.Write([]byte(claims.Subject))
+	}).Methods("GET")
+
+	return router
+}
+
+func RestrictByUrlRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	config := Config{
+		Secrets: map[string]string{
+			"": "secret",
+		},
+		RestrictedUrls: []string{"/admin/*"},
+	}
+
+	// Apply the JWT Auth middleware to the router
+	router.Use(Middleware(config))
+
+	router.HandleFunc("/admin/stats", func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := ClaimsFromContext(r.Context())
+		w.Write([]byte("Hello " + claims.Subject + ", you have been asked a token to see me."))
+	}).Methods("GET")
+
+	router.HandleFunc("/openurl", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("You gen call me without any token because I am not restricted url."))
+	}).Methods("GET")
+
+	return router
+}
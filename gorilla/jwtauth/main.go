@@ -0,0 +1,71 @@
+package jwtauth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/jwt"
+)
+
+// This is configuration struct of JWT Auth
+type Config struct {
+	// Secrets maps key ids (kid) to shared secrets used by HS256, HS384 and HS512 tokens.
+	// Use empty key id for tokens without kid header.
+	Secrets map[string]string `json:"secrets"`
+	// PEMFiles maps key ids to PEM files with RSA, ECDSA P-256 or Ed25519 public keys or certificates.
+	PEMFiles map[string]string `json:"pem_files"`
+	// JWKSFile is a path to a local JSON Web Key Set file. The file is reloaded when it changes,
+	// so keys can be rotated by adding a new kid before the issuer starts using it.
+	JWKSFile string `json:"jwks_file"`
+	// Algorithms that are accepted. By default HS256, HS384, HS512, RS256, ES256 and EdDSA are accepted.
+	Algorithms []string `json:"algorithms"`
+	// Issuers that are accepted in iss claim. If empty, iss claim is not checked.
+	Issuers []string `json:"issuers"`
+	// Audiences that are accepted in aud claim. If empty, aud claim is not checked.
+	Audiences []string `json:"audiences"`
+	// ClockSkew is the leeway given when exp, nbf and iat claims are checked.
+	ClockSkew time.Duration `json:"clock_skew"`
+	// If this field is set to true, tokens without exp claim are rejected.
+	// It is false by default, so a token without exp is accepted until its key is removed.
+	// Set it unless the issuer is known to always set exp.
+	RequireExpiration bool `json:"require_expiration"`
+	// Restricted Method means that the middleware only applies for method are given.
+	RestrictedMethods []string `json:"restricted_methods"`
+	// Restricted urls are the urls that are authoriztion is required.
+	// The patterns are the same as in basicauth: /v1/user, /v1/user/{key}, /v1/user/*
+	RestrictedUrls []string `json:"restricted_urls"`
+	// If this field is set to true, all the requests are authenticated
+	RequireAuthForAll bool `json:"require_auth_for_all"`
+	// UnauthorizedHandler is an HTTP handler function that is called when a request is not authorized.
+	// If it is not set, 401 status is written.
+	UnauthorizedHandler http.HandlerFunc
+}
+
+// Claims of a verified token.
+type Claims = jwt.Claims
+
+// Principal is the authenticated caller of a request.
+// It is the same type for every auth middleware of this module.
+type Principal = auth.Principal
+
+// LoadKeys reads the configured key files. Keys are loaded on the first request anyway,
+// call it on startup to find configuration errors early.
+func (cfg Config) LoadKeys() error {
+	return cfg.verifier().Keys.Load()
+}
+
+func (cfg Config) verifier() *jwt.Verifier {
+	return &jwt.Verifier{
+		Keys: &jwt.Keyring{
+			Secrets:  cfg.Secrets,
+			PEMFiles: cfg.PEMFiles,
+			JWKSFile: cfg.JWKSFile,
+		},
+		Algorithms:        cfg.Algorithms,
+		Issuers:           cfg.Issuers,
+		Audiences:         cfg.Audiences,
+		ClockSkew:         cfg.ClockSkew,
+		RequireExpiration: cfg.RequireExpiration,
+	}
+}
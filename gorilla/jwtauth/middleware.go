@@ -0,0 +1,67 @@
+package jwtauth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/jwt"
+	"github.com/golanguzb70/middleware/internal/rules"
+	"github.com/gorilla/mux"
+)
+
+// method for checking authorization
+func Middleware(cfg Config) mux.MiddlewareFunc {
+	var (
+		verifier = cfg.verifier()
+		policy   = rules.Policy{
+			RequireAuthForAll: cfg.RequireAuthForAll,
+			RestrictedMethods: cfg.RestrictedMethods,
+			RestrictedUrls:    cfg.RestrictedUrls,
+		}
+		unauthorized = cfg.UnauthorizedHandler
+	)
+	if unauthorized == nil {
+		unauthorized = func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !policy.Required(r.Method, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims, err := verifier.VerifyRequest(r)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", verifier.Challenge(err))
+				unauthorized(w, r)
+				return
+			}
+
+			ctx := jwt.NewContext(r.Context(), claims)
+			ctx = auth.NewContext(ctx, jwt.Principal(claims))
+
+			// Call the next handler in the chain
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Authenticator returns the bearer token scheme of the config to be combined with other schemes in chain package.
+// Keys are loaded once per returned authenticator, so create it once and reuse it.
+func (cfg Config) Authenticator() auth.Authenticator {
+	return cfg.verifier()
+}
+
+// PrincipalFromContext returns the caller the request was authenticated as. Name of the principal is the sub claim.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	return auth.FromContext(ctx)
+}
+
+// ClaimsFromContext returns claims of the token the request was authenticated with.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	return jwt.FromContext(ctx)
+}
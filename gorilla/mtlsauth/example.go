@@ -0,0 +1,35 @@
+package mtlsauth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+func RestrictAllRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	// Only the billing service and services of the dev namespace issued by "Internal CA" can call this server.
+	config := Config{
+		Identities: []Identity{
+			{URI: "spiffe://example.org/ns/prod/sa/billing", Name: "billing", Roles: []string{"billing"}},
+			{URI: "spiffe://example.org/ns/dev/*", Roles: []string{"dev"}},
+		},
+		AllowedIssuers:    []string{"Internal CA"},
+		RequireAuthForAll: true,
+		UnauthorizedHandler: func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		},
+	}
+
+	// Apply the mTLS middleware to the router
+	router.Use(Middleware(config))
+
+	router.HandleFunc("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		w.Write([]byte(principal.Name + " " + strings.Join(principal.Roles, ",")))
+	}).Methods("GET")
+
+	return router
+}
@@ -0,0 +1,40 @@
+package mtlsauth
+
+import (
+	"net/http"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/mtls"
+)
+
+// This is configuration struct of mutual TLS auth.
+// The TLS server must verify client certificates itself, e.g. with tls.RequireAndVerifyClientCert,
+// the middleware maps the verified certificate to a principal and rejects certificates without a verified chain.
+type Config struct {
+	// Identities maps client certificates to principals by subject CN, DNS SAN or URI SAN (SPIFFE ID).
+	// If it is empty, every certificate is accepted and named by its first URI SAN or subject CN.
+	Identities []Identity `json:"identities"`
+	// AllowedIssuers are common names or full distinguished names of the CAs client certificates may be issued by.
+	// If it is empty, every CA the TLS server trusts is allowed.
+	AllowedIssuers []string `json:"allowed_issuers"`
+	// CRLFile is a path to a PEM or DER certificate revocation list. The file is checked for changes every minute.
+	// If the list can not be read or is outdated, all requests are rejected.
+	CRLFile string `json:"crl_file"`
+	// Restricted Method means that the middleware only applies for method are given.
+	RestrictedMethods []string `json:"restricted_methods"`
+	// Restricted urls are the urls that are authoriztion is required.
+	// The patterns are the same as in basicauth: /v1/user, /v1/user/{key}, /v1/user/*
+	RestrictedUrls []string `json:"restricted_urls"`
+	// If this field is set to true, all the requests are authenticated
+	RequireAuthForAll bool `json:"require_auth_for_all"`
+	// UnauthorizedHandler is an HTTP handler function that is called when a request is not authorized.
+	// If it is not set, 401 status is written.
+	UnauthorizedHandler http.HandlerFunc
+}
+
+// Identity maps a client certificate to a principal.
+type Identity = mtls.Identity
+
+// Principal is the authenticated caller of a request.
+// It is the same type for every auth middleware of this module.
+type Principal = auth.Principal
@@ -0,0 +1,69 @@
+package mtlsauth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/mtls"
+	"github.com/golanguzb70/middleware/internal/rules"
+	"github.com/gorilla/mux"
+)
+
+// method for checking authorization
+func Middleware(cfg Config) mux.MiddlewareFunc {
+	var (
+		authenticator = cfg.authenticator()
+		policy        = rules.Policy{
+			RequireAuthForAll: cfg.RequireAuthForAll,
+			RestrictedMethods: cfg.RestrictedMethods,
+			RestrictedUrls:    cfg.RestrictedUrls,
+		}
+		unauthorized = cfg.UnauthorizedHandler
+	)
+	if unauthorized == nil {
+		unauthorized = func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !policy.Required(r.Method, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			principal, err := authenticator.Authenticate(r)
+			if err != nil {
+				unauthorized(w, r)
+				return
+			}
+
+			// Call the next handler in the chain
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+		})
+	}
+}
+
+// Authenticator returns the client certificate scheme of the config to be combined with other schemes in chain package.
+// The revocation list is loaded once per returned authenticator, so create it once and reuse it.
+func (cfg Config) Authenticator() auth.Authenticator {
+	return cfg.authenticator()
+}
+
+func (cfg Config) authenticator() *mtls.Authenticator {
+	a := &mtls.Authenticator{
+		Identities:     cfg.Identities,
+		AllowedIssuers: cfg.AllowedIssuers,
+	}
+	if cfg.CRLFile != "" {
+		a.CRL = &mtls.CRL{Path: cfg.CRLFile}
+	}
+	return a
+}
+
+// PrincipalFromContext returns the caller the request was authenticated as.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	return auth.FromContext(ctx)
+}
@@ -0,0 +1,47 @@
+package signedurlauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func DownloadRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	config := Config{
+		Keys: map[string]string{
+			"2024": "old-link-secret",
+			"2025": "new-link-secret",
+		},
+		SigningKeyID:   "2025",
+		RestrictedUrls: []string{"/downloads/*"},
+		UnauthorizedHandler: func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		},
+	}
+
+	router.Use(Middleware(config))
+
+	// Hands out a link that works for ten minutes and only once
+	router.HandleFunc("/links/{file}", func(w http.ResponseWriter, r *http.Request) {
+		link, err := config.SignURL("/downloads/"+mux.Vars(r)["file"], SignOptions{
+			TTL:     10 * time.Minute,
+			Method:  http.MethodGet,
+			OneTime: true,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"url": link})
+	}).Methods("GET")
+
+	router.HandleFunc("/downloads/{file}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("content of " + mux.Vars(r)["file"]))
+	}).Methods("GET")
+
+	return router
+}
@@ -0,0 +1,95 @@
+package signedurlauth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/clientip"
+	"github.com/golanguzb70/middleware/internal/nonce"
+	"github.com/golanguzb70/middleware/internal/signedurl"
+)
+
+// This is configuration struct of signed, expiring URLs.
+// Links signed with SignURL work without credentials until they expire.
+type Config struct {
+	// Keys maps key ids to secrets. Links signed with any of the keys are accepted, so secrets can be rotated.
+	Keys map[string]string `json:"keys"`
+	// SigningKeyID is the key new links are signed with. It may be omitted if there is only one key.
+	SigningKeyID string `json:"signing_key_id"`
+	// UsedTokenStore remembers one-time links that were used. Default is an in-memory store of the handler,
+	// use a shared one if the service has several instances.
+	// It is required when more than one handler is built from the config, e.g. Middleware and Authenticator,
+	// otherwise a one-time link can be used once against each of them. Use &MemoryUsedTokenStore{} to share one in-memory store.
+	UsedTokenStore UsedTokenStore `json:"-"`
+	// TrustedProxies are CIDRs of the reverse proxies in front of the service, e.g. 10.0.0.0/8.
+	// Links restricted to an IP are checked against the client address the trusted proxies forward.
+	// Without trusted proxies, the address of the direct peer is checked. Invalid entries never match.
+	TrustedProxies []string `json:"trusted_proxies"`
+	// ForwardedHeader is the header trusted proxies forward the client address in: X-Forwarded-For (default) or Forwarded.
+	ForwardedHeader string `json:"forwarded_header"`
+	// Restricted Method means that the middleware only applies for method are given.
+	RestrictedMethods []string `json:"restricted_methods"`
+	// Restricted urls are the urls that are authoriztion is required.
+	// The patterns are the same as in basicauth: /v1/user, /v1/user/{key}, /v1/user/*
+	RestrictedUrls []string `json:"restricted_urls"`
+	// If this field is set to true, all the requests are authenticated
+	RequireAuthForAll bool `json:"require_auth_for_all"`
+	// UnauthorizedHandler is an HTTP handler function that is called when a request is not authorized.
+	// If it is not set, 401 status is written.
+	UnauthorizedHandler http.HandlerFunc
+}
+
+// SignOptions are the expiry and the restrictions of a link.
+type SignOptions = signedurl.Options
+
+// UsedTokenStore remembers tokens of one-time links.
+// The key id and the token identify a link, the token may be forgotten after expires.
+type UsedTokenStore = nonce.Store
+
+// MemoryUsedTokenStore is the in-memory UsedTokenStore of a single process.
+type MemoryUsedTokenStore = nonce.Memory
+
+// Principal is the authenticated caller of a request. Its name is the key id of the link.
+// It is the same type for every auth middleware of this module.
+type Principal = auth.Principal
+
+// SignURL returns the url with signature parameters. The url may be absolute or only a path with query.
+func (cfg Config) SignURL(rawURL string, opts SignOptions) (string, error) {
+	keyID := cfg.SigningKeyID
+	if keyID == "" && len(cfg.Keys) == 1 {
+		for id := range cfg.Keys {
+			keyID = id
+		}
+	}
+	secret, ok := cfg.Keys[keyID]
+	if !ok {
+		return "", errors.New("signedurlauth: signing key is not set")
+	}
+	return signedurl.Sign(rawURL, keyID, secret, opts, time.Now())
+}
+
+// Validate checks the trusted proxy networks.
+func (cfg Config) Validate() error {
+	_, err := clientip.ParseSet(cfg.TrustedProxies)
+	return err
+}
+
+// Authenticator returns the signed url scheme of the config to be combined with other schemes in chain package.
+// The authenticator remembers used one-time links, so create it once and reuse it.
+// Set UsedTokenStore if Middleware is built from the same config as well.
+func (cfg Config) Authenticator() auth.Authenticator {
+	return cfg.verifier()
+}
+
+func (cfg Config) verifier() *signedurl.Verifier {
+	return &signedurl.Verifier{
+		Keys:       cfg.Keys,
+		UsedTokens: cfg.UsedTokenStore,
+		ClientIP: clientip.Resolver{
+			TrustedProxies: clientip.ParseValid(cfg.TrustedProxies),
+			Header:         cfg.ForwardedHeader,
+		},
+	}
+}
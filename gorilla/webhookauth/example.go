@@ -0,0 +1,45 @@
+package webhookauth
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func WebhooksRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	// Every provider has its own config, the middleware is added to its subrouter only
+	github := Config{
+		Provider:          GitHub,
+		Secrets:           []string{"github-secret"},
+		RequireAuthForAll: true,
+	}
+	stripe := Config{
+		Provider: Stripe,
+		// the new secret is added before it is rolled in the Stripe dashboard
+		Secrets:           []string{"stripe-new-secret", "stripe-old-secret"},
+		RequireAuthForAll: true,
+		UnauthorizedHandler: func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		},
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		// the body is still readable after the signature check
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte(principal.Name + " " + string(body)))
+	}
+
+	githubRouter := router.PathPrefix("/webhooks/github").Subrouter()
+	githubRouter.Use(Middleware(github))
+	githubRouter.HandleFunc("", handler).Methods("POST")
+
+	stripeRouter := router.PathPrefix("/webhooks/stripe").Subrouter()
+	stripeRouter.Use(Middleware(stripe))
+	stripeRouter.HandleFunc("", handler).Methods("POST")
+
+	return router
+}
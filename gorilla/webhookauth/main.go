@@ -0,0 +1,72 @@
+package webhookauth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/webhook"
+)
+
+// Supported providers.
+const (
+	GitHub = "github"
+	Stripe = "stripe"
+	Slack  = "slack"
+)
+
+// This is configuration struct of webhook signature auth. Use one config per provider.
+type Config struct {
+	// Provider selects how webhooks are signed: github, stripe or slack.
+	Provider string `json:"provider"`
+	// Secrets are the signing secrets of the provider. Several secrets may be given while a secret is rotated.
+	Secrets []string `json:"secrets"`
+	// Tolerance is the maximum age of a delivery of stripe and slack. Default is five minutes.
+	Tolerance time.Duration `json:"tolerance"`
+	// MaxBodySize limits the body read to check the signature. Default is 10 MB.
+	MaxBodySize int64 `json:"max_body_size"`
+	// Restricted Method means that the middleware only applies for method are given.
+	RestrictedMethods []string `json:"restricted_methods"`
+	// Restricted urls are the urls that are authoriztion is required.
+	// The patterns are the same as in basicauth: /v1/user, /v1/user/{key}, /v1/user/*
+	RestrictedUrls []string `json:"restricted_urls"`
+	// If this field is set to true, all the requests are authenticated
+	RequireAuthForAll bool `json:"require_auth_for_all"`
+	// UnauthorizedHandler is an HTTP handler function that is called when a request is not authorized.
+	// If it is not set, 401 status is written.
+	UnauthorizedHandler http.HandlerFunc
+}
+
+// Principal is the authenticated caller of a request. Its name is the provider.
+// It is the same type for every auth middleware of this module.
+type Principal = auth.Principal
+
+// Sign sets the signature headers the provider would send with the body. Use it to test webhook handlers.
+func Sign(provider string, header http.Header, secret string, body []byte) error {
+	preset, ok := webhook.Presets[provider]
+	if !ok {
+		return webhook.ErrUnknownProvider
+	}
+	preset.Sign(header, secret, body, time.Now())
+	return nil
+}
+
+// Validate checks the provider. Webhooks of an unknown provider are always rejected.
+func (cfg Config) Validate() error {
+	_, err := webhook.NewVerifier(cfg.Provider, cfg.Secrets)
+	return err
+}
+
+// Authenticator returns the webhook signature scheme of the config to be combined with other schemes in chain package.
+func (cfg Config) Authenticator() auth.Authenticator {
+	return cfg.verifier()
+}
+
+func (cfg Config) verifier() *webhook.Verifier {
+	return &webhook.Verifier{
+		Preset:      webhook.Presets[cfg.Provider],
+		Secrets:     cfg.Secrets,
+		Tolerance:   cfg.Tolerance,
+		MaxBodySize: cfg.MaxBodySize,
+	}
+}
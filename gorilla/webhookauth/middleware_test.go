@@ -0,0 +1,59 @@
+package webhookauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/webhook"
+	"github.com/gorilla/mux"
+	"gotest.tools/assert"
+)
+
+func TestWebhooks(t *testing.T) {
+	router := WebhooksRouter()
+
+	req := httptest.NewRequest("POST", "/webhooks/github", strings.NewReader(`{"action":"opened"}`))
+	assert.NilError(t, Sign(GitHub, req.Header, "github-secret", []byte(`{"action":"opened"}`)))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, `github {"action":"opened"}`, w.Body.String())
+
+	// signed for another provider
+	req = httptest.NewRequest("POST", "/webhooks/stripe", strings.NewReader(`{}`))
+	assert.NilError(t, Sign(GitHub, req.Header, "stripe-new-secret", []byte(`{}`)))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+
+	for _, secret := range []string{"stripe-old-secret", "stripe-new-secret"} {
+		req = httptest.NewRequest("POST", "/webhooks/stripe", strings.NewReader(`{}`))
+		assert.NilError(t, Sign(Stripe, req.Header, secret, []byte(`{}`)))
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Result().StatusCode)
+	}
+
+	req = httptest.NewRequest("POST", "/webhooks/stripe", strings.NewReader(`{}`))
+	webhook.Stripe.Sign(req.Header, "stripe-new-secret", []byte(`{}`), time.Now().Add(-time.Hour))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Config{Provider: "bitbucket", RequireAuthForAll: true}
+	assert.ErrorContains(t, cfg.Validate(), "unknown provider")
+
+	router := mux.NewRouter()
+	router.Use(Middleware(cfg))
+	router.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {})
+	req := httptest.NewRequest("POST", "/webhook", strings.NewReader(`{}`))
+	assert.NilError(t, Sign(GitHub, req.Header, "", []byte(`{}`)))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Result().StatusCode)
+}
@@ -0,0 +1,14 @@
+// Package auth holds helpers shared by the authentication middlewares of this module.
+package auth
+
+import "strings"
+
+// BearerToken extracts the token from "Bearer <token>" header value.
+func BearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestBearerToken(t *testing.T) {
+	token, ok := BearerToken("Bearer abc.def.ghi")
+	assert.Assert(t, ok)
+	assert.Equal(t, "abc.def.ghi", token)
+
+	token, ok = BearerToken("bearer abc")
+	assert.Assert(t, ok)
+	assert.Equal(t, "abc", token)
+
+	_, ok = BearerToken("Basic dXNlcjpwYXNz")
+	assert.Assert(t, !ok)
+
+	_, ok = BearerToken("Bearer ")
+	assert.Assert(t, !ok)
+}
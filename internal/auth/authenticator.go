@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrNoCredentials is returned by authenticators when the request carries no credentials of their scheme.
+	ErrNoCredentials = errors.New("auth: no credentials")
+	// ErrInvalidCredentials is returned by authenticators when the credentials are wrong.
+	ErrInvalidCredentials = errors.New("auth: invalid credentials")
+	// ErrForbidden is returned when the caller is authenticated but is not allowed to make the request.
+	ErrForbidden = errors.New("auth: forbidden")
+)
+
+// Authenticator authenticates requests with one scheme.
+type Authenticator interface {
+	// Authenticate returns the principal of the request or an error if the request is not authenticated.
+	Authenticate(r *http.Request) (*Principal, error)
+	// Challenge returns the WWW-Authenticate challenge for the error returned by Authenticate.
+	// Schemes without a challenge, such as mutual TLS, return empty string.
+	Challenge(err error) string
+}
+
+// Status returns the HTTP status code for an error returned by Authenticate.
+func Status(err error) int {
+	if errors.Is(err, ErrForbidden) {
+		return http.StatusForbidden
+	}
+	return http.StatusUnauthorized
+}
+
+// AnyOf returns an authenticator that tries the authenticators in order and
+// accepts the request with the first one that succeeds.
+func AnyOf(authenticators ...Authenticator) Authenticator {
+	return anyOf(authenticators)
+}
+
+// AllOf returns an authenticator that accepts the request only if all the
+// authenticators succeed. The principals are merged: the name comes from the
+// first authenticator, roles and attributes are combined.
+func AllOf(authenticators ...Authenticator) Authenticator {
+	return allOf(authenticators)
+}
+
+// chainError keeps the errors of every authenticator so the challenge lists
+// each scheme that could have been used.
+type chainError struct {
+	errs []error
+	// forbidden is set if a scheme authenticated the caller but denied the request.
+	forbidden bool
+}
+
+func (e *chainError) Error() string {
+	msgs := make([]string, 0, len(e.errs))
+	for _, err := range e.errs {
+		if err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *chainError) Is(target error) bool {
+	return target == ErrForbidden && e.forbidden
+}
+
+type anyOf []Authenticator
+
+func (c anyOf) Authenticate(r *http.Request) (*Principal, error) {
+	chainErr := &chainError{errs: make([]error, len(c))}
+	for i, a := range c {
+		p, err := a.Authenticate(r)
+		if err == nil {
+			return p, nil
+		}
+		chainErr.errs[i] = err
+		if errors.Is(err, ErrForbidden) {
+			chainErr.forbidden = true
+		}
+	}
+	return nil, chainErr
+}
+
+func (c anyOf) Challenge(err error) string {
+	return challenges(c, err)
+}
+
+type allOf []Authenticator
+
+func (c allOf) Authenticate(r *http.Request) (*Principal, error) {
+	var (
+		merged   *Principal
+		chainErr = &chainError{errs: make([]error, len(c))}
+		failed   bool
+	)
+	for i, a := range c {
+		p, err := a.Authenticate(r)
+		if err != nil {
+			chainErr.errs[i] = err
+			if errors.Is(err, ErrForbidden) {
+				chainErr.forbidden = true
+			}
+			failed = true
+			continue
+		}
+		merged = merge(merged, p)
+	}
+	if failed {
+		return nil, chainErr
+	}
+	return merged, nil
+}
+
+func (c allOf) Challenge(err error) string {
+	return challenges(c, err)
+}
+
+// challenges joins the challenges of the authenticators that failed.
+func challenges(c []Authenticator, err error) string {
+	var (
+		chainErr, _ = err.(*chainError)
+		list        []string
+	)
+	for i, a := range c {
+		var e error
+		if chainErr != nil {
+			e = chainErr.errs[i]
+			if e == nil {
+				continue
+			}
+		}
+		if ch := a.Challenge(e); ch != "" {
+			list = append(list, ch)
+		}
+	}
+	return strings.Join(list, ", ")
+}
+
+func merge(dst, src *Principal) *Principal {
+	if dst == nil {
+		cp := *src
+		cp.Roles = append([]string(nil), src.Roles...)
+		cp.Attributes = make(map[string]string, len(src.Attributes))
+		for k, v := range src.Attributes {
+			cp.Attributes[k] = v
+		}
+		cp.Attributes[src.Scheme+".name"] = src.Name
+		return &cp
+	}
+
+	dst.Scheme += "+" + src.Scheme
+	for _, role := range src.Roles {
+		if !dst.HasRole(role) {
+			dst.Roles = append(dst.Roles, role)
+		}
+	}
+	for k, v := range src.Attributes {
+		if _, ok := dst.Attributes[k]; !ok {
+			dst.Attributes[k] = v
+		}
+	}
+	if _, ok := dst.Attributes[src.Scheme+".name"]; !ok {
+		dst.Attributes[src.Scheme+".name"] = src.Name
+	}
+	return dst
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+type fake struct {
+	principal *Principal
+	err       error
+	challenge string
+}
+
+func (f fake) Authenticate(*http.Request) (*Principal, error) { return f.principal, f.err }
+func (f fake) Challenge(error) string                         { return f.challenge }
+
+func TestAnyOf(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	basic := fake{err: ErrNoCredentials, challenge: "Basic"}
+	bearer := fake{err: ErrInvalidCredentials, challenge: "Bearer"}
+	forbidden := fake{err: ErrForbidden, challenge: `Bearer error="insufficient_scope"`}
+	ok := fake{principal: &Principal{Name: "user1", Scheme: "basic"}}
+
+	p, err := AnyOf(basic, ok).Authenticate(r)
+	assert.NilError(t, err)
+	assert.Equal(t, "user1", p.Name)
+
+	a := AnyOf(basic, bearer)
+	_, err = a.Authenticate(r)
+	assert.Equal(t, http.StatusUnauthorized, Status(err))
+	assert.Equal(t, "Basic, Bearer", a.Challenge(err))
+
+	a = AnyOf(basic, forbidden)
+	_, err = a.Authenticate(r)
+	assert.Assert(t, errors.Is(err, ErrForbidden))
+	assert.Equal(t, http.StatusForbidden, Status(err))
+}
+
+func TestAllOf(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	cert := fake{principal: &Principal{Name: "laptop", Scheme: "mtls", Roles: []string{"device"}, Attributes: map[string]string{"serial": "1"}}}
+	user := fake{principal: &Principal{Name: "admin", Scheme: "basic", Roles: []string{"admin", "device"}}}
+	missing := fake{err: ErrNoCredentials, challenge: "Basic"}
+
+	p, err := AllOf(cert, user).Authenticate(r)
+	assert.NilError(t, err)
+	assert.Equal(t, "laptop", p.Name)
+	assert.Equal(t, "mtls+basic", p.Scheme)
+	assert.DeepEqual(t, []string{"device", "admin"}, p.Roles)
+	assert.DeepEqual(t, map[string]string{"serial": "1", "mtls.name": "laptop", "basic.name": "admin"}, p.Attributes)
+	// principals of the schemes are not modified
+	assert.DeepEqual(t, []string{"device"}, cert.principal.Roles)
+
+	a := AllOf(cert, missing)
+	_, err = a.Authenticate(r)
+	assert.Equal(t, http.StatusUnauthorized, Status(err))
+	assert.Equal(t, "Basic", a.Challenge(err))
+
+	// nested chains list the challenges of the inner schemes
+	a = AllOf(cert, AnyOf(missing, fake{err: ErrNoCredentials, challenge: "Bearer"}))
+	_, err = a.Authenticate(r)
+	assert.Equal(t, "Basic, Bearer", a.Challenge(err))
+}
@@ -0,0 +1,38 @@
+package auth
+
+import "context"
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	// Name identifies the caller, e.g. basic auth user name or certificate identity.
+	Name string `json:"name"`
+	// Scheme is the authentication scheme the caller was authenticated with, e.g. "basic" or "mtls".
+	Scheme string `json:"scheme"`
+	// Roles granted to the caller.
+	Roles []string `json:"roles,omitempty"`
+	// Attributes holds scheme specific details of the caller.
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// HasRole reports whether the principal was granted the role.
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx that carries the principal.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx by NewContext.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
@@ -0,0 +1,461 @@
+// Package basic implements HTTP Basic authentication (RFC 7617) shared by the
+// gin and gorilla basicauth middlewares.
+package basic
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/clientip"
+	"github.com/golanguzb70/middleware/internal/passwd"
+	"github.com/golanguzb70/middleware/internal/rules"
+	"github.com/golanguzb70/middleware/internal/schedule"
+	"github.com/golanguzb70/middleware/internal/session"
+	"github.com/golanguzb70/middleware/internal/tokens"
+	"github.com/golanguzb70/middleware/internal/totp"
+)
+
+var (
+	// ErrNoSessions is returned by Login if sessions are not configured.
+	ErrNoSessions = errors.New("basic: sessions are not configured")
+	// ErrNoTokens is returned by ExchangeToken and RevokeToken if tokens are not configured.
+	ErrNoTokens = errors.New("basic: tokens are not configured")
+)
+
+// Challenge is the WWW-Authenticate or Proxy-Authenticate value sent with 401 and 407 responses.
+const Challenge = `Basic realm="Authorization Required"`
+
+type User struct {
+	UserName string `json:"user_name"`
+	// Password is the password or its bcrypt ($2a$, $2b$, $2y$) or argon2id ($argon2id$) hash.
+	Password string `json:"password"`
+	// Roles are given to the principal of the user after successful authentication.
+	Roles []string `json:"roles"`
+	// TOTPSecret is the base32 secret of one-time codes (RFC 6238) as shown by authenticator apps.
+	// Users with a secret must send a code in the TOTP header or appended to the password.
+	TOTPSecret string `json:"totp_secret"`
+	// Credentials are more passwords of the user, each valid in its own time window.
+	// An old and a new password can overlap while a shared password is rotated.
+	Credentials []Credential `json:"credentials"`
+	// If this field is set to true, the user can not authenticate in any way.
+	Disabled bool `json:"disabled"`
+	// ExpiresAt is when the account stops working. Zero means never.
+	ExpiresAt time.Time `json:"expires_at"`
+	// AllowedCIDRs are the networks the user may authenticate from, e.g. 10.20.0.0/16. Empty means anywhere.
+	// Invalid entries never match.
+	AllowedCIDRs []string `json:"allowed_cidrs"`
+	// AccessWindow is when the user may authenticate, e.g. business hours. Nil means any time.
+	AccessWindow *schedule.Window `json:"access_window"`
+}
+
+// Credential is a password valid from NotBefore until NotAfter. Zero times are not checked.
+type Credential struct {
+	// Password is the password or its hash, the same as in User.
+	Password  string    `json:"password"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+// Active reports whether the user may authenticate at now.
+func (u *User) Active(now time.Time) bool {
+	if u.Disabled || (!u.ExpiresAt.IsZero() && !now.Before(u.ExpiresAt)) {
+		return false
+	}
+	return u.AccessWindow == nil || u.AccessWindow.Contains(now)
+}
+
+// Validate checks the password hashes, the passwords against the policy if it is set,
+// the TOTP secret, the allowed networks and the access window of the user.
+func (u *User) Validate(policy *passwd.Policy) error {
+	passwords := []string{u.Password}
+	for _, c := range u.Credentials {
+		passwords = append(passwords, c.Password)
+	}
+	for _, password := range passwords {
+		switch {
+		case passwd.IsHash(password):
+			if !passwd.Supported(password) {
+				return passwd.ErrUnsupported
+			}
+		case policy != nil && password != "":
+			if err := policy.Check(u.UserName, password); err != nil {
+				return err
+			}
+		}
+	}
+	if u.TOTPSecret != "" {
+		if _, err := totp.DecodeSecret(u.TOTPSecret); err != nil {
+			return err
+		}
+	}
+	if _, err := clientip.ParseSet(u.AllowedCIDRs); err != nil {
+		return err
+	}
+	if u.AccessWindow != nil {
+		return u.AccessWindow.Validate()
+	}
+	return nil
+}
+
+// CheckPassword reports whether the password is one of the credentials of the user valid at now.
+// Password is always valid; it is not checked when it is empty and there are other credentials.
+func (u *User) CheckPassword(password string, now time.Time) bool {
+	_, ok := u.match(password, now)
+	return ok
+}
+
+// match is CheckPassword that also returns the stored password or hash the password matched.
+func (u *User) match(password string, now time.Time) (string, bool) {
+	if (u.Password != "" || len(u.Credentials) == 0) && matches(u.Password, password) {
+		return u.Password, true
+	}
+	for _, c := range u.Credentials {
+		if !matches(c.Password, password) {
+			continue
+		}
+		if (c.NotBefore.IsZero() || !now.Before(c.NotBefore)) && (c.NotAfter.IsZero() || now.Before(c.NotAfter)) {
+			return c.Password, true
+		}
+	}
+	return "", false
+}
+
+// matches compares a password with a stored password or bcrypt or argon2id hash of it.
+func matches(stored, password string) bool {
+	if passwd.IsHash(stored) {
+		ok, _ := passwd.Verify(stored, password)
+		return ok
+	}
+	return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
+}
+
+// ParseHeader decodes "Basic base64(user:password)" header value.
+func ParseHeader(header string) (username, password string, ok bool) {
+	credentials := strings.SplitN(header, " ", 2)
+	if len(credentials) != 2 {
+		return "", "", false
+	}
+
+	decodedCredentials, err := base64.StdEncoding.DecodeString(credentials[1])
+	if err != nil {
+		return "", "", false
+	}
+
+	credentialsPair := strings.SplitN(string(decodedCredentials), ":", 2)
+	if len(credentialsPair) != 2 {
+		return "", "", false
+	}
+	return credentialsPair[0], credentialsPair[1], true
+}
+
+// Authenticator checks basic credentials against a list of users.
+type Authenticator struct {
+	Users []User
+	// Realm is the realm of the challenge. Default is "Authorization Required".
+	Realm string
+	// Proxy makes the authenticator read Proxy-Authorization instead of Authorization header.
+	Proxy bool
+	// Sessions makes the authenticator accept session cookies issued by Login as well.
+	Sessions *session.Manager
+	// Tokens makes the authenticator accept bearer access tokens issued by ExchangeToken as well.
+	Tokens *tokens.Service
+	// TOTP checks one-time codes of users with a TOTP secret. Such users are rejected if it is not set.
+	TOTP *totp.Verifier
+	// ClientIP finds the client address AllowedCIDRs of users are checked against.
+	ClientIP clientip.Resolver
+	// Rules limit when matching requests are allowed, see Decide.
+	Rules []rules.Rule
+	// Rehash upgrades outdated password hashes of users on successful login when set.
+	// Leave it nil if the authenticator only evaluates requests, see Rehasher.
+	Rehash *Rehasher
+	// Now is used instead of time.Now when set.
+	Now func() time.Time
+}
+
+// RequestHeader returns the header the credentials are read from.
+func (a *Authenticator) RequestHeader() string {
+	if a.Proxy {
+		return "Proxy-Authorization"
+	}
+	return "Authorization"
+}
+
+// ChallengeHeader returns the response header the challenge is sent in.
+func (a *Authenticator) ChallengeHeader() string {
+	if a.Proxy {
+		return "Proxy-Authenticate"
+	}
+	return "WWW-Authenticate"
+}
+
+// UnauthorizedStatus returns 407 in proxy mode and 401 otherwise.
+func (a *Authenticator) UnauthorizedStatus() int {
+	if a.Proxy {
+		return http.StatusProxyAuthRequired
+	}
+	return http.StatusUnauthorized
+}
+
+// Authenticate returns the principal of the user the request is authenticated as.
+// A valid session cookie is accepted before the Authorization header.
+func (a *Authenticator) Authenticate(r *http.Request) (*auth.Principal, error) {
+	principal, _, err := a.authenticate(r)
+	return principal, err
+}
+
+// AuthenticateAndRefresh is Authenticate that also extends the idle timeout of the session cookie.
+func (a *Authenticator) AuthenticateAndRefresh(w http.ResponseWriter, r *http.Request) (*auth.Principal, error) {
+	principal, s, err := a.authenticate(r)
+	if err == nil && s != nil {
+		err = a.Sessions.Refresh(w, s)
+	}
+	return principal, err
+}
+
+func (a *Authenticator) authenticate(r *http.Request) (*auth.Principal, *session.Session, error) {
+	if a.Tokens != nil {
+		if _, ok := auth.BearerToken(r.Header.Get(a.RequestHeader())); ok {
+			principal, err := a.Tokens.Authenticate(r)
+			if err != nil {
+				return nil, nil, err
+			}
+			// users are looked up on every request, so removed, disabled and expired users lose their access tokens
+			if user := a.Lookup(principal.Name); user == nil || !a.allowed(r, user) {
+				return nil, nil, auth.ErrInvalidCredentials
+			}
+			return principal, nil, nil
+		}
+	}
+
+	if a.Sessions != nil {
+		s, err := a.Sessions.Read(r)
+		if err == nil {
+			// users are looked up on every request, so removed, disabled and expired users lose their sessions
+			if user := a.Lookup(s.User); user != nil && a.allowed(r, user) {
+				principal := Principal(user)
+				principal.Scheme = "session"
+				return principal, s, nil
+			}
+		}
+		if r.Header.Get(a.RequestHeader()) == "" {
+			if errors.Is(err, auth.ErrNoCredentials) {
+				return nil, nil, err
+			}
+			return nil, nil, auth.ErrInvalidCredentials
+		}
+	}
+
+	principal, err := a.authenticateHeader(r)
+	return principal, nil, err
+}
+
+func (a *Authenticator) authenticateHeader(r *http.Request) (*auth.Principal, error) {
+	header := r.Header.Get(a.RequestHeader())
+	if header == "" {
+		return nil, auth.ErrNoCredentials
+	}
+
+	username, password, ok := ParseHeader(header)
+	if !ok {
+		return nil, auth.ErrInvalidCredentials
+	}
+
+	user := a.Check(r, username, password)
+	if user == nil {
+		return nil, auth.ErrInvalidCredentials
+	}
+	return Principal(user), nil
+}
+
+// Challenge returns the challenge value for 401 and 407 responses.
+// It is empty if sessions suppress the challenge.
+func (a *Authenticator) Challenge(err error) string {
+	if errors.Is(err, tokens.ErrInvalidToken) {
+		return tokens.Challenge
+	}
+	if a.Sessions != nil && a.Sessions.Config.SuppressChallenge {
+		return ""
+	}
+	if a.Realm != "" {
+		return fmt.Sprintf("Basic realm=%q", a.Realm)
+	}
+	return Challenge
+}
+
+// Login checks the credentials of the request and starts a session of the user.
+// Credentials are read from the Authorization header, a JSON body with
+// user_name and password fields, or username and password form fields.
+func (a *Authenticator) Login(w http.ResponseWriter, r *http.Request) (*auth.Principal, error) {
+	if a.Sessions == nil {
+		return nil, ErrNoSessions
+	}
+
+	username, password, ok := ParseHeader(r.Header.Get("Authorization"))
+	if !ok {
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			var body User
+			if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&body); err != nil {
+				return nil, auth.ErrInvalidCredentials
+			}
+			username, password = body.UserName, body.Password
+		} else {
+			username, password = r.PostFormValue("username"), r.PostFormValue("password")
+		}
+	}
+
+	user := a.Check(r, username, password)
+	if user == nil {
+		return nil, auth.ErrInvalidCredentials
+	}
+	if err := a.Sessions.Issue(w, user.UserName); err != nil {
+		return nil, err
+	}
+	principal := Principal(user)
+	principal.Scheme = "session"
+	return principal, nil
+}
+
+// Logout ends the session of the request.
+func (a *Authenticator) Logout(w http.ResponseWriter) {
+	if a.Sessions != nil {
+		a.Sessions.Clear(w)
+	}
+}
+
+// ExchangeToken issues tokens for the form of a token request (RFC 6749).
+// With grant_type client_credentials, or without grant_type, the Basic credentials of the
+// Authorization header are exchanged. With grant_type refresh_token, the refresh_token field
+// is exchanged and the old refresh token stops working.
+func (a *Authenticator) ExchangeToken(r *http.Request) (*tokens.Response, error) {
+	if a.Tokens == nil {
+		return nil, ErrNoTokens
+	}
+
+	switch r.PostFormValue("grant_type") {
+	case "", "client_credentials":
+		principal, err := a.authenticateHeader(r)
+		if err != nil {
+			return nil, err
+		}
+		return a.Tokens.Issue(principal.Name, principal.Roles)
+	case "refresh_token":
+		// roles are taken from the current user list, removed, disabled and expired users can not refresh
+		return a.Tokens.Refresh(r.PostFormValue("refresh_token"), func(username string) ([]string, bool) {
+			user := a.Lookup(username)
+			if user == nil || !a.allowed(r, user) {
+				return nil, false
+			}
+			return user.Roles, true
+		})
+	}
+	return nil, tokens.ErrUnsupportedGrant
+}
+
+// RevokeToken revokes the refresh token in the token field of the form (RFC 7009)
+// together with the tokens rotated from the same login.
+func (a *Authenticator) RevokeToken(r *http.Request) error {
+	if a.Tokens == nil {
+		return ErrNoTokens
+	}
+	return a.Tokens.Revoke(r.PostFormValue("token"))
+}
+
+// Check returns the user with the credentials of the request. Users with a TOTP secret must send
+// a valid one-time code in the TOTP header, or appended to the password if the header is missing.
+// Users with AllowedCIDRs must come from one of the networks.
+func (a *Authenticator) Check(r *http.Request, username, password string) *User {
+	user := a.Lookup(username)
+	if user == nil || user.TOTPSecret == "" {
+		user, stored := a.find(username, password)
+		if user == nil || !a.allowed(r, user) {
+			return nil
+		}
+		a.rehash(user, stored, password)
+		return user
+	}
+	if a.TOTP == nil || !a.allowed(r, user) {
+		return nil
+	}
+
+	code := r.Header.Get(a.TOTP.Config.HeaderName())
+	if code == "" {
+		digits := a.TOTP.Config.CodeDigits()
+		if len(password) <= digits {
+			return nil
+		}
+		password, code = password[:len(password)-digits], password[len(password)-digits:]
+	}
+	// the password is checked first, so a wrong password does not use up the code
+	stored, ok := user.match(password, a.now())
+	if !ok || a.TOTP.Verify(user.UserName, user.TOTPSecret, code) != nil {
+		return nil
+	}
+	a.rehash(user, stored, password)
+	return user
+}
+
+func (a *Authenticator) rehash(u *User, stored, password string) {
+	if a.Rehash != nil {
+		a.Rehash.rehash(u.UserName, stored, password)
+	}
+}
+
+// Find returns the active user with given credentials. One-time codes are not checked, use Check for requests.
+func (a *Authenticator) Find(username, password string) *User {
+	user, _ := a.find(username, password)
+	return user
+}
+
+// find is Find that also returns the stored password or hash the password matched.
+func (a *Authenticator) find(username, password string) (*User, string) {
+	now := a.now()
+	for i := 0; i < len(a.Users); i++ {
+		if username != a.Users[i].UserName || !a.Users[i].Active(now) {
+			continue
+		}
+		if stored, ok := a.Users[i].match(password, now); ok {
+			return &a.Users[i], stored
+		}
+	}
+	return nil, ""
+}
+
+// Lookup returns the active user with the name.
+func (a *Authenticator) Lookup(username string) *User {
+	now := a.now()
+	for i := 0; i < len(a.Users); i++ {
+		if username == a.Users[i].UserName && a.Users[i].Active(now) {
+			return &a.Users[i]
+		}
+	}
+	return nil
+}
+
+// allowed reports whether the request comes from a network the user may authenticate from.
+func (a *Authenticator) allowed(r *http.Request, u *User) bool {
+	if len(u.AllowedCIDRs) == 0 {
+		return true
+	}
+	addr, ok := a.ClientIP.Client(r)
+	return ok && clientip.ParseValid(u.AllowedCIDRs).Contains(addr)
+}
+
+func (a *Authenticator) now() time.Time {
+	if a.Now != nil {
+		return a.Now()
+	}
+	return time.Now()
+}
+
+// Principal returns the principal of an authenticated user.
+func Principal(u *User) *auth.Principal {
+	return &auth.Principal{Name: u.UserName, Scheme: "basic", Roles: u.Roles}
+}
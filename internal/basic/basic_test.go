@@ -0,0 +1,458 @@
+package basic
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/clientip"
+	"github.com/golanguzb70/middleware/internal/passwd"
+	"github.com/golanguzb70/middleware/internal/rules"
+	"github.com/golanguzb70/middleware/internal/schedule"
+	"github.com/golanguzb70/middleware/internal/session"
+	"github.com/golanguzb70/middleware/internal/tokens"
+	"github.com/golanguzb70/middleware/internal/totp"
+	"gotest.tools/assert"
+)
+
+func TestParseHeader(t *testing.T) {
+	username, password, ok := ParseHeader("Basic dXNlcjE6cGFzczp3b3Jk")
+	assert.Assert(t, ok)
+	assert.Equal(t, "user1", username)
+	assert.Equal(t, "pass:word", password)
+
+	for _, header := range []string{"Basic", "Basic !!!", "Basic dXNlcjE="} {
+		_, _, ok = ParseHeader(header)
+		assert.Assert(t, !ok, header)
+	}
+}
+
+func TestAuthenticator(t *testing.T) {
+	a := &Authenticator{Users: []User{{UserName: "user1", Password: "pass1", Roles: []string{"admin"}}}}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	_, err := a.Authenticate(r)
+	assert.Equal(t, auth.ErrNoCredentials, err)
+
+	r.SetBasicAuth("user1", "wrong")
+	_, err = a.Authenticate(r)
+	assert.Equal(t, auth.ErrInvalidCredentials, err)
+
+	r.SetBasicAuth("user1", "pass1")
+	p, err := a.Authenticate(r)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &auth.Principal{Name: "user1", Scheme: "basic", Roles: []string{"admin"}}, p)
+
+	a.Proxy = true
+	_, err = a.Authenticate(r)
+	assert.Equal(t, auth.ErrNoCredentials, err)
+	assert.Equal(t, 407, a.UnauthorizedStatus())
+	assert.Equal(t, "Proxy-Authenticate", a.ChallengeHeader())
+
+	assert.Equal(t, Challenge, a.Challenge(err))
+	a.Realm = "Admin"
+	assert.Equal(t, `Basic realm="Admin"`, a.Challenge(err))
+}
+
+func TestSessions(t *testing.T) {
+	a := &Authenticator{
+		Users:    []User{{UserName: "user1", Password: "pass1", Roles: []string{"admin"}}},
+		Sessions: &session.Manager{Config: session.Config{Secrets: []string{"secret"}}},
+	}
+
+	r := httptest.NewRequest("POST", "/login", strings.NewReader("username=user1&password=wrong"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, err := a.Login(httptest.NewRecorder(), r)
+	assert.Equal(t, auth.ErrInvalidCredentials, err)
+
+	r = httptest.NewRequest("POST", "/login", strings.NewReader(`{"user_name":"user1","password":"pass1"}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	_, err = a.Login(w, r)
+	assert.NilError(t, err)
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(w.Result().Cookies()[0])
+	p, err := a.Authenticate(r)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &auth.Principal{Name: "user1", Scheme: "session", Roles: []string{"admin"}}, p)
+
+	// removed users lose their sessions
+	a.Users = []User{{UserName: "user2", Password: "pass2"}}
+	_, err = a.Authenticate(r)
+	assert.Equal(t, auth.ErrInvalidCredentials, err)
+
+	// basic credentials are still accepted
+	r.SetBasicAuth("user2", "pass2")
+	p, err = a.Authenticate(r)
+	assert.NilError(t, err)
+	assert.Equal(t, "basic", p.Scheme)
+
+	r = httptest.NewRequest("POST", "/login", nil)
+	r.SetBasicAuth("user2", "pass2")
+	_, err = (&Authenticator{Users: a.Users}).Login(httptest.NewRecorder(), r)
+	assert.Equal(t, ErrNoSessions, err)
+}
+
+func TestTokens(t *testing.T) {
+	a := &Authenticator{
+		Users:  []User{{UserName: "user1", Password: "pass1", Roles: []string{"admin"}}},
+		Tokens: &tokens.Service{Config: &tokens.Config{Keys: map[string]string{"k1": "secret"}}},
+	}
+
+	r := httptest.NewRequest("POST", "/token", strings.NewReader("grant_type=client_credentials"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.SetBasicAuth("user1", "wrong")
+	_, err := a.ExchangeToken(r)
+	assert.Equal(t, auth.ErrInvalidCredentials, err)
+
+	r.SetBasicAuth("user1", "pass1")
+	issued, err := a.ExchangeToken(r)
+	assert.NilError(t, err)
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+issued.AccessToken)
+	p, err := a.Authenticate(r)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"admin"}, p.Roles)
+
+	r.Header.Set("Authorization", "Bearer invalid")
+	_, err = a.Authenticate(r)
+	assert.Equal(t, tokens.Challenge, a.Challenge(err))
+
+	r = httptest.NewRequest("POST", "/token", strings.NewReader("grant_type=refresh_token&refresh_token="+issued.RefreshToken))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, err = a.ExchangeToken(r)
+	assert.NilError(t, err)
+
+	r = httptest.NewRequest("POST", "/token", strings.NewReader("grant_type=password"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, err = a.ExchangeToken(r)
+	assert.Equal(t, tokens.ErrUnsupportedGrant, err)
+
+	// removed users lose their access tokens
+	a.Users = []User{{UserName: "user2", Password: "pass2"}}
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+issued.AccessToken)
+	_, err = a.Authenticate(r)
+	assert.Equal(t, auth.ErrInvalidCredentials, err)
+}
+
+func TestTokensOfInactiveUsers(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	a := &Authenticator{
+		Users: []User{
+			{UserName: "user1", Password: "pass1"},
+			{UserName: "contractor", Password: "pass", ExpiresAt: time.Date(2025, 3, 1, 13, 0, 0, 0, time.UTC)},
+		},
+		Tokens: &tokens.Service{Config: &tokens.Config{Keys: map[string]string{"k1": "secret"}, AccessTTL: 24 * time.Hour}},
+		Now:    func() time.Time { return now },
+	}
+	bearer := func(user, password string) *http.Request {
+		r := httptest.NewRequest("POST", "/token", strings.NewReader("grant_type=client_credentials"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.SetBasicAuth(user, password)
+		issued, err := a.ExchangeToken(r)
+		assert.NilError(t, err)
+		r = httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", "Bearer "+issued.AccessToken)
+		return r
+	}
+	disabled, expired := bearer("user1", "pass1"), bearer("contractor", "pass")
+
+	_, err := a.Authenticate(disabled)
+	assert.NilError(t, err)
+	_, err = a.Authenticate(expired)
+	assert.NilError(t, err)
+
+	// the user is disabled after the token was issued
+	a.Users[0].Disabled = true
+	_, err = a.Authenticate(disabled)
+	assert.Equal(t, auth.ErrInvalidCredentials, err)
+
+	// the account expires before the token does
+	now = now.Add(2 * time.Hour)
+	_, err = a.Authenticate(expired)
+	assert.Equal(t, auth.ErrInvalidCredentials, err)
+}
+
+func TestTOTP(t *testing.T) {
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	a := &Authenticator{
+		Users: []User{
+			{UserName: "admin", Password: "pass1", TOTPSecret: secret},
+			{UserName: "user1", Password: "pass1"},
+		},
+	}
+
+	code, err := totp.Generate(secret, time.Now(), 30*time.Second, 6)
+	assert.NilError(t, err)
+
+	// users with a secret are rejected without a verifier
+	r := httptest.NewRequest("GET", "/", nil)
+	r.SetBasicAuth("admin", "pass1"+code)
+	_, err = a.Authenticate(r)
+	assert.Equal(t, auth.ErrInvalidCredentials, err)
+
+	a.TOTP = &totp.Verifier{Config: &totp.Config{}}
+
+	// a wrong password does not use up the code
+	r.SetBasicAuth("admin", "wrong"+code)
+	_, err = a.Authenticate(r)
+	assert.Equal(t, auth.ErrInvalidCredentials, err)
+
+	r.SetBasicAuth("admin", "pass1"+code)
+	_, err = a.Authenticate(r)
+	assert.NilError(t, err)
+
+	// replay
+	_, err = a.Authenticate(r)
+	assert.Equal(t, auth.ErrInvalidCredentials, err)
+
+	r.SetBasicAuth("admin", "pass1")
+	_, err = a.Authenticate(r)
+	assert.Equal(t, auth.ErrInvalidCredentials, err)
+
+	// users without a secret are not affected
+	r.SetBasicAuth("user1", "pass1")
+	_, err = a.Authenticate(r)
+	assert.NilError(t, err)
+}
+
+func TestCredentials(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	a := &Authenticator{
+		Users: []User{
+			{
+				UserName: "service",
+				Credentials: []Credential{
+					{Password: "old", NotAfter: time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)},
+					{Password: "new", NotBefore: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+				},
+			},
+			{UserName: "disabled", Password: "pass", Disabled: true},
+			{UserName: "contractor", Password: "pass", ExpiresAt: time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		Now: func() time.Time { return now },
+	}
+
+	// both passwords work while they overlap
+	assert.Assert(t, a.Find("service", "old") != nil)
+	assert.Assert(t, a.Find("service", "new") != nil)
+	// the empty Password is not a credential
+	assert.Assert(t, a.Find("service", "") == nil)
+
+	assert.Assert(t, a.Find("disabled", "pass") == nil)
+	assert.Assert(t, a.Lookup("disabled") == nil)
+	assert.Assert(t, a.Find("contractor", "pass") != nil)
+
+	now = time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	assert.Assert(t, a.Find("service", "old") == nil)
+	assert.Assert(t, a.Find("service", "new") != nil)
+	assert.Assert(t, a.Find("contractor", "pass") == nil)
+
+	now = time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	assert.Assert(t, a.Find("service", "new") == nil)
+}
+
+func TestAllowedCIDRs(t *testing.T) {
+	trusted, _ := clientip.ParseSet([]string{"10.0.0.0/8"})
+	a := &Authenticator{
+		Users:    []User{{UserName: "service", Password: "pass", AllowedCIDRs: []string{"192.168.0.0/16"}}},
+		ClientIP: clientip.Resolver{TrustedProxies: trusted},
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.SetBasicAuth("service", "pass")
+	r.RemoteAddr = "192.168.1.5:1234"
+	_, err := a.Authenticate(r)
+	assert.NilError(t, err)
+
+	r.RemoteAddr = "203.0.113.7:1234"
+	r.Header.Set("X-Forwarded-For", "192.168.1.5")
+	_, err = a.Authenticate(r)
+	assert.Equal(t, auth.ErrInvalidCredentials, err)
+
+	// behind a trusted proxy
+	r.RemoteAddr = "10.0.0.1:1234"
+	_, err = a.Authenticate(r)
+	assert.NilError(t, err)
+}
+
+func TestAccessWindows(t *testing.T) {
+	now := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC) // monday
+	a := &Authenticator{
+		Users: []User{
+			{
+				UserName:     "contractor",
+				Password:     "pass",
+				Roles:        []string{"contractor"},
+				AccessWindow: &schedule.Window{Days: []string{"mon", "tue", "wed", "thu", "fri"}},
+			},
+			{UserName: "admin", Password: "pass"},
+		},
+		Rules: []rules.Rule{
+			{Condition: rules.Condition{Urls: []string{"/reports/*"}}, Roles: []string{"contractor"}, Window: &schedule.Window{Times: []string{"09:00-17:00"}}},
+		},
+		Now: func() time.Time { return now },
+	}
+
+	request := func(path, user string) *http.Request {
+		r := httptest.NewRequest("GET", path, nil)
+		r.SetBasicAuth(user, "pass")
+		return r
+	}
+	r := request("/reports/daily", "contractor")
+	assert.Assert(t, a.Decide(nil, r, "restricted_urls /reports/*").Allowed())
+
+	// the rule closes the endpoint in the evening
+	now = time.Date(2025, 3, 3, 18, 0, 0, 0, time.UTC)
+	d := a.Decide(nil, r, "restricted_urls /reports/*")
+	assert.Equal(t, 403, d.Status)
+	assert.Equal(t, auth.ErrForbidden, d.Err)
+	assert.Assert(t, a.Decide(nil, request("/profile", "contractor"), "restricted_urls /profile").Allowed())
+	// the rule is only for contractors
+	assert.Assert(t, a.Decide(nil, request("/reports/daily", "admin"), "restricted_urls /reports/*").Allowed())
+
+	// the user can not authenticate on saturday
+	now = time.Date(2025, 3, 8, 10, 0, 0, 0, time.UTC)
+	_, err := a.Authenticate(r)
+	assert.Equal(t, auth.ErrInvalidCredentials, err)
+}
+
+func TestDecide(t *testing.T) {
+	a := &Authenticator{
+		Users: []User{{UserName: "user1", Password: "pass1"}},
+		Realm: "Admin",
+		Rules: []rules.Rule{{Condition: rules.Condition{Methods: []string{"DELETE"}}, Users: []string{"user1"}, Window: &schedule.Window{Dates: []string{"2000-01-01"}}}},
+	}
+
+	r := httptest.NewRequest("GET", "/users", nil)
+	d := a.Decide(nil, r, "")
+	assert.Assert(t, d.Allowed())
+	assert.Equal(t, "allow", d.String())
+
+	d = a.Decide(nil, r, "realm Admin")
+	assert.Equal(t, 401, d.Status)
+	assert.Equal(t, auth.ErrNoCredentials, d.Err)
+	assert.Equal(t, `deny status=401 reason="auth: no credentials"`, d.String())
+
+	r.SetBasicAuth("user1", "pass1")
+	d = a.Decide(nil, r, "realm Admin")
+	assert.Assert(t, d.Allowed())
+	assert.Equal(t, "user1", d.User)
+	assert.Equal(t, "Admin", d.Principal.Attributes["realm"])
+
+	r.Method = "DELETE"
+	d = a.Decide(nil, r, "realm Admin")
+	assert.Equal(t, 403, d.Status)
+	assert.Equal(t, 0, d.Rule)
+}
+
+func TestHashedPasswords(t *testing.T) {
+	hash, err := passwd.Hash(passwd.Bcrypt, "pass1", passwd.Params{BcryptCost: 4})
+	assert.NilError(t, err)
+	a := &Authenticator{
+		Users: []User{
+			{UserName: "user1", Password: hash},
+			{UserName: "user2", Credentials: []Credential{{Password: hash}}},
+		},
+	}
+
+	assert.Assert(t, a.Find("user1", "pass1") != nil)
+	assert.Assert(t, a.Find("user1", hash) == nil)
+	assert.Assert(t, a.Find("user2", "pass1") != nil)
+	assert.Assert(t, a.Find("user2", "pass2") == nil)
+}
+
+type rehashStore struct {
+	calls [][3]string
+	err   error
+}
+
+func (s *rehashStore) Rehash(username, oldHash, newHash string) error {
+	s.calls = append(s.calls, [3]string{username, oldHash, newHash})
+	return s.err
+}
+
+func TestValidateUser(t *testing.T) {
+	policy := &passwd.Policy{}
+	assert.NilError(t, (&User{UserName: "user1", Password: "correct-Horse-battery-9"}).Validate(policy))
+
+	for _, u := range []User{
+		{UserName: "user1", Password: "$apr1$salt$hash"},
+		{UserName: "user1", Credentials: []Credential{{Password: "password"}}},
+		{UserName: "user1", TOTPSecret: "not base32!"},
+		{UserName: "user1", AllowedCIDRs: []string{"10.0.0.0/33"}},
+		{UserName: "user1", AccessWindow: &schedule.Window{Days: []string{"funday"}}},
+	} {
+		assert.Assert(t, u.Validate(policy) != nil, "%+v", u)
+	}
+	// plain passwords are not checked without a policy
+	assert.NilError(t, (&User{UserName: "user1", Password: "password"}).Validate(nil))
+}
+
+func TestRehash(t *testing.T) {
+	old, err := passwd.Hash(passwd.Bcrypt, "pass1", passwd.Params{BcryptCost: 4})
+	assert.NilError(t, err)
+	current, err := passwd.Hash(passwd.Bcrypt, "pass2", passwd.Params{BcryptCost: 5})
+	assert.NilError(t, err)
+	rotated, err := passwd.Hash(passwd.Bcrypt, "new-pass4", passwd.Params{BcryptCost: 4})
+	assert.NilError(t, err)
+
+	store := &rehashStore{}
+	var errs []error
+	a := &Authenticator{
+		Users: []User{
+			{UserName: "user1", Password: old},
+			{UserName: "user2", Password: current},
+			{UserName: "user3", Password: "pass3"},
+			{UserName: "user4", Credentials: []Credential{{Password: old}, {Password: rotated}}},
+		},
+		Rehash: &Rehasher{
+			Hasher:  passwd.Hasher{Params: passwd.Params{BcryptCost: 5}},
+			Store:   store,
+			OnError: func(err error) { errs = append(errs, err) },
+		},
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+
+	assert.Assert(t, a.Check(r, "user1", "wrong") == nil)
+	assert.Equal(t, 0, len(store.calls))
+
+	store.err = errors.New("read-only")
+	assert.Assert(t, a.Check(r, "user1", "pass1") != nil)
+	a.Rehash.Wait()
+	assert.Equal(t, 1, len(store.calls))
+	assert.ErrorContains(t, errs[0], `basic: rehash of user "user1": read-only`)
+
+	// failed upgrades are tried again, successful ones only once
+	store.err = nil
+	assert.Assert(t, a.Check(r, "user1", "pass1") != nil)
+	a.Rehash.Wait()
+	assert.Assert(t, a.Check(r, "user1", "pass1") != nil)
+	a.Rehash.Wait()
+	assert.Equal(t, 2, len(store.calls))
+	assert.Equal(t, "user1", store.calls[1][0])
+	assert.Equal(t, old, store.calls[1][1])
+	ok, err := passwd.Verify(store.calls[1][2], "pass1")
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	assert.Assert(t, !a.Rehash.Hasher.Outdated(store.calls[1][2]))
+
+	// current hashes and plain passwords are kept
+	assert.Assert(t, a.Check(r, "user2", "pass2") != nil)
+	assert.Assert(t, a.Check(r, "user3", "pass3") != nil)
+	a.Rehash.Wait()
+	assert.Equal(t, 2, len(store.calls))
+
+	// only the credential the password matched is upgraded
+	assert.Assert(t, a.Check(r, "user4", "new-pass4") != nil)
+	a.Rehash.Wait()
+	assert.Equal(t, 3, len(store.calls))
+	assert.DeepEqual(t, [2]string{"user4", rotated}, [2]string{store.calls[2][0], store.calls[2][1]})
+}
@@ -0,0 +1,95 @@
+package basic
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/rules"
+)
+
+// DecisionHeader carries the decision in report-only mode, e.g. `deny status=401 reason="auth: no credentials"`.
+const DecisionHeader = "X-Auth-Decision"
+
+// Decision is what the middleware decides about a request.
+type Decision struct {
+	Method string `json:"method"`
+	Host   string `json:"host"`
+	Path   string `json:"path"`
+	// Realm is the realm the request is authenticated in, empty for the users of the config.
+	Realm string `json:"realm,omitempty"`
+	// Restriction is why the request must be authenticated, e.g. "restricted_urls /admin/*" or "realm Admin".
+	// It is empty if the request need not be authenticated.
+	Restriction string `json:"restriction,omitempty"`
+	// User is the name of the authenticated caller, empty for anonymous requests.
+	User string `json:"user,omitempty"`
+	// Rule is the index of the access rule that rejected the request, -1 if no rule rejected it.
+	Rule int `json:"rule"`
+	// Status is 200 if the request is let through, 401 or 407 without valid credentials
+	// and 403 if an access rule rejects it.
+	Status int `json:"status"`
+	// Reason is the error text of a rejected request.
+	Reason string `json:"reason,omitempty"`
+
+	// Principal is the authenticated caller, nil for anonymous requests.
+	Principal *auth.Principal `json:"-"`
+	// Err is the authentication error or auth.ErrForbidden of a rejected request.
+	Err error `json:"-"`
+}
+
+// Allowed reports whether the request is let through.
+func (d *Decision) Allowed() bool {
+	return d.Status == http.StatusOK
+}
+
+// String formats the decision for DecisionHeader and logs.
+func (d *Decision) String() string {
+	if d.Allowed() {
+		return "allow"
+	}
+	return fmt.Sprintf("deny status=%d reason=%s", d.Status, strconv.Quote(d.Reason))
+}
+
+// Decide authenticates and authorizes the request the way the middlewares do, without writing a response.
+// Restriction is why the request must be authenticated, empty if it need not be, see rules.Policy.Explain.
+// If w is not nil, the idle timeout of the session cookie is extended.
+func (a *Authenticator) Decide(w http.ResponseWriter, r *http.Request, restriction string) *Decision {
+	d := &Decision{
+		Method:      r.Method,
+		Host:        r.Host,
+		Path:        r.URL.Path,
+		Realm:       a.Realm,
+		Restriction: restriction,
+		Rule:        -1,
+		Status:      http.StatusOK,
+	}
+
+	if restriction != "" {
+		var (
+			principal *auth.Principal
+			err       error
+		)
+		if w != nil {
+			principal, err = a.AuthenticateAndRefresh(w, r)
+		} else {
+			principal, err = a.Authenticate(r)
+		}
+		if err != nil {
+			d.Status, d.Err, d.Reason = a.UnauthorizedStatus(), err, err.Error()
+			return d
+		}
+		if a.Realm != "" {
+			if principal.Attributes == nil {
+				principal.Attributes = map[string]string{}
+			}
+			principal.Attributes["realm"] = a.Realm
+		}
+		d.Principal, d.User = principal, principal.Name
+	}
+
+	if i := rules.Denied(a.Rules, r, d.Principal, a.ClientIP.TrustedProxies, a.now()); i >= 0 {
+		d.Rule, d.Status, d.Err, d.Reason = i, http.StatusForbidden, auth.ErrForbidden, auth.ErrForbidden.Error()
+	}
+	return d
+}
@@ -0,0 +1,96 @@
+package basic
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/golanguzb70/middleware/internal/passwd"
+)
+
+// CredentialStore keeps the password hashes of users, e.g. in a users file or a database.
+type CredentialStore interface {
+	// Rehash replaces oldHash, the Password of the user or of one of its Credentials, with newHash,
+	// a hash of the same password made with the current algorithm and parameters.
+	Rehash(username, oldHash, newHash string) error
+}
+
+// Rehasher upgrades outdated password hashes of users on successful login, e.g. after the bcrypt cost
+// is raised or during a migration to argon2id. Users keep working with the old hash until they are reloaded.
+// The new hash is made and stored in the background, so the login does not wait for it.
+// Authenticators that only evaluate requests, e.g. in report-only mode or for a shadow config,
+// must not have a Rehasher, so they never write to the store.
+type Rehasher struct {
+	// Hasher is the algorithm and parameters hashes should have.
+	passwd.Hasher
+	// Store persists the new hashes.
+	Store CredentialStore `json:"-"`
+	// OnError is called with the errors of hashing and Store, in the goroutine of the upgrade.
+	// The hash is upgraded again on the next login.
+	OnError func(error) `json:"-"`
+
+	mu sync.Mutex
+	// done are the old hashes handed to Store, so that a hash is upgraded once and not on every request
+	done    map[string]bool
+	pending sync.WaitGroup
+}
+
+// Validate checks the algorithm, the bcrypt cost and that Store is set.
+func (h *Rehasher) Validate() error {
+	if err := h.Hasher.Validate(); err != nil {
+		return err
+	}
+	if h.Store == nil {
+		return errors.New("credential store is not set")
+	}
+	return nil
+}
+
+// rehash upgrades old, the stored hash the password of the user was verified against, if it is outdated.
+func (h *Rehasher) rehash(username, old, password string) {
+	if !h.Hasher.Outdated(old) || !h.start(old) {
+		return
+	}
+	h.pending.Add(1)
+	go func() {
+		defer h.pending.Done()
+		if err := h.store(username, old, password); err != nil {
+			h.mu.Lock()
+			delete(h.done, old)
+			h.mu.Unlock()
+			if h.OnError != nil {
+				h.OnError(err)
+			}
+		}
+	}()
+}
+
+// Wait waits for the upgrades in progress, e.g. before the process exits.
+func (h *Rehasher) Wait() {
+	h.pending.Wait()
+}
+
+// start marks the hash as being upgraded. It returns false if it is already.
+func (h *Rehasher) start(old string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.done[old] {
+		return false
+	}
+	if h.done == nil {
+		h.done = make(map[string]bool)
+	}
+	h.done[old] = true
+	return true
+}
+
+func (h *Rehasher) store(username, old, password string) error {
+	hash, err := h.Hasher.Hash(password)
+	if err != nil {
+		return fmt.Errorf("basic: rehash of user %q: %w", username, err)
+	}
+	if err := h.Store.Rehash(username, old, hash); err != nil {
+		return fmt.Errorf("basic: rehash of user %q: %w", username, err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+// Package clientip works with the network address of the client of a request.
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Set is a list of networks.
+type Set []netip.Prefix
+
+// ParseSet parses CIDRs such as "10.0.0.0/8". Single addresses are accepted too.
+func ParseSet(cidrs []string) (Set, error) {
+	set := make(Set, 0, len(cidrs))
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		if !strings.Contains(c, "/") {
+			addr, err := netip.ParseAddr(c)
+			if err != nil {
+				return nil, fmt.Errorf("clientip: invalid address %q", c)
+			}
+			set = append(set, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(c)
+		if err != nil {
+			return nil, fmt.Errorf("clientip: invalid network %q", c)
+		}
+		set = append(set, p.Masked())
+	}
+	return set, nil
+}
+
+// ParseValid is like ParseSet but invalid entries are skipped, so they never match.
+func ParseValid(cidrs []string) Set {
+	set := make(Set, 0, len(cidrs))
+	for _, c := range cidrs {
+		s, err := ParseSet([]string{c})
+		if err == nil {
+			set = append(set, s...)
+		}
+	}
+	return set
+}
+
+// Contains reports whether the address is in one of the networks.
+func (s Set) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range s {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Peer returns the address of the direct peer of the request.
+func Peer(r *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// Forwarding headers the address of the client may be read from.
+const (
+	XForwardedFor = "X-Forwarded-For"
+	Forwarded     = "Forwarded"
+)
+
+// Resolver finds the address of the client of a request that may have passed trusted proxies.
+type Resolver struct {
+	// TrustedProxies are the networks of the proxies that append the address of their peer to Header.
+	// Without trusted proxies the direct peer is the client and Header is ignored.
+	TrustedProxies Set
+	// Header is X-Forwarded-For (default) or Forwarded (RFC 7239).
+	Header string
+}
+
+// Client returns the address of the client. Addresses of the header are read
+// from right to left and the first one that is not a trusted proxy is the
+// client, so a client can not choose its address by sending the header itself.
+// It returns false if an address appended by a trusted proxy can not be parsed.
+func (res Resolver) Client(r *http.Request) (netip.Addr, bool) {
+	addr, ok := Peer(r)
+	if !ok || !res.TrustedProxies.Contains(addr) {
+		return addr, ok
+	}
+
+	hops := res.hops(r)
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := parseHop(hops[i])
+		if !ok {
+			return netip.Addr{}, false
+		}
+		addr = hop
+		if !res.TrustedProxies.Contains(addr) {
+			return addr, true
+		}
+	}
+	// every address is a trusted proxy, the first one is the client
+	return addr, true
+}
+
+func (res Resolver) hops(r *http.Request) []string {
+	var hops []string
+	if strings.EqualFold(res.Header, Forwarded) {
+		for _, value := range r.Header.Values(Forwarded) {
+			for _, element := range strings.Split(value, ",") {
+				for _, pair := range strings.Split(element, ";") {
+					k, v, _ := strings.Cut(strings.TrimSpace(pair), "=")
+					if strings.EqualFold(k, "for") {
+						hops = append(hops, strings.Trim(v, `"`))
+					}
+				}
+			}
+		}
+		return hops
+	}
+
+	for _, value := range r.Header.Values(XForwardedFor) {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// parseHop parses an address that may have a port and brackets, e.g.
+// 192.0.2.60:4711 or [2001:db8::1]:4711. Obfuscated identifiers and "unknown" are rejected.
+func parseHop(hop string) (netip.Addr, bool) {
+	if addrPort, err := netip.ParseAddrPort(hop); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(hop, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
@@ -0,0 +1,76 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestSet(t *testing.T) {
+	set, err := ParseSet([]string{"10.0.0.0/8", "192.168.1.7", "fd00::/8"})
+	assert.NilError(t, err)
+
+	assert.Assert(t, set.Contains(netip.MustParseAddr("10.1.2.3")))
+	assert.Assert(t, set.Contains(netip.MustParseAddr("::ffff:10.1.2.3")))
+	assert.Assert(t, set.Contains(netip.MustParseAddr("192.168.1.7")))
+	assert.Assert(t, !set.Contains(netip.MustParseAddr("192.168.1.8")))
+	assert.Assert(t, set.Contains(netip.MustParseAddr("fd12::1")))
+
+	_, err = ParseSet([]string{"10.0.0.0/33"})
+	assert.ErrorContains(t, err, "invalid network")
+
+	assert.Equal(t, 1, len(ParseValid([]string{"bad", "127.0.0.1"})))
+}
+
+func TestPeer(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "[::1]:1234"
+	addr, ok := Peer(r)
+	assert.Assert(t, ok)
+	assert.Equal(t, "::1", addr.String())
+
+	r.RemoteAddr = "pipe"
+	_, ok = Peer(r)
+	assert.Assert(t, !ok)
+}
+
+func TestResolver(t *testing.T) {
+	trusted, _ := ParseSet([]string{"10.0.0.0/8"})
+	res := Resolver{TrustedProxies: trusted}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "203.0.113.7:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	addr, ok := res.Client(r)
+	assert.Assert(t, ok)
+	assert.Equal(t, "203.0.113.7", addr.String(), "the header of an untrusted peer is ignored")
+
+	// the client sent a forged address, the trusted proxies appended the real ones
+	r.RemoteAddr = "10.0.0.2:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.7")
+	r.Header.Add("X-Forwarded-For", "10.0.0.1")
+	addr, ok = res.Client(r)
+	assert.Assert(t, ok)
+	assert.Equal(t, "203.0.113.7", addr.String())
+
+	r.Header.Set("X-Forwarded-For", "garbage")
+	_, ok = res.Client(r)
+	assert.Assert(t, !ok)
+
+	r.Header.Del("X-Forwarded-For")
+	addr, ok = res.Client(r)
+	assert.Assert(t, ok)
+	assert.Equal(t, "10.0.0.2", addr.String())
+
+	res.Header = Forwarded
+	r.Header.Set("Forwarded", `for=198.51.100.1;proto=https, for="[2001:db8::1]:4711"`)
+	addr, ok = res.Client(r)
+	assert.Assert(t, ok)
+	assert.Equal(t, "2001:db8::1", addr.String())
+
+	r.Header.Set("Forwarded", `for=_hidden`)
+	_, ok = res.Client(r)
+	assert.Assert(t, !ok)
+}
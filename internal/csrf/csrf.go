@@ -0,0 +1,331 @@
+// Package csrf protects cookie authenticated routes from cross-site request
+// forgery. It is shared by the gin and gorilla csrf middlewares.
+//
+// Two token patterns are supported:
+//
+//   - double submit: the token is kept in a cookie and the request must send
+//     the same value in a header or form field. With a secret the cookie value
+//     is signed, so a sibling subdomain can not plant its own cookie.
+//   - synchronizer: the token is an HMAC of the authenticated principal and a
+//     timestamp, so it is bound to the user without server side state.
+//
+// Unsafe requests are also checked for a same-origin Origin or Referer header.
+package csrf
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/rules"
+)
+
+// Token patterns.
+const (
+	DoubleSubmit = "double_submit"
+	Synchronizer = "synchronizer"
+)
+
+// Defaults of the names the token is sent with.
+const (
+	DefaultCookieName = "csrf_token"
+	DefaultHeaderName = "X-CSRF-Token"
+	DefaultFieldName  = "csrf_token"
+)
+
+var (
+	ErrMissingToken = errors.New("csrf: token is missing")
+	ErrInvalidToken = errors.New("csrf: token is invalid")
+	ErrOrigin       = errors.New("csrf: cross-origin request")
+	ErrNoSecret     = errors.New("csrf: synchronizer tokens require a secret")
+)
+
+// Protector checks unsafe requests.
+type Protector struct {
+	// Mode is DoubleSubmit (default) or Synchronizer.
+	Mode string
+	// Secret signs double submit cookies and synchronizer tokens. It is required for synchronizer tokens.
+	Secret string
+	// CookieName, HeaderName and FieldName are the names the token is sent with.
+	CookieName string
+	HeaderName string
+	FieldName  string
+	// TrustedOrigins may send requests besides the origin of the request host, e.g. https://app.example.com.
+	TrustedOrigins []string
+	// RequireOrigin rejects unsafe requests without Origin and Referer headers.
+	RequireOrigin bool
+	// ExemptUrls are not checked. The patterns are the same as in basicauth.
+	ExemptUrls []string
+	// ExemptAuthorizationSchemes are Authorization schemes browsers never send on their own. Default is Bearer.
+	// Basic is not exempt by default, because browsers resend cached Basic credentials.
+	ExemptAuthorizationSchemes []string
+	// MaxAge is the lifetime of synchronizer tokens and double submit cookies. Default is 12 hours.
+	MaxAge time.Duration
+	// InsecureCookie sends the double submit cookie over plain HTTP too.
+	InsecureCookie bool
+	// Now is used instead of time.Now when set.
+	Now func() time.Time
+}
+
+// Protect returns the token to render in pages and checks unsafe requests.
+// A double submit cookie is set on w when the request has no valid one.
+func (p *Protector) Protect(w http.ResponseWriter, r *http.Request) (string, error) {
+	token, err := p.token(w, r)
+	if err != nil {
+		return "", err
+	}
+	if p.exempt(r) {
+		return token, nil
+	}
+	if err := p.checkOrigin(r); err != nil {
+		return token, err
+	}
+
+	submitted := r.Header.Get(p.headerName())
+	if submitted == "" {
+		submitted = r.PostFormValue(p.fieldName())
+	}
+	if submitted == "" {
+		return token, ErrMissingToken
+	}
+	return token, p.verify(r, submitted)
+}
+
+func (p *Protector) token(w http.ResponseWriter, r *http.Request) (string, error) {
+	if p.Mode == Synchronizer {
+		if p.Secret == "" {
+			return "", ErrNoSecret
+		}
+		return p.synchronizerToken(principalName(r)), nil
+	}
+
+	if cookie, err := r.Cookie(p.cookieName()); err == nil && p.validCookie(cookie.Value) {
+		return cookie.Value, nil
+	}
+	value := randomString()
+	if p.Secret != "" {
+		value += "." + p.sign(value)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     p.cookieName(),
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(p.maxAge().Seconds()),
+		Secure:   !p.InsecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+	// the token is compared with the cookie of this request, so later checks see the new one
+	r.AddCookie(&http.Cookie{Name: p.cookieName(), Value: value})
+	return value, nil
+}
+
+func (p *Protector) verify(r *http.Request, submitted string) error {
+	if p.Mode == Synchronizer {
+		return p.verifySynchronizer(principalName(r), submitted)
+	}
+	cookie, err := r.Cookie(p.cookieName())
+	if err != nil || !p.validCookie(cookie.Value) {
+		return ErrInvalidToken
+	}
+	if subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(submitted)) != 1 {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+func (p *Protector) validCookie(value string) bool {
+	if value == "" {
+		return false
+	}
+	if p.Secret == "" {
+		return true
+	}
+	token, sig, ok := strings.Cut(value, ".")
+	return ok && hmac.Equal([]byte(sig), []byte(p.sign(token)))
+}
+
+// synchronizerToken is base64url(unix time || nonce || HMAC(principal, time, nonce)).
+func (p *Protector) synchronizerToken(principal string) string {
+	b := make([]byte, 8, 8+16+sha256.Size)
+	binary.BigEndian.PutUint64(b, uint64(p.now().Unix()))
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	b = append(b, nonce...)
+	b = append(b, p.mac(principal, b)...)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func (p *Protector) verifySynchronizer(principal, token string) error {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(b) != 8+16+sha256.Size {
+		return ErrInvalidToken
+	}
+	if !hmac.Equal(b[24:], p.mac(principal, b[:24])) {
+		return ErrInvalidToken
+	}
+	issued := time.Unix(int64(binary.BigEndian.Uint64(b[:8])), 0)
+	if p.now().Sub(issued) > p.maxAge() {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+func (p *Protector) mac(principal string, data []byte) []byte {
+	m := hmac.New(sha256.New, []byte(p.Secret))
+	m.Write([]byte(principal + "\n"))
+	m.Write(data)
+	return m.Sum(nil)
+}
+
+func (p *Protector) sign(value string) string {
+	m := hmac.New(sha256.New, []byte(p.Secret))
+	m.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+func (p *Protector) exempt(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	for _, pattern := range p.ExemptUrls {
+		if rules.MatchURL(pattern, r.URL.Path) {
+			return true
+		}
+	}
+	scheme, _, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if scheme == "" {
+		return false
+	}
+	schemes := p.ExemptAuthorizationSchemes
+	if schemes == nil {
+		schemes = []string{"Bearer"}
+	}
+	for _, s := range schemes {
+		if strings.EqualFold(s, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOrigin compares the host of Origin, or Referer if there is no Origin,
+// with the request host. TLS may end at a proxy, so schemes are not compared.
+func (p *Protector) checkOrigin(r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" || origin == "null" {
+		if referer := r.Header.Get("Referer"); referer != "" {
+			u, err := url.Parse(referer)
+			if err != nil {
+				return ErrOrigin
+			}
+			origin = u.Scheme + "://" + u.Host
+		}
+	}
+	if origin == "" || origin == "null" {
+		if p.RequireOrigin {
+			return ErrOrigin
+		}
+		return nil
+	}
+
+	for _, trusted := range p.TrustedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(trusted, "/"), origin) {
+			return nil
+		}
+	}
+	u, err := url.Parse(origin)
+	if err != nil || !strings.EqualFold(u.Host, r.Host) {
+		return ErrOrigin
+	}
+	return nil
+}
+
+func principalName(r *http.Request) string {
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		return principal.Scheme + ":" + principal.Name
+	}
+	return ""
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func (p *Protector) cookieName() string {
+	if p.CookieName != "" {
+		return p.CookieName
+	}
+	return DefaultCookieName
+}
+
+func (p *Protector) headerName() string {
+	if p.HeaderName != "" {
+		return p.HeaderName
+	}
+	return DefaultHeaderName
+}
+
+func (p *Protector) fieldName() string {
+	if p.FieldName != "" {
+		return p.FieldName
+	}
+	return DefaultFieldName
+}
+
+// TemplateField returns a hidden form input with the token.
+func (p *Protector) TemplateField(token string) template.HTML {
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(p.fieldName()) +
+		`" value="` + template.HTMLEscapeString(token) + `">`)
+}
+
+func (p *Protector) maxAge() time.Duration {
+	if p.MaxAge > 0 {
+		return p.MaxAge
+	}
+	return 12 * time.Hour
+}
+
+func (p *Protector) now() time.Time {
+	if p.Now != nil {
+		return p.Now()
+	}
+	return time.Now()
+}
+
+type contextKey struct{}
+
+type contextValue struct {
+	token string
+	field template.HTML
+}
+
+// NewContext returns a copy of ctx that carries the token of the request and its hidden form input.
+func NewContext(ctx context.Context, token string, field template.HTML) context.Context {
+	return context.WithValue(ctx, contextKey{}, contextValue{token: token, field: field})
+}
+
+// FromContext returns the token stored in ctx.
+func FromContext(ctx context.Context) string {
+	v, _ := ctx.Value(contextKey{}).(contextValue)
+	return v.token
+}
+
+// FieldFromContext returns the hidden form input stored in ctx.
+func FieldFromContext(ctx context.Context) template.HTML {
+	v, _ := ctx.Value(contextKey{}).(contextValue)
+	return v.field
+}
@@ -0,0 +1,147 @@
+package csrf
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"gotest.tools/assert"
+)
+
+// firstVisit returns the token and the cookie a safe request gets.
+func firstVisit(t *testing.T, p *Protector) (string, *http.Cookie) {
+	w := httptest.NewRecorder()
+	token, err := p.Protect(w, httptest.NewRequest("GET", "http://example.com/form", nil))
+	assert.NilError(t, err)
+	cookies := w.Result().Cookies()
+	assert.Equal(t, 1, len(cookies))
+	return token, cookies[0]
+}
+
+func TestDoubleSubmit(t *testing.T) {
+	for _, secret := range []string{"", "secret"} {
+		p := &Protector{Secret: secret}
+		token, cookie := firstVisit(t, p)
+		assert.Equal(t, token, cookie.Value)
+
+		// header
+		r := httptest.NewRequest("POST", "http://example.com/transfer", nil)
+		r.AddCookie(cookie)
+		r.Header.Set("X-CSRF-Token", token)
+		_, err := p.Protect(httptest.NewRecorder(), r)
+		assert.NilError(t, err)
+
+		// form field
+		r = httptest.NewRequest("POST", "http://example.com/transfer", strings.NewReader(url.Values{"csrf_token": {token}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.AddCookie(cookie)
+		_, err = p.Protect(httptest.NewRecorder(), r)
+		assert.NilError(t, err)
+
+		r = httptest.NewRequest("POST", "http://example.com/transfer", nil)
+		r.AddCookie(cookie)
+		_, err = p.Protect(httptest.NewRecorder(), r)
+		assert.Assert(t, errors.Is(err, ErrMissingToken))
+
+		r = httptest.NewRequest("POST", "http://example.com/transfer", nil)
+		r.Header.Set("X-CSRF-Token", token)
+		_, err = p.Protect(httptest.NewRecorder(), r)
+		assert.Assert(t, errors.Is(err, ErrInvalidToken))
+	}
+
+	// a planted cookie without signature is rejected
+	p := &Protector{Secret: "secret"}
+	r := httptest.NewRequest("POST", "http://example.com/transfer", nil)
+	r.AddCookie(&http.Cookie{Name: "csrf_token", Value: "planted"})
+	r.Header.Set("X-CSRF-Token", "planted")
+	_, err := p.Protect(httptest.NewRecorder(), r)
+	assert.Assert(t, errors.Is(err, ErrInvalidToken))
+}
+
+func TestSynchronizer(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	p := &Protector{Mode: Synchronizer, Secret: "secret", MaxAge: time.Hour, Now: func() time.Time { return now }}
+	alice := &auth.Principal{Name: "alice", Scheme: "session"}
+
+	r := httptest.NewRequest("GET", "http://example.com/form", nil)
+	r = r.WithContext(auth.NewContext(r.Context(), alice))
+	w := httptest.NewRecorder()
+	token, err := p.Protect(w, r)
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(w.Result().Cookies()))
+
+	post := func(principal *auth.Principal) error {
+		r := httptest.NewRequest("POST", "http://example.com/transfer", nil)
+		r.Header.Set("X-CSRF-Token", token)
+		r = r.WithContext(auth.NewContext(r.Context(), principal))
+		_, err := p.Protect(httptest.NewRecorder(), r)
+		return err
+	}
+	assert.NilError(t, post(alice))
+	assert.Assert(t, errors.Is(post(&auth.Principal{Name: "mallory", Scheme: "session"}), ErrInvalidToken))
+
+	now = now.Add(2 * time.Hour)
+	assert.Assert(t, errors.Is(post(alice), ErrInvalidToken))
+
+	_, err = (&Protector{Mode: Synchronizer}).Protect(httptest.NewRecorder(), r)
+	assert.Assert(t, errors.Is(err, ErrNoSecret))
+}
+
+func TestOrigin(t *testing.T) {
+	p := &Protector{TrustedOrigins: []string{"https://app.example.org"}}
+	token, cookie := firstVisit(t, p)
+
+	check := func(header, value string) error {
+		r := httptest.NewRequest("POST", "http://example.com/transfer", nil)
+		r.AddCookie(cookie)
+		r.Header.Set("X-CSRF-Token", token)
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		_, err := p.Protect(httptest.NewRecorder(), r)
+		return err
+	}
+
+	assert.NilError(t, check("Origin", "https://example.com"))
+	assert.NilError(t, check("Origin", "https://app.example.org"))
+	assert.NilError(t, check("Referer", "https://example.com/form"))
+	assert.NilError(t, check("", ""))
+	assert.Assert(t, errors.Is(check("Origin", "https://evil.example"), ErrOrigin))
+	assert.Assert(t, errors.Is(check("Referer", "https://evil.example/page"), ErrOrigin))
+
+	p.RequireOrigin = true
+	assert.Assert(t, errors.Is(check("", ""), ErrOrigin))
+}
+
+func TestExemptions(t *testing.T) {
+	p := &Protector{ExemptUrls: []string{"/webhooks/*"}}
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest("GET", "/transfer", nil),
+		httptest.NewRequest("HEAD", "/transfer", nil),
+		httptest.NewRequest("POST", "/webhooks/github", nil),
+		httptest.NewRequest("POST", "/api/transfer", nil),
+	} {
+		if r.URL.Path == "/api/transfer" {
+			r.Header.Set("Authorization", "Bearer token")
+		}
+		_, err := p.Protect(httptest.NewRecorder(), r)
+		assert.NilError(t, err, r.Method+" "+r.URL.Path)
+	}
+
+	// browsers resend cached Basic credentials, so they do not exempt a request
+	r := httptest.NewRequest("POST", "/transfer", nil)
+	r.SetBasicAuth("alice", "secret")
+	_, err := p.Protect(httptest.NewRecorder(), r)
+	assert.Assert(t, errors.Is(err, ErrMissingToken))
+}
+
+func TestTemplateField(t *testing.T) {
+	p := &Protector{FieldName: "_csrf"}
+	assert.Equal(t, `<input type="hidden" name="_csrf" value="a&lt;b">`, string(p.TemplateField("a<b")))
+}
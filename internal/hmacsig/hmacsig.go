@@ -0,0 +1,293 @@
+// Package hmacsig signs and verifies HTTP requests with a shared secret, in
+// the spirit of AWS Signature Version 4. It is shared by the gin and gorilla
+// hmacauth middlewares.
+//
+// The Authorization header of a signed request looks like
+//
+//	HMAC-SHA256 KeyId=partner1, SignedHeaders=content-type;host, Signature=<hex>
+//
+// and the request carries X-Timestamp (unix seconds), X-Nonce and
+// X-Content-SHA256 (hex SHA-256 of the body) headers. The signature is
+// HMAC-SHA256 of the canonical request:
+//
+//	METHOD
+//	escaped path
+//	canonical query, sorted by name and value
+//	name:value lines of the signed headers, sorted by name
+//	signed header names joined with ';'
+//	timestamp
+//	nonce
+//	hex SHA-256 of the body
+package hmacsig
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/reqbody"
+)
+
+// Scheme is the authorization scheme of signed requests.
+const Scheme = "HMAC-SHA256"
+
+// Headers of a signed request.
+const (
+	TimestampHeader = "X-Timestamp"
+	NonceHeader     = "X-Nonce"
+	DigestHeader    = "X-Content-SHA256"
+)
+
+var (
+	ErrMalformed     = errors.New("hmacsig: malformed signature")
+	ErrUnknownKey    = errors.New("hmacsig: unknown key id")
+	ErrSignature     = errors.New("hmacsig: signature mismatch")
+	ErrTimestamp     = errors.New("hmacsig: timestamp is outside of the allowed window")
+	ErrReplay        = errors.New("hmacsig: nonce was already used")
+	ErrDigest        = errors.New("hmacsig: body digest mismatch")
+	ErrBodyTooLarge  = reqbody.ErrTooLarge
+	ErrMissingHeader = errors.New("hmacsig: required header is not signed")
+)
+
+// Verifier checks signed requests.
+type Verifier struct {
+	// Keys maps key ids to secrets. Several keys may be active at once, e.g. during rotation.
+	Keys map[string]string
+	// RequiredHeaders must be in SignedHeaders, e.g. "host" and "content-type".
+	RequiredHeaders []string
+	// Window is the maximum difference between the timestamp and the current time. Default is five minutes.
+	Window time.Duration
+	// Nonces blocks replays. Default is an in-memory store.
+	Nonces NonceStore
+	// MaxBodySize limits the body that is read to compute the digest. Default is 10 MB.
+	MaxBodySize int64
+	// Now is used instead of time.Now when set.
+	Now func() time.Time
+
+	once sync.Once
+}
+
+// Authenticate verifies the signature of the request and returns the key id as principal.
+// The body is read to check its digest and is replaced, so handlers can still read it.
+func (v *Verifier) Authenticate(r *http.Request) (*auth.Principal, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, auth.ErrNoCredentials
+	}
+	scheme, params, ok := strings.Cut(header, " ")
+	if !ok || scheme != Scheme {
+		return nil, auth.ErrNoCredentials
+	}
+
+	keyID, signedHeaders, sig, err := parseAuthorization(params)
+	if err != nil {
+		return nil, err
+	}
+	secret, ok := v.Keys[keyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	for _, h := range v.RequiredHeaders {
+		if !contains(signedHeaders, strings.ToLower(h)) {
+			return nil, ErrMissingHeader
+		}
+	}
+
+	timestamp := r.Header.Get(TimestampHeader)
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	now := v.now()
+	signedAt := time.Unix(sec, 0)
+	if d := now.Sub(signedAt); d > v.window() || d < -v.window() {
+		return nil, ErrTimestamp
+	}
+
+	nonce := r.Header.Get(NonceHeader)
+	if nonce == "" {
+		return nil, ErrMalformed
+	}
+
+	digest, err := v.bodyDigest(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.Header.Get(DigestHeader) != digest {
+		return nil, ErrDigest
+	}
+
+	expected := signature(secret, canonicalRequest(r, signedHeaders, timestamp, nonce, digest))
+	if !hmac.Equal(expected, sig) {
+		return nil, ErrSignature
+	}
+
+	// the nonce is stored only for valid signatures, so nobody can burn nonces of others
+	if !v.nonces().Use(keyID, nonce, signedAt.Add(v.window())) {
+		return nil, ErrReplay
+	}
+
+	return &auth.Principal{Name: keyID, Scheme: "hmac", Attributes: map[string]string{"key_id": keyID}}, nil
+}
+
+// Challenge returns the WWW-Authenticate value.
+func (v *Verifier) Challenge(error) string {
+	return Scheme + ` realm="Authorization Required"`
+}
+
+// Sign adds the signature headers to a request. The body is read and replaced.
+// The host header is always signed.
+func Sign(r *http.Request, keyID, secret string, signedHeaders []string, t time.Time) error {
+	var body []byte
+	if r.Body != nil {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		r.Body.Close()
+		body = b
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	names := []string{"host"}
+	for _, h := range signedHeaders {
+		if h = strings.ToLower(h); !contains(names, h) {
+			names = append(names, h)
+		}
+	}
+	sort.Strings(names)
+
+	sum := sha256.Sum256(body)
+	digest := hex.EncodeToString(sum[:])
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	r.Header.Set(TimestampHeader, timestamp)
+	r.Header.Set(NonceHeader, hex.EncodeToString(nonce))
+	r.Header.Set(DigestHeader, digest)
+
+	sig := signature(secret, canonicalRequest(r, names, timestamp, r.Header.Get(NonceHeader), digest))
+	r.Header.Set("Authorization", fmt.Sprintf("%s KeyId=%s, SignedHeaders=%s, Signature=%s", Scheme, keyID, strings.Join(names, ";"), hex.EncodeToString(sig)))
+	return nil
+}
+
+func parseAuthorization(params string) (keyID string, signedHeaders []string, sig []byte, err error) {
+	for _, p := range strings.Split(params, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+		switch k {
+		case "KeyId":
+			keyID = v
+		case "SignedHeaders":
+			signedHeaders = strings.Split(strings.ToLower(v), ";")
+		case "Signature":
+			sig, err = hex.DecodeString(v)
+			if err != nil {
+				return "", nil, nil, ErrMalformed
+			}
+		}
+	}
+	if keyID == "" || len(sig) == 0 || len(signedHeaders) == 0 {
+		return "", nil, nil, ErrMalformed
+	}
+	return keyID, signedHeaders, sig, nil
+}
+
+func (v *Verifier) bodyDigest(r *http.Request) (string, error) {
+	body, err := reqbody.Read(r, v.MaxBodySize)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func canonicalRequest(r *http.Request, signedHeaders []string, timestamp, nonce, digest string) string {
+	var b strings.Builder
+	b.WriteString(r.Method + "\n")
+	b.WriteString(r.URL.EscapedPath() + "\n")
+	b.WriteString(canonicalQuery(r.URL.Query()) + "\n")
+	for _, h := range signedHeaders {
+		value := r.Header.Get(h)
+		if h == "host" {
+			value = r.Host
+		}
+		b.WriteString(h + ":" + strings.TrimSpace(value) + "\n")
+	}
+	b.WriteString(strings.Join(signedHeaders, ";") + "\n")
+	b.WriteString(timestamp + "\n")
+	b.WriteString(nonce + "\n")
+	b.WriteString(digest)
+	return b.String()
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), q[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func signature(secret, canonical string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(canonical))
+	return mac.Sum(nil)
+}
+
+func (v *Verifier) window() time.Duration {
+	if v.Window > 0 {
+		return v.Window
+	}
+	return 5 * time.Minute
+}
+
+func (v *Verifier) nonces() NonceStore {
+	v.once.Do(func() {
+		if v.Nonces == nil {
+			v.Nonces = &MemoryNonceStore{Now: v.Now}
+		}
+	})
+	return v.Nonces
+}
+
+func (v *Verifier) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+	return time.Now()
+}
+
+func contains(arr []string, val string) bool {
+	for _, item := range arr {
+		if item == val {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package hmacsig
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"gotest.tools/assert"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	v := &Verifier{
+		Keys:            map[string]string{"partner1": "secret1", "partner2": "secret2"},
+		RequiredHeaders: []string{"Host", "Content-Type"},
+		Now:             func() time.Time { return now },
+	}
+
+	req := httptest.NewRequest("POST", "/v1/orders?b=2&a=1", strings.NewReader(`{"id":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	assert.NilError(t, Sign(req, "partner2", "secret2", []string{"Content-Type"}, now))
+
+	principal, err := v.Authenticate(req)
+	assert.NilError(t, err)
+	assert.Equal(t, "partner2", principal.Name)
+	body, _ := io.ReadAll(req.Body)
+	assert.Equal(t, `{"id":1}`, string(body))
+
+	// the same request again is a replay
+	req.Body = io.NopCloser(strings.NewReader(`{"id":1}`))
+	_, err = v.Authenticate(req)
+	assert.Assert(t, errors.Is(err, ErrReplay))
+}
+
+// httptestRequest holds the request and the values it is signed with.
+type httptestRequest struct {
+	*http.Request
+	keyID  string
+	secret string
+	at     time.Time
+}
+
+func TestVerifyErrors(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	v := &Verifier{
+		Keys:            map[string]string{"partner1": "secret1"},
+		RequiredHeaders: []string{"host"},
+		Now:             func() time.Time { return now },
+	}
+
+	cases := []struct {
+		name string
+		// beforeSign modifies the values the request is signed with, otherwise the signed request is modified
+		beforeSign bool
+		modify     func(r *httptestRequest)
+		err        error
+	}{
+		{"no header", false, func(r *httptestRequest) { r.Header.Del("Authorization") }, auth.ErrNoCredentials},
+		{"unknown key", true, func(r *httptestRequest) { r.keyID = "partner9" }, ErrUnknownKey},
+		{"wrong secret", true, func(r *httptestRequest) { r.secret = "other" }, ErrSignature},
+		{"old timestamp", true, func(r *httptestRequest) { r.at = now.Add(-10 * time.Minute) }, ErrTimestamp},
+		{"changed path", false, func(r *httptestRequest) { r.URL.Path = "/v1/admin" }, ErrSignature},
+		{"changed query", false, func(r *httptestRequest) { r.URL.RawQuery = "a=2" }, ErrSignature},
+		{"changed body", false, func(r *httptestRequest) { r.Body = io.NopCloser(strings.NewReader("{}")) }, ErrDigest},
+		{"changed header", false, func(r *httptestRequest) { r.Header.Set("X-Request-Id", "2") }, ErrSignature},
+	}
+
+	for _, c := range cases {
+		r := &httptestRequest{Request: httptest.NewRequest("POST", "/v1/orders?a=1", strings.NewReader(`{"id":1}`)), keyID: "partner1", secret: "secret1", at: now}
+		r.Header.Set("X-Request-Id", "1")
+		if c.beforeSign {
+			c.modify(r)
+		}
+		assert.NilError(t, Sign(r.Request, r.keyID, r.secret, []string{"X-Request-Id"}, r.at))
+		if !c.beforeSign {
+			c.modify(r)
+		}
+		_, err := v.Authenticate(r.Request)
+		assert.Assert(t, errors.Is(err, c.err), "%s: %v", c.name, err)
+	}
+}
+
+func TestRequiredHeaders(t *testing.T) {
+	v := &Verifier{
+		Keys:            map[string]string{"partner1": "secret1"},
+		RequiredHeaders: []string{"Content-Type"},
+	}
+	req := httptest.NewRequest("GET", "/", nil)
+	assert.NilError(t, Sign(req, "partner1", "secret1", nil, time.Now()))
+	_, err := v.Authenticate(req)
+	assert.Assert(t, errors.Is(err, ErrMissingHeader))
+}
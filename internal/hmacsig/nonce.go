@@ -0,0 +1,9 @@
+package hmacsig
+
+import "github.com/golanguzb70/middleware/internal/nonce"
+
+// NonceStore remembers used nonces to block replayed requests.
+type NonceStore = nonce.Store
+
+// MemoryNonceStore keeps nonces in memory.
+type MemoryNonceStore = nonce.Memory
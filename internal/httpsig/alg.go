@@ -0,0 +1,122 @@
+package httpsig
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256" // registers SHA-256 for crypto.Hash
+	_ "crypto/sha512" // registers SHA-384 and SHA-512 for crypto.Hash
+	"math/big"
+)
+
+// Supported algorithms, named as in the HTTP Signature Algorithms registry.
+const (
+	HMACSHA256        = "hmac-sha256"
+	Ed25519           = "ed25519"
+	ECDSAP256SHA256   = "ecdsa-p256-sha256"
+	ECDSAP384SHA384   = "ecdsa-p384-sha384"
+	RSAPSSSHA512      = "rsa-pss-sha512"
+	RSAPKCS1v15SHA256 = "rsa-v1_5-sha256"
+)
+
+// Algorithms lists every algorithm this package can verify.
+var Algorithms = []string{HMACSHA256, Ed25519, ECDSAP256SHA256, ECDSAP384SHA384, RSAPSSSHA512, RSAPKCS1v15SHA256}
+
+// algorithmOf returns the algorithm a key is used with when the signature
+// has no alg parameter. RSA keys are taken as RSA-PSS.
+func algorithmOf(key interface{}) string {
+	switch k := key.(type) {
+	case []byte:
+		return HMACSHA256
+	case ed25519.PublicKey:
+		return Ed25519
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return ECDSAP256SHA256
+		case elliptic.P384():
+			return ECDSAP384SHA384
+		}
+	case *rsa.PublicKey:
+		return RSAPSSSHA512
+	}
+	return ""
+}
+
+// verify checks sig over base. The type of the key must match the
+// algorithm, so a public key can never be used as a HMAC secret.
+func verify(alg string, key interface{}, base string, sig []byte) error {
+	switch alg {
+	case HMACSHA256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return ErrUnsupportedAlg
+		}
+		mac := hmac.New(crypto.SHA256.New, secret)
+		mac.Write([]byte(base))
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return ErrInvalidSignature
+		}
+		return nil
+
+	case Ed25519:
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return ErrUnsupportedAlg
+		}
+		if !ed25519.Verify(pub, []byte(base), sig) {
+			return ErrInvalidSignature
+		}
+		return nil
+
+	case ECDSAP256SHA256, ECDSAP384SHA384:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || algorithmOf(pub) != alg {
+			return ErrUnsupportedAlg
+		}
+		h, size := crypto.SHA256, 32
+		if alg == ECDSAP384SHA384 {
+			h, size = crypto.SHA384, 48
+		}
+		if len(sig) != 2*size {
+			return ErrInvalidSignature
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, digest(h, base), r, s) {
+			return ErrInvalidSignature
+		}
+		return nil
+
+	case RSAPSSSHA512:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrUnsupportedAlg
+		}
+		opts := &rsa.PSSOptions{SaltLength: 64, Hash: crypto.SHA512}
+		if rsa.VerifyPSS(pub, crypto.SHA512, digest(crypto.SHA512, base), sig, opts) != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+
+	case RSAPKCS1v15SHA256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrUnsupportedAlg
+		}
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest(crypto.SHA256, base), sig) != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+	return ErrUnsupportedAlg
+}
+
+func digest(h crypto.Hash, base string) []byte {
+	d := h.New()
+	d.Write([]byte(base))
+	return d.Sum(nil)
+}
@@ -0,0 +1,152 @@
+package httpsig
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Component is a covered component of a signature, e.g. "@method",
+// "content-digest" or "@query-param" with Name "Pet".
+type Component struct {
+	ID   string
+	Name string
+}
+
+// ParseComponent parses "@method", "content-type" or "@query-param;name=Pet".
+func ParseComponent(s string) Component {
+	id, name, _ := strings.Cut(s, ";name=")
+	return Component{ID: strings.ToLower(strings.Trim(id, `"`)), Name: strings.Trim(name, `"`)}
+}
+
+func (c Component) String() string {
+	if c.Name != "" {
+		return c.ID + ";name=" + c.Name
+	}
+	return c.ID
+}
+
+func (c Component) item() item {
+	it := item{value: c.ID}
+	if c.Name != "" {
+		it.params = params{{name: "name", value: c.Name}}
+	}
+	return it
+}
+
+// componentOf reads a covered component of the signature input.
+func componentOf(it item) (Component, error) {
+	id, ok := it.value.(string)
+	if !ok || id == "" || id != strings.ToLower(id) {
+		return Component{}, ErrMalformed
+	}
+	c := Component{ID: id}
+	for _, p := range it.params {
+		name, ok := p.value.(string)
+		if p.name != "name" || c.ID != "@query-param" || !ok {
+			// sf, key, bs, req and tr parameters are not supported
+			return Component{}, ErrUnsupportedComponent
+		}
+		c.Name = name
+	}
+	if c.ID == "@query-param" && c.Name == "" {
+		return Component{}, ErrMalformed
+	}
+	return c, nil
+}
+
+// values returns the values of the component in the request. Only
+// @query-param may have several values, each is a line of the signature base.
+func (c Component) values(r *http.Request) ([]string, error) {
+	switch c.ID {
+	case "@method":
+		return []string{r.Method}, nil
+	case "@target-uri":
+		return []string{scheme(r) + "://" + authority(r) + r.URL.RequestURI()}, nil
+	case "@authority":
+		return []string{authority(r)}, nil
+	case "@scheme":
+		return []string{scheme(r)}, nil
+	case "@request-target":
+		return []string{r.URL.RequestURI()}, nil
+	case "@path":
+		path := r.URL.EscapedPath()
+		if path == "" {
+			path = "/"
+		}
+		return []string{path}, nil
+	case "@query":
+		return []string{"?" + r.URL.RawQuery}, nil
+	case "@query-param":
+		values, ok := r.URL.Query()[c.Name]
+		if !ok {
+			return nil, ErrMissingComponent
+		}
+		encoded := make([]string, len(values))
+		for i, v := range values {
+			encoded[i] = strings.ReplaceAll(url.QueryEscape(v), "+", "%20")
+		}
+		return encoded, nil
+	}
+	if strings.HasPrefix(c.ID, "@") {
+		return nil, ErrUnsupportedComponent
+	}
+
+	if c.ID == "host" {
+		return []string{r.Host}, nil
+	}
+	values := r.Header.Values(c.ID)
+	if len(values) == 0 {
+		return nil, ErrMissingComponent
+	}
+	trimmed := make([]string, len(values))
+	for i, v := range values {
+		trimmed[i] = strings.TrimSpace(v)
+	}
+	return []string{strings.Join(trimmed, ", ")}, nil
+}
+
+func scheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// authority is the lowercase host, the default port of the scheme is omitted.
+func authority(r *http.Request) string {
+	host := strings.ToLower(r.Host)
+	if r.TLS != nil {
+		return strings.TrimSuffix(host, ":443")
+	}
+	return strings.TrimSuffix(host, ":80")
+}
+
+// signatureBase builds the signature base of RFC 9421 section 2.5.
+func signatureBase(r *http.Request, input innerList) (string, []Component, error) {
+	var (
+		b          strings.Builder
+		components []Component
+	)
+	for _, it := range input.items {
+		c, err := componentOf(it)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, seen := range components {
+			if seen == c {
+				return "", nil, ErrMalformed
+			}
+		}
+		values, err := c.values(r)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, v := range values {
+			b.WriteString(c.item().serialize() + ": " + v + "\n")
+		}
+		components = append(components, c)
+	}
+	b.WriteString(`"@signature-params": ` + input.serialize())
+	return b.String(), components, nil
+}
@@ -0,0 +1,63 @@
+package httpsig
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/golanguzb70/middleware/internal/reqbody"
+)
+
+// ContentDigestHeader carries digests of the body (RFC 9530).
+const ContentDigestHeader = "Content-Digest"
+
+var digestAlgorithms = map[string]func([]byte) []byte{
+	"sha-256": func(b []byte) []byte { sum := sha256.Sum256(b); return sum[:] },
+	"sha-512": func(b []byte) []byte { sum := sha512.Sum512(b); return sum[:] },
+}
+
+// VerifyContentDigest checks every sha-256 and sha-512 digest of the
+// Content-Digest header against the body. Other algorithms are ignored, but
+// at least one digest must be supported. The body is replaced, so it can be
+// read again.
+func VerifyContentDigest(r *http.Request, maxBodySize int64) error {
+	members, err := parseDictionary(r.Header.Get(ContentDigestHeader))
+	if err != nil {
+		return ErrDigest
+	}
+	body, err := reqbody.Read(r, maxBodySize)
+	if err != nil {
+		return err
+	}
+
+	checked := false
+	for _, m := range members {
+		sum, ok := digestAlgorithms[m.name]
+		if !ok {
+			continue
+		}
+		if m.item == nil {
+			return ErrDigest
+		}
+		want, ok := m.item.value.([]byte)
+		if !ok || subtle.ConstantTimeCompare(sum(body), want) != 1 {
+			return ErrDigest
+		}
+		checked = true
+	}
+	if !checked {
+		return ErrDigest
+	}
+	return nil
+}
+
+// SetContentDigest sets the sha-256 Content-Digest header of the body.
+func SetContentDigest(r *http.Request) error {
+	body, err := reqbody.Read(r, -1)
+	if err != nil {
+		return err
+	}
+	r.Header.Set(ContentDigestHeader, "sha-256="+serializeBare(digestAlgorithms["sha-256"](body)))
+	return nil
+}
@@ -0,0 +1,312 @@
+// Package httpsig verifies HTTP Message Signatures (RFC 9421) of requests and
+// their Content-Digest (RFC 9530). It is shared by the gin and gorilla
+// httpsigauth middlewares.
+//
+// Keys are looked up by the keyid parameter of the signature in a local
+// jwt.Keyring, so HMAC secrets, PEM files and JWKS files can be used.
+package httpsig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/jwt"
+	"github.com/golanguzb70/middleware/internal/reqbody"
+)
+
+// Headers of a signed request.
+const (
+	SignatureInputHeader = "Signature-Input"
+	SignatureHeader      = "Signature"
+)
+
+var (
+	ErrMalformed            = errors.New("httpsig: malformed signature")
+	ErrUnsupportedAlg       = errors.New("httpsig: unsupported algorithm")
+	ErrUnsupportedComponent = errors.New("httpsig: unsupported component")
+	ErrMissingComponent     = errors.New("httpsig: component is missing from the request")
+	ErrNotCovered           = errors.New("httpsig: required component is not covered by the signature")
+	ErrKeyNotFound          = errors.New("httpsig: unknown key id")
+	ErrInvalidSignature     = errors.New("httpsig: invalid signature")
+	ErrExpired              = errors.New("httpsig: signature is expired")
+	ErrCreatedInFuture      = errors.New("httpsig: signature is created in the future")
+	ErrDigest               = errors.New("httpsig: content digest mismatch")
+	ErrBodyTooLarge         = reqbody.ErrTooLarge
+)
+
+// Verifier checks message signatures of requests.
+type Verifier struct {
+	// Keys holds the verification keys, the keyid parameter of a signature selects the key.
+	Keys *jwt.Keyring
+	// Algorithms restricts the accepted algorithms. Default is every supported algorithm.
+	Algorithms []string
+	// Label selects the signature to verify. Default is to accept any valid signature of the request.
+	Label string
+	// RequiredComponents must be covered by the signature, e.g. "@method", "@target-uri", "content-digest".
+	RequiredComponents []string
+	// RequireContentDigest requires requests with a body to have a Content-Digest covered by the signature.
+	// A Content-Digest header is always checked against the body when it is present.
+	RequireContentDigest bool
+	// MaxAge rejects signatures created longer ago. Zero means the created parameter is not required.
+	MaxAge time.Duration
+	// ClockSkew is tolerated when created and expires are checked.
+	ClockSkew time.Duration
+	// MaxBodySize limits the body read to check its digest. Default is 10 MB.
+	MaxBodySize int64
+	// Now is used instead of time.Now when set.
+	Now func() time.Time
+}
+
+// Signature is a verified signature of a request.
+type Signature struct {
+	Label      string
+	KeyID      string
+	Algorithm  string
+	Components []Component
+	Created    time.Time
+	Expires    time.Time
+	Nonce      string
+	Tag        string
+}
+
+// Verify checks the signatures of the request and returns the first valid one.
+// Without Signature-Input the error is auth.ErrNoCredentials.
+func (v *Verifier) Verify(r *http.Request) (*Signature, error) {
+	if r.Header.Get(SignatureInputHeader) == "" {
+		return nil, auth.ErrNoCredentials
+	}
+	inputs, err := parseDictionary(joinHeader(r, SignatureInputHeader))
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	sigs, err := parseDictionary(joinHeader(r, SignatureHeader))
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	if r.Header.Get(ContentDigestHeader) != "" {
+		if err := VerifyContentDigest(r, v.MaxBodySize); err != nil {
+			return nil, err
+		}
+	}
+
+	var firstErr error
+	for _, input := range inputs {
+		if v.Label != "" && input.name != v.Label {
+			continue
+		}
+		sig, err := v.verifyOne(r, input, sigs)
+		if err == nil {
+			return sig, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		firstErr = ErrMalformed
+	}
+	return nil, firstErr
+}
+
+func (v *Verifier) verifyOne(r *http.Request, input member, sigs []member) (*Signature, error) {
+	if input.inner == nil {
+		return nil, ErrMalformed
+	}
+	var value []byte
+	for _, m := range sigs {
+		if m.name == input.name && m.item != nil {
+			value, _ = m.item.value.([]byte)
+		}
+	}
+	if len(value) == 0 {
+		return nil, ErrMalformed
+	}
+
+	sig := &Signature{Label: input.name}
+	if err := sig.readParams(input.inner.params); err != nil {
+		return nil, err
+	}
+	if sig.KeyID == "" {
+		return nil, ErrKeyNotFound
+	}
+	if err := v.checkTime(sig); err != nil {
+		return nil, err
+	}
+
+	base, components, err := signatureBase(r, *input.inner)
+	if err != nil {
+		return nil, err
+	}
+	sig.Components = components
+	if err := v.checkCoverage(r, components); err != nil {
+		return nil, err
+	}
+
+	if v.Keys == nil {
+		return nil, ErrKeyNotFound
+	}
+	keys, err := v.Keys.ByID(sig.KeyID)
+	if err != nil {
+		if errors.Is(err, jwt.ErrKeyNotFound) {
+			return nil, ErrKeyNotFound
+		}
+		return nil, err
+	}
+
+	err = ErrUnsupportedAlg
+	for _, key := range keys {
+		alg := sig.Algorithm
+		if alg == "" {
+			alg = algorithmOf(key.Key)
+		}
+		if !v.allowed(alg) {
+			continue
+		}
+		if err = verify(alg, key.Key, base, value); err == nil {
+			sig.Algorithm = alg
+			return sig, nil
+		}
+	}
+	return nil, err
+}
+
+func (sig *Signature) readParams(ps params) error {
+	for _, p := range ps {
+		var ok bool
+		switch p.name {
+		case "created", "expires":
+			var n int64
+			if n, ok = p.value.(int64); ok {
+				if p.name == "created" {
+					sig.Created = time.Unix(n, 0)
+				} else {
+					sig.Expires = time.Unix(n, 0)
+				}
+			}
+		case "keyid":
+			sig.KeyID, ok = p.value.(string)
+		case "alg":
+			sig.Algorithm, ok = p.value.(string)
+		case "nonce":
+			sig.Nonce, ok = p.value.(string)
+		case "tag":
+			sig.Tag, ok = p.value.(string)
+		default:
+			ok = true
+		}
+		if !ok {
+			return fmt.Errorf("%w: parameter %s", ErrMalformed, p.name)
+		}
+	}
+	return nil
+}
+
+func (v *Verifier) checkTime(sig *Signature) error {
+	now := time.Now()
+	if v.Now != nil {
+		now = v.Now()
+	}
+	if !sig.Expires.IsZero() && now.After(sig.Expires.Add(v.ClockSkew)) {
+		return ErrExpired
+	}
+	if sig.Created.IsZero() {
+		if v.MaxAge > 0 {
+			return fmt.Errorf("%w: created parameter is required", ErrMalformed)
+		}
+		return nil
+	}
+	if sig.Created.After(now.Add(v.ClockSkew)) {
+		return ErrCreatedInFuture
+	}
+	if v.MaxAge > 0 && now.Sub(sig.Created) > v.MaxAge+v.ClockSkew {
+		return ErrExpired
+	}
+	return nil
+}
+
+func (v *Verifier) checkCoverage(r *http.Request, components []Component) error {
+	required := v.RequiredComponents
+	if v.RequireContentDigest && r.ContentLength != 0 {
+		required = append([]string{"content-digest"}, required...)
+	}
+	for _, s := range required {
+		want := ParseComponent(s)
+		covered := false
+		for _, c := range components {
+			if c == want {
+				covered = true
+			}
+		}
+		if !covered {
+			return fmt.Errorf("%w: %s", ErrNotCovered, want)
+		}
+	}
+	return nil
+}
+
+func (v *Verifier) allowed(alg string) bool {
+	if len(v.Algorithms) == 0 {
+		return contains(Algorithms, alg)
+	}
+	return contains(v.Algorithms, alg)
+}
+
+// Authenticate verifies the request and returns the key id as principal.
+func (v *Verifier) Authenticate(r *http.Request) (*auth.Principal, error) {
+	sig, err := v.Verify(r)
+	if err != nil {
+		return nil, err
+	}
+	return Principal(sig), nil
+}
+
+// Challenge returns the WWW-Authenticate value.
+func (v *Verifier) Challenge(error) string {
+	return `Signature realm="Authorization Required"`
+}
+
+// Principal converts a verified signature to a principal named by the key id.
+func Principal(sig *Signature) *auth.Principal {
+	return &auth.Principal{
+		Name:   sig.KeyID,
+		Scheme: "httpsig",
+		Attributes: map[string]string{
+			"key_id":    sig.KeyID,
+			"label":     sig.Label,
+			"algorithm": sig.Algorithm,
+		},
+	}
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries the verified signature.
+func NewContext(ctx context.Context, sig *Signature) context.Context {
+	return context.WithValue(ctx, contextKey{}, sig)
+}
+
+// FromContext returns the verified signature stored in ctx.
+func FromContext(ctx context.Context) (*Signature, bool) {
+	sig, ok := ctx.Value(contextKey{}).(*Signature)
+	return sig, ok
+}
+
+// joinHeader joins several header lines of a dictionary field.
+func joinHeader(r *http.Request, name string) string {
+	return strings.Join(r.Header.Values(name), ", ")
+}
+
+func contains(arr []string, val string) bool {
+	for _, item := range arr {
+		if item == val {
+			return true
+		}
+	}
+	return false
+}
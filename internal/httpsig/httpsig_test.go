@@ -0,0 +1,208 @@
+package httpsig
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/jwt"
+	"gotest.tools/assert"
+)
+
+// rfcRequest is the example request of RFC 9421 appendix B.2.
+func rfcRequest() *http.Request {
+	req := httptest.NewRequest("POST", "http://example.com/foo?param=Value&Pet=dog", strings.NewReader(`{"hello": "world"}`))
+	req.Header.Set("Date", "Tue, 20 Apr 2021 02:07:55 GMT")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Digest", "sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:")
+	req.Header.Set("Content-Length", "18")
+	return req
+}
+
+func TestRFCVectors(t *testing.T) {
+	secret, _ := base64.StdEncoding.DecodeString("uzvJfB4u3N0Jy4T7NZ75MDVcr8zSTInedJtkgcu46YW4XByzNJjxBdtjUkdJPBtbmHhIDi6pcl8jsasjlTMtDQ==")
+	pemFile := filepath.Join(t.TempDir(), "ed25519.pem")
+	assert.NilError(t, os.WriteFile(pemFile, []byte(`-----BEGIN PUBLIC KEY-----
+MCowBQYDK2VwAyEAJrQLj5P/89iXES9+vFgrIy29clF9CC/oPPsw3c5D0bs=
+-----END PUBLIC KEY-----
+`), 0o600))
+
+	v := &Verifier{
+		Keys: &jwt.Keyring{
+			Secrets:  map[string]string{"test-shared-secret": string(secret)},
+			PEMFiles: map[string]string{"test-key-ed25519": pemFile},
+		},
+		Now: func() time.Time { return time.Unix(1618884473, 0) },
+	}
+
+	// B.2.5 signing a request using hmac-sha256
+	req := rfcRequest()
+	req.Header.Set("Signature-Input", `sig-b25=("date" "@authority" "content-type");created=1618884473;keyid="test-shared-secret"`)
+	req.Header.Set("Signature", `sig-b25=:pxcQw6G3AjtMBQjwo8XzkZf/bws5LelbaMk5rGIGtE8=:`)
+	sig, err := v.Verify(req)
+	assert.NilError(t, err)
+	assert.Equal(t, "test-shared-secret", sig.KeyID)
+	assert.Equal(t, HMACSHA256, sig.Algorithm)
+
+	// B.2.6 signing a request using ed25519
+	req = rfcRequest()
+	req.Header.Set("Signature-Input", `sig-b26=("date" "@method" "@path" "@authority" "content-type" "content-length");created=1618884473;keyid="test-key-ed25519"`)
+	req.Header.Set("Signature", `sig-b26=:wqcAqbmYJ2ji2glfAMaRy4gruYYnx2nEFN2HN6jrnDnQCK1u02Gb04v9EDgwUPiu4A0w6vuQv5lIp5WPpBKRCw==:`)
+	principal, err := v.Authenticate(req)
+	assert.NilError(t, err)
+	assert.Equal(t, "test-key-ed25519", principal.Name)
+	assert.Equal(t, Ed25519, principal.Attributes["algorithm"])
+
+	req.Header.Set("Date", "Tue, 20 Apr 2021 02:07:56 GMT")
+	_, err = v.Verify(req)
+	assert.Assert(t, errors.Is(err, ErrInvalidSignature))
+}
+
+func TestContentDigest(t *testing.T) {
+	req := rfcRequest()
+	assert.NilError(t, VerifyContentDigest(req, 0))
+	body, _ := io.ReadAll(req.Body)
+	assert.Equal(t, `{"hello": "world"}`, string(body))
+
+	req = rfcRequest()
+	req.Header.Set("Content-Digest", "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:, md5=:AAAA:")
+	assert.NilError(t, VerifyContentDigest(req, 0))
+
+	req = rfcRequest()
+	req.Body = io.NopCloser(strings.NewReader(`{"hello": "there"}`))
+	assert.Assert(t, errors.Is(VerifyContentDigest(req, 0), ErrDigest))
+
+	req = rfcRequest()
+	req.Header.Set("Content-Digest", "md5=:AAAA:")
+	assert.Assert(t, errors.Is(VerifyContentDigest(req, 0), ErrDigest))
+
+	req = rfcRequest()
+	assert.Assert(t, errors.Is(VerifyContentDigest(req, 5), ErrBodyTooLarge))
+}
+
+func TestSignAndVerify(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	dir := t.TempDir()
+	writeKey := func(name string, pub interface{}) string {
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		assert.NilError(t, err)
+		path := filepath.Join(dir, name)
+		assert.NilError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+		return path
+	}
+
+	now := time.Unix(1700000000, 0)
+	v := &Verifier{
+		Keys: &jwt.Keyring{
+			Secrets: map[string]string{"hmac": "secret"},
+			PEMFiles: map[string]string{
+				"ed":  writeKey("ed.pem", edKey.Public()),
+				"ec":  writeKey("ec.pem", &ecKey.PublicKey),
+				"rsa": writeKey("rsa.pem", &rsaKey.PublicKey),
+			},
+		},
+		RequiredComponents:   []string{"@method", "@target-uri"},
+		RequireContentDigest: true,
+		MaxAge:               5 * time.Minute,
+		Now:                  func() time.Time { return now },
+	}
+
+	components := []string{"@method", "@target-uri", "content-digest", "@query-param;name=id"}
+	signers := []Signer{
+		{KeyID: "hmac", Algorithm: HMACSHA256, Key: []byte("secret")},
+		{KeyID: "ed", Algorithm: Ed25519, Key: edKey},
+		{KeyID: "ec", Algorithm: ECDSAP256SHA256, Key: ecKey},
+		{KeyID: "rsa", Algorithm: RSAPSSSHA512, Key: rsaKey},
+		{KeyID: "rsa", Algorithm: RSAPKCS1v15SHA256, Key: rsaKey},
+	}
+	for _, s := range signers {
+		s.Components = components
+		req := httptest.NewRequest("PUT", "https://api.example.com/v1/orders?id=7", strings.NewReader(`{"id":7}`))
+		assert.NilError(t, s.Sign(req, now))
+
+		sig, err := v.Verify(req)
+		assert.NilError(t, err, s.Algorithm)
+		assert.Equal(t, s.KeyID, sig.KeyID)
+		assert.Equal(t, s.Algorithm, sig.Algorithm)
+
+		req.URL.RawQuery = "id=8"
+		_, err = v.Verify(req)
+		assert.Assert(t, errors.Is(err, ErrInvalidSignature), s.Algorithm)
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	v := &Verifier{
+		Keys:                 &jwt.Keyring{Secrets: map[string]string{"hmac": "secret"}},
+		RequiredComponents:   []string{"@method"},
+		RequireContentDigest: true,
+		MaxAge:               time.Minute,
+		Now:                  func() time.Time { return now },
+	}
+	signer := Signer{KeyID: "hmac", Algorithm: HMACSHA256, Key: []byte("secret"), Components: []string{"@method", "@path", "content-digest"}}
+
+	req := httptest.NewRequest("POST", "/v1/orders", strings.NewReader("{}"))
+	_, err := v.Verify(req)
+	assert.Assert(t, errors.Is(err, auth.ErrNoCredentials))
+
+	cases := []struct {
+		name   string
+		signer Signer
+		at     time.Time
+		err    error
+	}{
+		{"old", signer, now.Add(-2 * time.Minute), ErrExpired},
+		{"future", signer, now.Add(time.Minute), ErrCreatedInFuture},
+		{"unknown key", Signer{KeyID: "other", Algorithm: HMACSHA256, Key: []byte("secret"), Components: signer.Components}, now, ErrKeyNotFound},
+		{"wrong secret", Signer{KeyID: "hmac", Algorithm: HMACSHA256, Key: []byte("guess"), Components: signer.Components}, now, ErrInvalidSignature},
+		{"method not covered", Signer{KeyID: "hmac", Algorithm: HMACSHA256, Key: []byte("secret"), Components: []string{"@path", "content-digest"}}, now, ErrNotCovered},
+		{"digest not covered", Signer{KeyID: "hmac", Algorithm: HMACSHA256, Key: []byte("secret"), Components: []string{"@method"}}, now, ErrNotCovered},
+		{"expires", Signer{KeyID: "hmac", Algorithm: HMACSHA256, Key: []byte("secret"), Components: signer.Components, Expires: time.Second}, now.Add(-30 * time.Second), ErrExpired},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("POST", "/v1/orders", strings.NewReader("{}"))
+		assert.NilError(t, c.signer.Sign(req, c.at))
+		_, err := v.Verify(req)
+		assert.Assert(t, errors.Is(err, c.err), "%s: %v", c.name, err)
+	}
+
+	// an algorithm must not turn a key into another kind of key
+	v.Algorithms = []string{Ed25519}
+	req = httptest.NewRequest("POST", "/v1/orders", strings.NewReader("{}"))
+	assert.NilError(t, signer.Sign(req, now))
+	_, err = v.Verify(req)
+	assert.Assert(t, errors.Is(err, ErrUnsupportedAlg))
+}
+
+func TestParseDictionary(t *testing.T) {
+	members, err := parseDictionary(`sig1=("@method" "@query-param";name="Pet");created=1;keyid="k", sig2=:AAE=:, flag`)
+	assert.NilError(t, err)
+	assert.Equal(t, 3, len(members))
+	assert.Equal(t, `("@method" "@query-param";name="Pet");created=1;keyid="k"`, members[0].inner.serialize())
+	assert.DeepEqual(t, []byte{0, 1}, members[1].item.value)
+	assert.Equal(t, true, members[2].item.value)
+
+	for _, s := range []string{`sig1=("@method"`, `sig1=:!!:`, `Sig=1`, `a=1,`, `a="x`} {
+		_, err := parseDictionary(s)
+		assert.Assert(t, err != nil, s)
+	}
+}
@@ -0,0 +1,352 @@
+package httpsig
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+// This file holds the subset of Structured Field Values (RFC 8941) used by
+// Signature-Input, Signature and Content-Digest headers. Decimals are not
+// supported, they are not used by those headers.
+
+// token is a bare token, e.g. sha-256 in "sha-256=:...:".
+type token string
+
+type param struct {
+	name  string
+	value interface{}
+}
+
+type params []param
+
+func (p params) get(name string) (interface{}, bool) {
+	for _, item := range p {
+		if item.name == name {
+			return item.value, true
+		}
+	}
+	return nil, false
+}
+
+// item is a bare item (string, int64, bool, []byte or token) with parameters.
+type item struct {
+	value  interface{}
+	params params
+}
+
+type innerList struct {
+	items  []item
+	params params
+}
+
+// member is an item or an inner list of a dictionary.
+type member struct {
+	name  string
+	item  *item
+	inner *innerList
+}
+
+type sfParser struct {
+	s string
+	i int
+}
+
+// parseDictionary parses a dictionary. Later members replace earlier ones
+// with the same name, as the RFC requires.
+func parseDictionary(s string) ([]member, error) {
+	p := &sfParser{s: s}
+	var members []member
+	p.skipSpaces()
+	for !p.done() {
+		name, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		m := member{name: name}
+		if p.peek() == '=' {
+			p.i++
+			if p.peek() == '(' {
+				list, err := p.innerList()
+				if err != nil {
+					return nil, err
+				}
+				m.inner = &list
+			} else {
+				it, err := p.item()
+				if err != nil {
+					return nil, err
+				}
+				m.item = &it
+			}
+		} else {
+			ps, err := p.params()
+			if err != nil {
+				return nil, err
+			}
+			m.item = &item{value: true, params: ps}
+		}
+
+		replaced := false
+		for i := range members {
+			if members[i].name == name {
+				members[i] = m
+				replaced = true
+			}
+		}
+		if !replaced {
+			members = append(members, m)
+		}
+
+		p.skipOWS()
+		if p.done() {
+			break
+		}
+		if p.peek() != ',' {
+			return nil, ErrMalformed
+		}
+		p.i++
+		p.skipOWS()
+		if p.done() {
+			return nil, ErrMalformed
+		}
+	}
+	return members, nil
+}
+
+func (p *sfParser) innerList() (innerList, error) {
+	var list innerList
+	p.i++ // (
+	for !p.done() {
+		p.skipSpaces()
+		if p.peek() == ')' {
+			p.i++
+			ps, err := p.params()
+			if err != nil {
+				return list, err
+			}
+			list.params = ps
+			return list, nil
+		}
+		it, err := p.item()
+		if err != nil {
+			return list, err
+		}
+		list.items = append(list.items, it)
+		if c := p.peek(); c != ' ' && c != ')' {
+			return list, ErrMalformed
+		}
+	}
+	return list, ErrMalformed
+}
+
+func (p *sfParser) item() (item, error) {
+	v, err := p.bareItem()
+	if err != nil {
+		return item{}, err
+	}
+	ps, err := p.params()
+	if err != nil {
+		return item{}, err
+	}
+	return item{value: v, params: ps}, nil
+}
+
+func (p *sfParser) params() (params, error) {
+	var ps params
+	for p.peek() == ';' {
+		p.i++
+		p.skipSpaces()
+		name, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		var v interface{} = true
+		if p.peek() == '=' {
+			p.i++
+			if v, err = p.bareItem(); err != nil {
+				return nil, err
+			}
+		}
+		ps = append(ps, param{name: name, value: v})
+	}
+	return ps, nil
+}
+
+func (p *sfParser) bareItem() (interface{}, error) {
+	c := p.peek()
+	switch {
+	case c == '"':
+		return p.str()
+	case c == ':':
+		return p.bytes()
+	case c == '?':
+		if p.i+1 < len(p.s) && (p.s[p.i+1] == '0' || p.s[p.i+1] == '1') {
+			v := p.s[p.i+1] == '1'
+			p.i += 2
+			return v, nil
+		}
+		return nil, ErrMalformed
+	case c == '-' || isDigit(c):
+		return p.integer()
+	case isAlpha(c) || c == '*':
+		start := p.i
+		for !p.done() && isTokenChar(p.s[p.i]) {
+			p.i++
+		}
+		return token(p.s[start:p.i]), nil
+	}
+	return nil, ErrMalformed
+}
+
+func (p *sfParser) str() (string, error) {
+	var b strings.Builder
+	p.i++ // "
+	for !p.done() {
+		c := p.s[p.i]
+		p.i++
+		switch {
+		case c == '\\':
+			if p.done() || (p.s[p.i] != '"' && p.s[p.i] != '\\') {
+				return "", ErrMalformed
+			}
+			b.WriteByte(p.s[p.i])
+			p.i++
+		case c == '"':
+			return b.String(), nil
+		case c < 0x20 || c > 0x7e:
+			return "", ErrMalformed
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", ErrMalformed
+}
+
+func (p *sfParser) bytes() ([]byte, error) {
+	p.i++ // :
+	end := strings.IndexByte(p.s[p.i:], ':')
+	if end < 0 {
+		return nil, ErrMalformed
+	}
+	b, err := base64.StdEncoding.DecodeString(p.s[p.i : p.i+end])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	p.i += end + 1
+	return b, nil
+}
+
+func (p *sfParser) integer() (int64, error) {
+	start := p.i
+	if p.peek() == '-' {
+		p.i++
+	}
+	for !p.done() && isDigit(p.s[p.i]) {
+		p.i++
+	}
+	if p.peek() == '.' || p.i-start > 16 {
+		return 0, ErrMalformed
+	}
+	n, err := strconv.ParseInt(p.s[start:p.i], 10, 64)
+	if err != nil {
+		return 0, ErrMalformed
+	}
+	return n, nil
+}
+
+func (p *sfParser) key() (string, error) {
+	start := p.i
+	if c := p.peek(); !(c >= 'a' && c <= 'z') && c != '*' {
+		return "", ErrMalformed
+	}
+	for !p.done() {
+		c := p.s[p.i]
+		if !(c >= 'a' && c <= 'z') && !isDigit(c) && c != '_' && c != '-' && c != '.' && c != '*' {
+			break
+		}
+		p.i++
+	}
+	return p.s[start:p.i], nil
+}
+
+func (p *sfParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.s[p.i]
+}
+
+func (p *sfParser) done() bool { return p.i >= len(p.s) }
+
+func (p *sfParser) skipSpaces() {
+	for p.peek() == ' ' {
+		p.i++
+	}
+}
+
+func (p *sfParser) skipOWS() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.i++
+	}
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isAlpha(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+
+func isTokenChar(c byte) bool {
+	return isAlpha(c) || isDigit(c) || strings.IndexByte("!#$%&'*+-.^_`|~:/", c) >= 0
+}
+
+// serialize writes the inner list as it is covered by @signature-params.
+func (l innerList) serialize() string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for i, it := range l.items {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(it.serialize())
+	}
+	b.WriteByte(')')
+	b.WriteString(l.params.serialize())
+	return b.String()
+}
+
+func (it item) serialize() string {
+	return serializeBare(it.value) + it.params.serialize()
+}
+
+func (ps params) serialize() string {
+	var b strings.Builder
+	for _, p := range ps {
+		b.WriteByte(';')
+		b.WriteString(p.name)
+		if v, ok := p.value.(bool); ok && v {
+			continue
+		}
+		b.WriteByte('=')
+		b.WriteString(serializeBare(p.value))
+	}
+	return b.String()
+}
+
+func serializeBare(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		if v {
+			return "?1"
+		}
+		return "?0"
+	case []byte:
+		return ":" + base64.StdEncoding.EncodeToString(v) + ":"
+	case token:
+		return string(v)
+	}
+	return ""
+}
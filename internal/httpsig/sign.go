@@ -0,0 +1,114 @@
+package httpsig
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Signer signs requests. It is meant for clients written in Go and for tests.
+type Signer struct {
+	// Label of the signature. Default is "sig1".
+	Label string
+	KeyID string
+	// Algorithm of the key, one of Algorithms.
+	Algorithm string
+	// Key is a []byte secret, ed25519.PrivateKey, *ecdsa.PrivateKey or *rsa.PrivateKey.
+	Key interface{}
+	// Components to cover, e.g. "@method", "@target-uri", "content-digest", "@query-param;name=id".
+	Components []string
+	// Expires sets the expires parameter relative to created when positive.
+	Expires time.Duration
+	Nonce   string
+}
+
+// Sign adds Signature-Input and Signature headers to the request. If
+// content-digest is covered and the header is not set yet, it is computed.
+func (s Signer) Sign(r *http.Request, created time.Time) error {
+	list := innerList{}
+	for _, spec := range s.Components {
+		c := ParseComponent(spec)
+		if c.ID == "content-digest" && r.Header.Get(ContentDigestHeader) == "" {
+			if err := SetContentDigest(r); err != nil {
+				return err
+			}
+		}
+		list.items = append(list.items, c.item())
+	}
+	list.params = append(list.params, param{name: "created", value: created.Unix()})
+	if s.Expires > 0 {
+		list.params = append(list.params, param{name: "expires", value: created.Add(s.Expires).Unix()})
+	}
+	if s.Nonce != "" {
+		list.params = append(list.params, param{name: "nonce", value: s.Nonce})
+	}
+	list.params = append(list.params, param{name: "keyid", value: s.KeyID}, param{name: "alg", value: s.Algorithm})
+
+	base, _, err := signatureBase(r, list)
+	if err != nil {
+		return err
+	}
+	sig, err := sign(s.Algorithm, s.Key, base)
+	if err != nil {
+		return err
+	}
+
+	label := s.Label
+	if label == "" {
+		label = "sig1"
+	}
+	r.Header.Set(SignatureInputHeader, label+"="+list.serialize())
+	r.Header.Set(SignatureHeader, label+"="+serializeBare(sig))
+	return nil
+}
+
+func sign(alg string, key interface{}, base string) ([]byte, error) {
+	switch k := key.(type) {
+	case []byte:
+		if alg != HMACSHA256 {
+			break
+		}
+		mac := hmac.New(crypto.SHA256.New, k)
+		mac.Write([]byte(base))
+		return mac.Sum(nil), nil
+
+	case ed25519.PrivateKey:
+		if alg != Ed25519 {
+			break
+		}
+		return ed25519.Sign(k, []byte(base)), nil
+
+	case *ecdsa.PrivateKey:
+		if alg != algorithmOf(&k.PublicKey) {
+			break
+		}
+		h, size := crypto.SHA256, 32
+		if alg == ECDSAP384SHA384 {
+			h, size = crypto.SHA384, 48
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest(h, base))
+		if err != nil {
+			return nil, err
+		}
+		sig := make([]byte, 2*size)
+		r.FillBytes(sig[:size])
+		s.FillBytes(sig[size:])
+		return sig, nil
+
+	case *rsa.PrivateKey:
+		switch alg {
+		case RSAPSSSHA512:
+			opts := &rsa.PSSOptions{SaltLength: 64, Hash: crypto.SHA512}
+			return rsa.SignPSS(rand.Reader, k, crypto.SHA512, digest(crypto.SHA512, base), opts)
+		case RSAPKCS1v15SHA256:
+			return rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest(crypto.SHA256, base))
+		}
+	}
+	return nil, errors.New("httpsig: key does not fit the algorithm")
+}
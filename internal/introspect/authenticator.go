@@ -0,0 +1,87 @@
+package introspect
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+)
+
+// ScopeError is returned when an active token misses required scopes.
+type ScopeError struct {
+	Scopes []string
+}
+
+func (e *ScopeError) Error() string {
+	return "introspect: token requires scopes " + strings.Join(e.Scopes, " ")
+}
+
+func (e *ScopeError) Is(target error) bool {
+	return target == auth.ErrForbidden
+}
+
+// Authenticator checks bearer tokens with the introspection endpoint and
+// enforces the scopes required for the request.
+type Authenticator struct {
+	Client *Client
+	// RequiredScopes maps url patterns to scopes, see RequiredScopes function.
+	RequiredScopes map[string][]string
+}
+
+// Check returns the introspection response of the bearer token of the request.
+// Endpoint errors are returned as errors, so requests are never let through without an answer.
+func (a *Authenticator) Check(r *http.Request) (*Response, error) {
+	token, ok := auth.BearerToken(r.Header.Get("Authorization"))
+	if !ok {
+		return nil, auth.ErrNoCredentials
+	}
+
+	resp, err := a.Client.Introspect(r.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := RequiredScopes(a.RequiredScopes, r.Method, r.URL.Path)
+	if !resp.HasScopes(scopes...) {
+		return nil, &ScopeError{Scopes: scopes}
+	}
+	return resp, nil
+}
+
+// Authenticate returns the subject of the token as principal.
+func (a *Authenticator) Authenticate(r *http.Request) (*auth.Principal, error) {
+	resp, err := a.Check(r)
+	if err != nil {
+		return nil, err
+	}
+	return Principal(resp), nil
+}
+
+// Challenge returns the WWW-Authenticate value for an error of Check.
+func (a *Authenticator) Challenge(err error) string {
+	var scopeErr *ScopeError
+	switch {
+	case err == nil || errors.Is(err, auth.ErrNoCredentials):
+		return `Bearer realm="Authorization Required"`
+	case errors.As(err, &scopeErr):
+		return `Bearer realm="Authorization Required", error="insufficient_scope", scope="` + strings.Join(scopeErr.Scopes, " ") + `"`
+	}
+	return `Bearer realm="Authorization Required", error="invalid_token"`
+}
+
+// Principal returns the principal of an active token. The name is the sub
+// member of the response, or username if there is no subject.
+func Principal(r *Response) *auth.Principal {
+	p := &auth.Principal{Name: r.Subject, Scheme: "bearer", Attributes: map[string]string{}}
+	if p.Name == "" {
+		p.Name = r.Username
+	}
+	if r.ClientID != "" {
+		p.Attributes["client_id"] = r.ClientID
+	}
+	if r.Scope != "" {
+		p.Attributes["scope"] = r.Scope
+	}
+	return p
+}
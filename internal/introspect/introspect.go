@@ -0,0 +1,244 @@
+// Package introspect implements an OAuth 2.0 Token Introspection (RFC 7662)
+// client with a cache of active tokens. It is shared by the gin and gorilla
+// introspectauth middlewares.
+package introspect
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInactive is returned for tokens the authorization server reports as not active.
+	ErrInactive = errors.New("introspect: token is not active")
+	// ErrEndpoint is returned when the introspection endpoint can not be used.
+	// Requests must be denied in this case.
+	ErrEndpoint = errors.New("introspect: introspection endpoint failed")
+)
+
+// Response is an introspection response.
+type Response struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	ID        string   `json:"jti,omitempty"`
+	// Raw holds every member of the response, including the extension ones.
+	Raw map[string]interface{} `json:"-"`
+}
+
+// Scopes returns the space separated scope member as a list.
+func (r *Response) Scopes() []string {
+	return strings.Fields(r.Scope)
+}
+
+// HasScopes reports whether the token was granted all the given scopes.
+func (r *Response) HasScopes(scopes ...string) bool {
+	granted := r.Scopes()
+	for _, s := range scopes {
+		found := false
+		for _, g := range granted {
+			if g == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Audience is the aud member which may be a string or an array of strings.
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = Audience{s}
+		return nil
+	}
+	var arr []string
+	if err := json.Unmarshal(b, &arr); err != nil {
+		return err
+	}
+	*a = arr
+	return nil
+}
+
+// Client calls the introspection endpoint and caches active responses until they expire.
+type Client struct {
+	// Endpoint is the introspection endpoint url.
+	Endpoint string
+	// ClientID and ClientSecret authenticate the resource server at the endpoint with HTTP Basic auth.
+	ClientID     string
+	ClientSecret string
+	// HTTPClient is used to call the endpoint. Default client has 10 seconds timeout.
+	HTTPClient *http.Client
+	// MaxCacheTTL limits how long an active response is cached. Zero means until exp of the token.
+	// Responses without exp are not cached unless MaxCacheTTL is set.
+	MaxCacheTTL time.Duration
+	// MaxCacheEntries limits the size of the cache. Default is 10000.
+	MaxCacheEntries int
+	// Now is used instead of time.Now when set.
+	Now func() time.Time
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]entry
+}
+
+type entry struct {
+	resp    *Response
+	expires time.Time
+}
+
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// Introspect returns the response for an active token. ErrInactive is
+// returned for inactive and expired tokens, ErrEndpoint wraps every failure
+// of the endpoint.
+func (c *Client) Introspect(ctx context.Context, token string) (*Response, error) {
+	key := sha256.Sum256([]byte(token))
+	now := c.now()
+
+	c.mu.Lock()
+	e, ok := c.cache[key]
+	if ok && !now.Before(e.expires) {
+		delete(c.cache, key)
+		ok = false
+	}
+	c.mu.Unlock()
+	if ok {
+		return e.resp, nil
+	}
+
+	resp, err := c.call(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Active {
+		return nil, ErrInactive
+	}
+	if resp.ExpiresAt != 0 && !now.Before(time.Unix(resp.ExpiresAt, 0)) {
+		return nil, ErrInactive
+	}
+	if resp.NotBefore != 0 && now.Before(time.Unix(resp.NotBefore, 0)) {
+		return nil, ErrInactive
+	}
+
+	c.store(key, resp, now)
+	return resp, nil
+}
+
+func (c *Client) call(ctx context.Context, token string) (*Response, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEndpoint, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = defaultHTTPClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEndpoint, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d", ErrEndpoint, res.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEndpoint, err)
+	}
+	var resp Response
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEndpoint, err)
+	}
+	if err := json.Unmarshal(body, &resp.Raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEndpoint, err)
+	}
+	return &resp, nil
+}
+
+func (c *Client) store(key [sha256.Size]byte, resp *Response, now time.Time) {
+	var expires time.Time
+	if resp.ExpiresAt != 0 {
+		expires = time.Unix(resp.ExpiresAt, 0)
+	}
+	if c.MaxCacheTTL > 0 && (expires.IsZero() || now.Add(c.MaxCacheTTL).Before(expires)) {
+		expires = now.Add(c.MaxCacheTTL)
+	}
+	if expires.IsZero() {
+		return
+	}
+
+	max := c.MaxCacheEntries
+	if max <= 0 {
+		max = 10000
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cache == nil {
+		c.cache = make(map[[sha256.Size]byte]entry)
+	}
+	if len(c.cache) >= max {
+		for k, e := range c.cache {
+			if !now.Before(e.expires) {
+				delete(c.cache, k)
+			}
+		}
+		if len(c.cache) >= max {
+			return
+		}
+	}
+	c.cache[key] = entry{resp: resp, expires: expires}
+}
+
+func (c *Client) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+type responseKey struct{}
+
+// NewContext returns a copy of ctx that carries the introspection response.
+func NewContext(ctx context.Context, r *Response) context.Context {
+	return context.WithValue(ctx, responseKey{}, r)
+}
+
+// FromContext returns the response stored in ctx by NewContext.
+func FromContext(ctx context.Context) (*Response, bool) {
+	r, ok := ctx.Value(responseKey{}).(*Response)
+	return r, ok
+}
@@ -0,0 +1,98 @@
+package introspect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func newServer(t *testing.T, calls *int32, responses map[string]map[string]interface{}) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "resource" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		resp, ok := responses[r.PostFormValue("token")]
+		if !ok {
+			resp = map[string]interface{}{"active": false}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestIntrospectCachesActiveTokens(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	var calls int32
+	srv := newServer(t, &calls, map[string]map[string]interface{}{
+		"good":    {"active": true, "scope": "read write", "sub": "user1", "aud": "api", "exp": now.Unix() + 60},
+		"noexp":   {"active": true, "sub": "user2"},
+		"expired": {"active": true, "exp": now.Unix() - 1},
+	})
+	c := &Client{Endpoint: srv.URL, ClientID: "resource", ClientSecret: "secret", Now: func() time.Time { return now }}
+
+	resp, err := c.Introspect(context.Background(), "good")
+	assert.NilError(t, err)
+	assert.Equal(t, "user1", resp.Subject)
+	assert.DeepEqual(t, Audience{"api"}, resp.Audience)
+	assert.Assert(t, resp.HasScopes("read", "write"))
+	assert.Assert(t, !resp.HasScopes("admin"))
+
+	_, err = c.Introspect(context.Background(), "good")
+	assert.NilError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// cached response expires with the token
+	now = now.Add(time.Minute)
+	_, err = c.Introspect(context.Background(), "good")
+	assert.Equal(t, ErrInactive, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	_, err = c.Introspect(context.Background(), "noexp")
+	assert.NilError(t, err)
+	_, err = c.Introspect(context.Background(), "noexp")
+	assert.NilError(t, err)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&calls))
+
+	_, err = c.Introspect(context.Background(), "expired")
+	assert.Equal(t, ErrInactive, err)
+
+	_, err = c.Introspect(context.Background(), "unknown")
+	assert.Equal(t, ErrInactive, err)
+}
+
+func TestIntrospectFailsClosed(t *testing.T) {
+	var calls int32
+	srv := newServer(t, &calls, nil)
+	c := &Client{Endpoint: srv.URL, ClientID: "resource", ClientSecret: "wrong"}
+
+	_, err := c.Introspect(context.Background(), "good")
+	assert.Assert(t, errors.Is(err, ErrEndpoint))
+
+	srv.Close()
+	c.ClientSecret = "secret"
+	_, err = c.Introspect(context.Background(), "good")
+	assert.Assert(t, errors.Is(err, ErrEndpoint))
+}
+
+func TestRequiredScopes(t *testing.T) {
+	routes := map[string][]string{
+		"/v1/user/*":           {"user:read"},
+		"DELETE /v1/user/{id}": {"user:write", "user:read"},
+		"/v1/admin":            {"admin"},
+	}
+	assert.DeepEqual(t, []string{"user:read"}, RequiredScopes(routes, "GET", "/v1/user/10"))
+	assert.DeepEqual(t, []string{"user:read", "user:write"}, RequiredScopes(routes, "DELETE", "/v1/user/10"))
+	assert.Assert(t, RequiredScopes(routes, "GET", "/v1/other") == nil)
+}
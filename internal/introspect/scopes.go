@@ -0,0 +1,36 @@
+package introspect
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/golanguzb70/middleware/internal/rules"
+)
+
+// RequiredScopes collects the scopes required for a request. Keys of routes
+// are url patterns as in basicauth.Config.RestrictedUrls, optionally prefixed
+// with a method, e.g. "/v1/user/*" or "DELETE /v1/user/{id}".
+func RequiredScopes(routes map[string][]string, method, path string) []string {
+	seen := map[string]bool{}
+	var scopes []string
+	for route, required := range routes {
+		pattern := route
+		if m, p, ok := strings.Cut(route, " "); ok {
+			if m != method {
+				continue
+			}
+			pattern = strings.TrimSpace(p)
+		}
+		if !rules.MatchURL(pattern, path) {
+			continue
+		}
+		for _, s := range required {
+			if !seen[s] {
+				seen[s] = true
+				scopes = append(scopes, s)
+			}
+		}
+	}
+	sort.Strings(scopes)
+	return scopes
+}
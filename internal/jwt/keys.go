@@ -0,0 +1,367 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+// Key is a verification key with its key id.
+type Key struct {
+	ID string
+	// Algorithm restricts the key to one algorithm. Empty means any algorithm the key type fits.
+	Algorithm string
+	// Key is a []byte secret, *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey.
+	Key interface{}
+}
+
+// Keyring holds the verification keys. Keys loaded from files are reloaded
+// when the files change, so keys can be rotated without a restart by adding
+// a new kid to the JWKS file before the issuer starts using it.
+type Keyring struct {
+	// Secrets maps key ids to HMAC secrets.
+	Secrets map[string]string
+	// PEMFiles maps key ids to PEM encoded public key or certificate files.
+	PEMFiles map[string]string
+	// JWKSFile is a path to a local JSON Web Key Set file.
+	JWKSFile string
+	// RefreshInterval is how often files are checked for changes. Default is one minute.
+	RefreshInterval time.Duration
+
+	mu        sync.RWMutex
+	keys      []Key
+	modTimes  map[string]time.Time
+	checkedAt time.Time
+	err       error
+	now       func() time.Time
+}
+
+// Load reads all the keys. It is called lazily by Lookup, call it directly
+// to surface configuration errors at startup.
+func (k *Keyring) Load() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.reload()
+}
+
+// Err returns the error of the last reload, or nil if it succeeded.
+// The keys loaded before a failed reload are still served.
+func (k *Keyring) Err() error {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.err
+}
+
+// reload loads the keys and records the error. A failed load keeps the keys
+// loaded before and is retried only after a back-off.
+func (k *Keyring) reload() error {
+	k.err = k.load()
+	if k.err != nil {
+		k.checkedAt = k.clock()
+	}
+	return k.err
+}
+
+func (k *Keyring) load() error {
+	var (
+		keys     []Key
+		modTimes = map[string]time.Time{}
+	)
+
+	for kid, secret := range k.Secrets {
+		keys = append(keys, Key{ID: kid, Key: []byte(secret)})
+	}
+
+	for kid, path := range k.PEMFiles {
+		b, mod, err := readFile(path)
+		if err != nil {
+			return err
+		}
+		pub, err := ParsePEM(b)
+		if err != nil {
+			return fmt.Errorf("jwt: %s: %w", path, err)
+		}
+		keys = append(keys, Key{ID: kid, Key: pub})
+		modTimes[path] = mod
+	}
+
+	if k.JWKSFile != "" {
+		b, mod, err := readFile(k.JWKSFile)
+		if err != nil {
+			return err
+		}
+		set, err := ParseJWKS(b)
+		if err != nil {
+			return fmt.Errorf("jwt: %s: %w", k.JWKSFile, err)
+		}
+		keys = append(keys, set...)
+		modTimes[k.JWKSFile] = mod
+	}
+
+	k.keys = keys
+	k.modTimes = modTimes
+	k.checkedAt = k.clock()
+	return nil
+}
+
+// Lookup returns the keys that may verify a token with given kid and alg.
+// Tokens without kid are checked against every key that fits the algorithm.
+func (k *Keyring) Lookup(kid, alg string) ([]Key, error) {
+	if err := k.refresh(kid); err != nil {
+		return nil, err
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var found []Key
+	for _, key := range k.keys {
+		if kid != "" && key.ID != kid {
+			continue
+		}
+		if key.Algorithm != "" && key.Algorithm != alg {
+			continue
+		}
+		if keyFits(alg, key.Key) {
+			found = append(found, key)
+		}
+	}
+	if len(found) == 0 {
+		return nil, ErrKeyNotFound
+	}
+	return found, nil
+}
+
+// ByID returns the keys with the key id whatever algorithm they are for.
+// It lets other signature formats reuse the keyring.
+func (k *Keyring) ByID(kid string) ([]Key, error) {
+	if err := k.refresh(kid); err != nil {
+		return nil, err
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var found []Key
+	for _, key := range k.keys {
+		if key.ID == kid {
+			found = append(found, key)
+		}
+	}
+	if len(found) == 0 {
+		return nil, ErrKeyNotFound
+	}
+	return found, nil
+}
+
+// refresh loads the keys on first use and reloads them when a watched file
+// has changed. An unknown kid triggers a check before the interval elapses.
+// A failed reload keeps serving the last good keys, such as while a JWKS file
+// is half written, and the files are checked again after the interval.
+func (k *Keyring) refresh(kid string) error {
+	k.mu.RLock()
+	loaded := k.modTimes != nil
+	due := k.clock().Sub(k.checkedAt) >= k.interval()
+	unknown := kid != "" && !k.has(kid)
+	k.mu.RUnlock()
+
+	if loaded && !due && !unknown {
+		return nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	// do not hit the file system on every request with an unknown kid
+	// or while the keys cannot be loaded
+	recent := k.clock().Sub(k.checkedAt) < time.Second
+	if k.modTimes == nil {
+		if k.err != nil && recent {
+			return k.err
+		}
+		return k.reload()
+	}
+	if recent {
+		return nil
+	}
+	for path, mod := range k.modTimes {
+		st, err := os.Stat(path)
+		if err != nil || !st.ModTime().Equal(mod) {
+			_ = k.reload()
+			return nil
+		}
+	}
+	k.checkedAt = k.clock()
+	return nil
+}
+
+func (k *Keyring) has(kid string) bool {
+	for _, key := range k.keys {
+		if key.ID == kid {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *Keyring) interval() time.Duration {
+	if k.RefreshInterval > 0 {
+		return k.RefreshInterval
+	}
+	return time.Minute
+}
+
+func (k *Keyring) clock() time.Time {
+	if k.now != nil {
+		return k.now()
+	}
+	return time.Now()
+}
+
+func readFile(path string) ([]byte, time.Time, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("jwt: %w", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("jwt: %w", err)
+	}
+	return b, st.ModTime(), nil
+}
+
+// ParsePEM parses the first PEM block of a public key, PKCS#1 RSA public key
+// or certificate.
+func ParsePEM(b []byte) (interface{}, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+	return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// ParseJWKS parses a JSON Web Key Set (RFC 7517). Keys with "use" other than
+// "sig" and unsupported key types are skipped.
+func ParseJWKS(b []byte) ([]Key, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, err
+	}
+
+	var keys []Key
+	for _, j := range set.Keys {
+		if j.Use != "" && j.Use != "sig" {
+			continue
+		}
+		key, err := j.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", j.Kid, err)
+		}
+		if key == nil {
+			continue
+		}
+		keys = append(keys, Key{ID: j.Kid, Algorithm: j.Alg, Key: key})
+	}
+	return keys, nil
+}
+
+func (j jwk) publicKey() (interface{}, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		if j.Crv != "P-256" {
+			return nil, nil
+		}
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !pub.Curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+		return pub, nil
+
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, nil
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+
+	case "oct":
+		k, err := base64.RawURLEncoding.DecodeString(j.K)
+		if err != nil {
+			return nil, err
+		}
+		return k, nil
+	}
+	return nil, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
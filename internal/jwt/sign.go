@@ -0,0 +1,31 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+)
+
+// SignHMAC returns a compact token with the claims signed by HS256, HS384 or HS512.
+// It is meant for tokens the service issues and verifies itself.
+func SignHMAC(alg, kid string, secret []byte, claims map[string]interface{}) (string, error) {
+	h, ok := map[string]crypto.Hash{HS256: crypto.SHA256, HS384: crypto.SHA384, HS512: crypto.SHA512}[alg]
+	if !ok {
+		return "", ErrUnsupportedAlg
+	}
+
+	header, err := json.Marshal(Header{Algorithm: alg, KeyID: kid, Type: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	mac := hmac.New(h.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
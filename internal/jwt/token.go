@@ -0,0 +1,167 @@
+// Package jwt verifies compact JWS tokens (RFC 7515, RFC 7519) using only the
+// standard library. It is shared by the gin and gorilla jwtauth middlewares.
+package jwt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformed        = errors.New("jwt: malformed token")
+	ErrUnsupportedAlg   = errors.New("jwt: unsupported algorithm")
+	ErrKeyNotFound      = errors.New("jwt: signing key not found")
+	ErrInvalidSignature = errors.New("jwt: invalid signature")
+	ErrExpired          = errors.New("jwt: token is expired")
+	ErrNotYetValid      = errors.New("jwt: token is not valid yet")
+	ErrIssuedInFuture   = errors.New("jwt: token is issued in the future")
+	ErrMissingExp       = errors.New("jwt: token has no expiration time")
+	ErrInvalidIssuer    = errors.New("jwt: invalid issuer")
+	ErrInvalidAudience  = errors.New("jwt: invalid audience")
+)
+
+// Header is the JOSE header of a token.
+type Header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid,omitempty"`
+	Type      string `json:"typ,omitempty"`
+}
+
+// Claims holds the registered claims of a token. All the claims, including
+// the registered ones, are also available in Raw.
+type Claims struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	ID        string
+	Raw       map[string]interface{}
+}
+
+// Get returns a claim by its name.
+func (c *Claims) Get(name string) (interface{}, bool) {
+	v, ok := c.Raw[name]
+	return v, ok
+}
+
+// String returns a claim by its name if it is a string.
+func (c *Claims) String(name string) string {
+	s, _ := c.Raw[name].(string)
+	return s
+}
+
+// Token is a parsed but not yet verified token.
+type Token struct {
+	Header    Header
+	Claims    Claims
+	Signature []byte
+	// signed is the "header.payload" part the signature is computed over.
+	signed string
+}
+
+// Parse splits a compact token and decodes its header and claims.
+// The signature is not checked, use Verifier for that.
+func Parse(raw string) (*Token, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	var t Token
+	if err := decodeSegment(parts[0], &t.Header); err != nil {
+		return nil, err
+	}
+
+	var payload map[string]interface{}
+	if err := decodeSegment(parts[1], &payload); err != nil {
+		return nil, err
+	}
+	claims, err := parseClaims(payload)
+	if err != nil {
+		return nil, err
+	}
+	t.Claims = claims
+
+	t.Signature, err = base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	t.signed = parts[0] + "." + parts[1]
+
+	return &t, nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return ErrMalformed
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return ErrMalformed
+	}
+	return nil
+}
+
+func parseClaims(raw map[string]interface{}) (Claims, error) {
+	c := Claims{Raw: raw}
+	var ok bool
+
+	if v, has := raw["iss"]; has {
+		if c.Issuer, ok = v.(string); !ok {
+			return c, ErrMalformed
+		}
+	}
+	if v, has := raw["sub"]; has {
+		if c.Subject, ok = v.(string); !ok {
+			return c, ErrMalformed
+		}
+	}
+	if v, has := raw["jti"]; has {
+		if c.ID, ok = v.(string); !ok {
+			return c, ErrMalformed
+		}
+	}
+
+	switch aud := raw["aud"].(type) {
+	case nil:
+	case string:
+		c.Audience = []string{aud}
+	case []interface{}:
+		for _, a := range aud {
+			s, ok := a.(string)
+			if !ok {
+				return c, ErrMalformed
+			}
+			c.Audience = append(c.Audience, s)
+		}
+	default:
+		return c, ErrMalformed
+	}
+
+	for name, dst := range map[string]*time.Time{"exp": &c.ExpiresAt, "nbf": &c.NotBefore, "iat": &c.IssuedAt} {
+		v, has := raw[name]
+		if !has {
+			continue
+		}
+		n, ok := v.(json.Number)
+		if !ok {
+			return c, ErrMalformed
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return c, ErrMalformed
+		}
+		sec := int64(f)
+		*dst = time.Unix(sec, int64((f-float64(sec))*1e9))
+	}
+
+	return c, nil
+}
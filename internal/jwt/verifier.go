@@ -0,0 +1,171 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+)
+
+// Verifier checks the signature and the registered claims of tokens.
+type Verifier struct {
+	Keys *Keyring
+	// Algorithms that are accepted. Default is every supported algorithm.
+	Algorithms []string
+	// Issuers that are accepted. Empty means any issuer.
+	Issuers []string
+	// Audiences that are accepted. The token must contain at least one of them. Empty means any audience.
+	Audiences []string
+	// ClockSkew is the leeway given when exp, nbf and iat are checked.
+	ClockSkew time.Duration
+	// RequireExpiration rejects tokens without exp claim. It is false by
+	// default, which accepts a token without exp for as long as its key is valid.
+	RequireExpiration bool
+	// Now is used instead of time.Now when set.
+	Now func() time.Time
+}
+
+// Verify parses the token, checks its signature and claims.
+func (v *Verifier) Verify(raw string) (*Claims, error) {
+	t, err := Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if !contains(v.algorithms(), t.Header.Algorithm) {
+		return nil, ErrUnsupportedAlg
+	}
+
+	keys, err := v.Keys.Lookup(t.Header.KeyID, t.Header.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ErrInvalidSignature
+	for _, key := range keys {
+		if err = verifySignature(t.Header.Algorithm, key.Key, t.signed, t.Signature); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := v.validate(&t.Claims); err != nil {
+		return nil, err
+	}
+	return &t.Claims, nil
+}
+
+// VerifyRequest verifies the bearer token of the Authorization header.
+// auth.ErrNoCredentials is returned if there is no bearer token.
+func (v *Verifier) VerifyRequest(r *http.Request) (*Claims, error) {
+	token, ok := auth.BearerToken(r.Header.Get("Authorization"))
+	if !ok {
+		return nil, auth.ErrNoCredentials
+	}
+	return v.Verify(token)
+}
+
+// Authenticate verifies the bearer token and returns the subject of the token as principal.
+func (v *Verifier) Authenticate(r *http.Request) (*auth.Principal, error) {
+	claims, err := v.VerifyRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	return Principal(claims), nil
+}
+
+// Challenge returns the WWW-Authenticate value for an error of VerifyRequest.
+func (v *Verifier) Challenge(err error) string {
+	if err == nil || errors.Is(err, auth.ErrNoCredentials) {
+		return `Bearer realm="Authorization Required"`
+	}
+	return `Bearer realm="Authorization Required", error="invalid_token", error_description="` + strings.TrimPrefix(err.Error(), "jwt: ") + `"`
+}
+
+// Principal returns the principal of a verified token.
+func Principal(c *Claims) *auth.Principal {
+	p := &auth.Principal{Name: c.Subject, Scheme: "bearer", Attributes: map[string]string{}}
+	if c.Issuer != "" {
+		p.Attributes["issuer"] = c.Issuer
+	}
+	if c.ID != "" {
+		p.Attributes["token_id"] = c.ID
+	}
+	return p
+}
+
+func (v *Verifier) validate(c *Claims) error {
+	now := time.Now()
+	if v.Now != nil {
+		now = v.Now()
+	}
+
+	if c.ExpiresAt.IsZero() {
+		if v.RequireExpiration {
+			return ErrMissingExp
+		}
+	} else if !now.Before(c.ExpiresAt.Add(v.ClockSkew)) {
+		return ErrExpired
+	}
+
+	if !c.NotBefore.IsZero() && now.Add(v.ClockSkew).Before(c.NotBefore) {
+		return ErrNotYetValid
+	}
+
+	if !c.IssuedAt.IsZero() && now.Add(v.ClockSkew).Before(c.IssuedAt) {
+		return ErrIssuedInFuture
+	}
+
+	if len(v.Issuers) > 0 && !contains(v.Issuers, c.Issuer) {
+		return ErrInvalidIssuer
+	}
+
+	if len(v.Audiences) > 0 {
+		matched := false
+		for _, a := range c.Audience {
+			if contains(v.Audiences, a) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return ErrInvalidAudience
+		}
+	}
+
+	return nil
+}
+
+func (v *Verifier) algorithms() []string {
+	if len(v.Algorithms) > 0 {
+		return v.Algorithms
+	}
+	return Algorithms
+}
+
+type claimsKey struct{}
+
+// NewContext returns a copy of ctx that carries the claims.
+func NewContext(ctx context.Context, c *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, c)
+}
+
+// FromContext returns the claims stored in ctx by NewContext.
+func FromContext(ctx context.Context) (*Claims, bool) {
+	c, ok := ctx.Value(claimsKey{}).(*Claims)
+	return c, ok
+}
+
+func contains(arr []string, val string) bool {
+	for _, item := range arr {
+		if item == val {
+			return true
+		}
+	}
+	return false
+}
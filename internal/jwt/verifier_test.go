@@ -0,0 +1,233 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func sign(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	t.Helper()
+	h, _ := json.Marshal(Header{Algorithm: alg, KeyID: kid, Type: "JWT"})
+	p, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(p)
+
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		sum := sha256.Sum256([]byte(signed))
+		sig, _ = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, sum[:])
+	case *ecdsa.PrivateKey:
+		sum := sha256.Sum256([]byte(signed))
+		r, s, _ := ecdsa.Sign(rand.Reader, k, sum[:])
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(k, []byte(signed))
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func writePEM(t *testing.T, pub interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	assert.NilError(t, err)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	assert.NilError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+	return path
+}
+
+func TestVerifyAlgorithms(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	v := &Verifier{Keys: &Keyring{
+		Secrets: map[string]string{"hs": "secret"},
+		PEMFiles: map[string]string{
+			"rs": writePEM(t, &rsaKey.PublicKey),
+			"es": writePEM(t, &ecKey.PublicKey),
+			"ed": writePEM(t, edKey.Public()),
+		},
+	}}
+	claims := map[string]interface{}{"sub": "user1", "exp": time.Now().Add(time.Hour).Unix()}
+
+	for _, tc := range []struct {
+		alg, kid string
+		key      interface{}
+	}{
+		{HS256, "hs", []byte("secret")},
+		{RS256, "rs", rsaKey},
+		{ES256, "es", ecKey},
+		{EdDSA, "ed", edKey},
+	} {
+		c, err := v.Verify(sign(t, tc.alg, tc.kid, tc.key, claims))
+		assert.NilError(t, err, tc.alg)
+		assert.Equal(t, "user1", c.Subject)
+	}
+
+	_, err := v.Verify(sign(t, HS256, "hs", []byte("wrong"), claims))
+	assert.Equal(t, ErrInvalidSignature, err)
+
+	// a public key must never be accepted as HMAC secret
+	_, err = v.Verify(sign(t, HS256, "rs", []byte("secret"), claims))
+	assert.Equal(t, ErrKeyNotFound, err)
+
+	_, err = v.Verify(sign(t, "none", "", nil, claims))
+	assert.Equal(t, ErrUnsupportedAlg, err)
+
+	v.Algorithms = []string{RS256}
+	_, err = v.Verify(sign(t, HS256, "hs", []byte("secret"), claims))
+	assert.Equal(t, ErrUnsupportedAlg, err)
+}
+
+func TestValidateClaims(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	key := []byte("secret")
+	v := &Verifier{
+		Keys:      &Keyring{Secrets: map[string]string{"": "secret"}},
+		Issuers:   []string{"https://issuer"},
+		Audiences: []string{"api"},
+		ClockSkew: 30 * time.Second,
+		Now:       func() time.Time { return now },
+	}
+	base := func() map[string]interface{} {
+		return map[string]interface{}{"iss": "https://issuer", "aud": []string{"other", "api"}, "exp": now.Unix() + 60}
+	}
+
+	_, err := v.Verify(sign(t, HS256, "", key, base()))
+	assert.NilError(t, err)
+
+	c := base()
+	c["exp"] = now.Unix() - 10 // within clock skew
+	_, err = v.Verify(sign(t, HS256, "", key, c))
+	assert.NilError(t, err)
+
+	c["exp"] = now.Unix() - 60
+	_, err = v.Verify(sign(t, HS256, "", key, c))
+	assert.Equal(t, ErrExpired, err)
+
+	c = base()
+	c["nbf"] = now.Unix() + 60
+	_, err = v.Verify(sign(t, HS256, "", key, c))
+	assert.Equal(t, ErrNotYetValid, err)
+
+	c = base()
+	c["iat"] = now.Unix() + 60
+	_, err = v.Verify(sign(t, HS256, "", key, c))
+	assert.Equal(t, ErrIssuedInFuture, err)
+
+	c = base()
+	c["iss"] = "https://evil"
+	_, err = v.Verify(sign(t, HS256, "", key, c))
+	assert.Equal(t, ErrInvalidIssuer, err)
+
+	c = base()
+	c["aud"] = "other"
+	_, err = v.Verify(sign(t, HS256, "", key, c))
+	assert.Equal(t, ErrInvalidAudience, err)
+
+	v.RequireExpiration = true
+	c = base()
+	delete(c, "exp")
+	_, err = v.Verify(sign(t, HS256, "", key, c))
+	assert.Equal(t, ErrMissingExp, err)
+}
+
+func TestJWKSRotation(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS := func(keys ...map[string]string) {
+		b, _ := json.Marshal(map[string]interface{}{"keys": keys})
+		assert.NilError(t, os.WriteFile(path, b, 0o600))
+		// make sure modification time differs on coarse file systems
+		now = now.Add(time.Minute)
+		assert.NilError(t, os.Chtimes(path, now, now))
+	}
+	oct := func(kid, secret string) map[string]string {
+		return map[string]string{"kty": "oct", "kid": kid, "k": base64.RawURLEncoding.EncodeToString([]byte(secret))}
+	}
+
+	writeJWKS(oct("k1", "first"))
+	keys := &Keyring{JWKSFile: path, now: func() time.Time { return now }}
+	v := &Verifier{Keys: keys}
+	assert.NilError(t, keys.Load())
+
+	claims := map[string]interface{}{"sub": "user1"}
+	_, err := v.Verify(sign(t, HS256, "k1", []byte("first"), claims))
+	assert.NilError(t, err)
+
+	_, err = v.Verify(sign(t, HS256, "k2", []byte("second"), claims))
+	assert.Equal(t, ErrKeyNotFound, err)
+
+	writeJWKS(oct("k1", "first"), oct("k2", "second"))
+	_, err = v.Verify(sign(t, HS256, "k2", []byte("second"), claims))
+	assert.NilError(t, err)
+
+	writeJWKS(oct("k2", "second"))
+	_, err = v.Verify(sign(t, HS256, "k1", []byte("first"), claims))
+	assert.Equal(t, ErrKeyNotFound, err)
+}
+
+func TestJWKSReloadError(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	write := func(b []byte) {
+		assert.NilError(t, os.WriteFile(path, b, 0o600))
+		now = now.Add(time.Minute)
+		assert.NilError(t, os.Chtimes(path, now, now))
+	}
+	oct, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "oct", "kid": "k1", "k": base64.RawURLEncoding.EncodeToString([]byte("first"))},
+	}})
+
+	write(oct)
+	keys := &Keyring{JWKSFile: path, now: func() time.Time { return now }}
+	v := &Verifier{Keys: keys}
+	assert.NilError(t, keys.Load())
+
+	// a half written file keeps the old keys
+	write(oct[:len(oct)/2])
+	claims := map[string]interface{}{"sub": "user1"}
+	_, err := v.Verify(sign(t, HS256, "k1", []byte("first"), claims))
+	assert.NilError(t, err)
+	assert.Assert(t, keys.Err() != nil)
+
+	write(oct)
+	_, err = v.Verify(sign(t, HS256, "k1", []byte("first"), claims))
+	assert.NilError(t, err)
+	assert.NilError(t, keys.Err())
+}
+
+func TestSignHMAC(t *testing.T) {
+	v := &Verifier{Keys: &Keyring{Secrets: map[string]string{"k1": "secret"}}}
+
+	raw, err := SignHMAC(HS384, "k1", []byte("secret"), map[string]interface{}{"sub": "user1"})
+	assert.NilError(t, err)
+	claims, err := v.Verify(raw)
+	assert.NilError(t, err)
+	assert.Equal(t, "user1", claims.Subject)
+
+	_, err = SignHMAC(RS256, "k1", []byte("secret"), nil)
+	assert.Equal(t, ErrUnsupportedAlg, err)
+}
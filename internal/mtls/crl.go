@@ -0,0 +1,141 @@
+package mtls
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// CRL is a certificate revocation list loaded from a local PEM or DER file.
+// The file is checked for changes every Refresh and reloaded when it changes.
+// If the list can not be read or is past its next update time, every certificate is rejected.
+type CRL struct {
+	// Path of the CRL file.
+	Path string
+	// Issuer verifies the signature of the list when set.
+	Issuer *x509.Certificate
+	// Refresh is how often the file is checked for changes. Default is 1 minute.
+	Refresh time.Duration
+
+	mu      sync.RWMutex
+	checked time.Time
+	err     error
+	modTime time.Time
+	list    *revocationList
+	revoked map[string]bool
+}
+
+// revocationList is the part of a parsed list that Check needs.
+// x509.ParseRevocationList needs Go 1.19, so the list is parsed with x509.ParseCRL.
+type revocationList struct {
+	rawIssuer  []byte
+	nextUpdate time.Time
+}
+
+// tbsCertList reads the raw issuer name, which pkix.TBSCertificateList only keeps decoded.
+type tbsCertList struct {
+	Version   int `asn1:"optional,default:0"`
+	Signature pkix.AlgorithmIdentifier
+	Issuer    asn1.RawValue
+}
+
+// Load reads the file. It is called lazily by Check, call it directly to
+// surface configuration errors at startup.
+func (c *CRL) Load() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err, c.checked = c.load(), time.Now()
+	return c.err
+}
+
+// current returns the list, or the error of reading it. The file is read again if it was
+// checked more than Refresh ago, requests in between only take the read lock.
+func (c *CRL) current() (*revocationList, map[string]bool, error) {
+	c.mu.RLock()
+	list, revoked, err, fresh := c.list, c.revoked, c.err, c.fresh()
+	c.mu.RUnlock()
+	if fresh {
+		return list, revoked, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// another request may have reloaded the file while this one waited for the lock
+	if !c.fresh() {
+		c.err, c.checked = c.load(), time.Now()
+	}
+	return c.list, c.revoked, c.err
+}
+
+func (c *CRL) fresh() bool {
+	refresh := c.Refresh
+	if refresh <= 0 {
+		refresh = time.Minute
+	}
+	return !c.checked.IsZero() && time.Since(c.checked) < refresh
+}
+
+func (c *CRL) load() error {
+	st, err := os.Stat(c.Path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCRL, err)
+	}
+	if c.list != nil && st.ModTime().Equal(c.modTime) {
+		return nil
+	}
+
+	b, err := os.ReadFile(c.Path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCRL, err)
+	}
+	if block, _ := pem.Decode(b); block != nil {
+		b = block.Bytes
+	}
+	list, err := x509.ParseCRL(b) //nolint:staticcheck // ParseRevocationList needs Go 1.19
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCRL, err)
+	}
+	if c.Issuer != nil {
+		if err := c.Issuer.CheckCRLSignature(list); err != nil { //nolint:staticcheck // see ParseCRL
+			return fmt.Errorf("%w: %v", ErrCRL, err)
+		}
+	}
+	var tbs tbsCertList
+	if _, err := asn1.Unmarshal(list.TBSCertList.Raw, &tbs); err != nil {
+		return fmt.Errorf("%w: %v", ErrCRL, err)
+	}
+
+	revoked := make(map[string]bool, len(list.TBSCertList.RevokedCertificates))
+	for _, rc := range list.TBSCertList.RevokedCertificates {
+		revoked[rc.SerialNumber.String()] = true
+	}
+
+	c.list = &revocationList{rawIssuer: tbs.Issuer.FullBytes, nextUpdate: list.TBSCertList.NextUpdate}
+	c.revoked, c.modTime = revoked, st.ModTime()
+	return nil
+}
+
+// Check returns ErrRevoked if the certificate is in the list.
+func (c *CRL) Check(cert *x509.Certificate, now time.Time) error {
+	list, revoked, err := c.current()
+	if err != nil {
+		return err
+	}
+	if !list.nextUpdate.IsZero() && now.After(list.nextUpdate) {
+		return fmt.Errorf("%w: list is outdated", ErrCRL)
+	}
+	// the list only covers certificates of its own issuer
+	if !bytes.Equal(list.rawIssuer, cert.RawIssuer) {
+		return nil
+	}
+	if revoked[cert.SerialNumber.String()] {
+		return ErrRevoked
+	}
+	return nil
+}
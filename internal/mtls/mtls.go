@@ -0,0 +1,214 @@
+// Package mtls maps verified TLS client certificates to principals. It is
+// shared by the gin and gorilla mtlsauth middlewares.
+package mtls
+
+import (
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+)
+
+var (
+	ErrNoCertificate = errors.New("mtls: no client certificate")
+	ErrUnverified    = errors.New("mtls: client certificate is not verified")
+	ErrIssuer        = errors.New("mtls: issuer is not allowed")
+	ErrRevoked       = errors.New("mtls: certificate is revoked")
+	ErrExpired       = errors.New("mtls: certificate is expired")
+	ErrUnknown       = errors.New("mtls: certificate does not match any identity")
+	ErrCRL           = errors.New("mtls: revocation list can not be used")
+)
+
+// Identity maps a client certificate to a principal. The certificate matches
+// the identity if all the non-empty match fields match.
+type Identity struct {
+	// CommonName is matched against subject CN of the certificate.
+	CommonName string `json:"common_name"`
+	// DNSName is matched against DNS SANs. "*.example.com" matches one label under example.com.
+	DNSName string `json:"dns_name"`
+	// URI is matched against URI SANs, e.g. a SPIFFE ID "spiffe://example.org/ns/prod/sa/billing".
+	// A trailing "/*" matches every URI under the prefix, e.g. "spiffe://example.org/*".
+	URI string `json:"uri"`
+	// Name of the principal. If empty, the matched CN, DNS name or URI is used.
+	Name string `json:"name"`
+	// Roles given to the principal.
+	Roles []string `json:"roles"`
+}
+
+// Authenticator checks the client certificate of a request.
+type Authenticator struct {
+	// Identities maps certificates to principals. If empty, every certificate of
+	// an allowed issuer is accepted and named by its first URI SAN or subject CN.
+	Identities []Identity
+	// AllowedIssuers are subject CNs or full distinguished names of the issuers
+	// client certificates may be signed by. The issuer is taken from the verified chain.
+	// Empty means any issuer the TLS server trusts.
+	AllowedIssuers []string
+	// CRL checks certificates against a certificate revocation list.
+	CRL *CRL
+	// Now is used instead of time.Now when set.
+	Now func() time.Time
+}
+
+// Authenticate returns the principal of the client certificate. The chain
+// must be verified by the TLS server, e.g. with tls.Config.ClientAuth set to
+// tls.RequireAndVerifyClientCert or tls.VerifyClientCertIfGiven; certificates
+// accepted with tls.RequestClientCert or tls.RequireAnyClientCert are rejected.
+func (a *Authenticator) Authenticate(r *http.Request) (*auth.Principal, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil, ErrNoCertificate
+	}
+	if len(r.TLS.VerifiedChains) == 0 {
+		return nil, ErrUnverified
+	}
+	cert := r.TLS.PeerCertificates[0]
+
+	now := time.Now()
+	if a.Now != nil {
+		now = a.Now()
+	}
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return nil, ErrExpired
+	}
+
+	if len(a.AllowedIssuers) > 0 && !a.issuerAllowed(r.TLS.VerifiedChains) {
+		return nil, ErrIssuer
+	}
+
+	if a.CRL != nil {
+		if err := a.CRL.Check(cert, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(a.Identities) == 0 {
+		name := cert.Subject.CommonName
+		if len(cert.URIs) > 0 {
+			name = cert.URIs[0].String()
+		}
+		return a.principal(cert, name, nil), nil
+	}
+
+	for _, id := range a.Identities {
+		if name, ok := id.match(cert); ok {
+			if id.Name != "" {
+				name = id.Name
+			}
+			return a.principal(cert, name, id.Roles), nil
+		}
+	}
+	return nil, ErrUnknown
+}
+
+// issuerAllowed reports whether the certificate that signed the client certificate in one of
+// the verified chains is an allowed issuer. The issuer name of the client certificate itself is
+// not used, anyone can put any name there.
+func (a *Authenticator) issuerAllowed(chains [][]*x509.Certificate) bool {
+	for _, chain := range chains {
+		// a client certificate trusted directly is its own issuer
+		issuer := chain[0]
+		if len(chain) > 1 {
+			issuer = chain[1]
+		}
+		if contains(a.AllowedIssuers, issuer.Subject.CommonName) || contains(a.AllowedIssuers, issuer.Subject.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Challenge returns empty string, there is no WWW-Authenticate challenge for client certificates.
+func (a *Authenticator) Challenge(error) string {
+	return ""
+}
+
+func (a *Authenticator) principal(cert *x509.Certificate, name string, roles []string) *auth.Principal {
+	attrs := map[string]string{
+		"subject": cert.Subject.String(),
+		"issuer":  cert.Issuer.String(),
+		"serial":  cert.SerialNumber.String(),
+	}
+	for _, u := range cert.URIs {
+		if u.Scheme == "spiffe" {
+			attrs["spiffe_id"] = u.String()
+			break
+		}
+	}
+	return &auth.Principal{Name: name, Scheme: "mtls", Roles: roles, Attributes: attrs}
+}
+
+// match returns the matched value of the certificate used as default principal name.
+func (id Identity) match(cert *x509.Certificate) (string, bool) {
+	if id.CommonName == "" && id.DNSName == "" && id.URI == "" {
+		return "", false
+	}
+
+	var name string
+	if id.URI != "" {
+		found := false
+		for _, u := range cert.URIs {
+			if matchURI(id.URI, u.String()) {
+				name, found = u.String(), true
+				break
+			}
+		}
+		if !found {
+			return "", false
+		}
+	}
+
+	if id.DNSName != "" {
+		found := false
+		for _, d := range cert.DNSNames {
+			if matchDNS(id.DNSName, d) {
+				if name == "" {
+					name = d
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", false
+		}
+	}
+
+	if id.CommonName != "" {
+		if cert.Subject.CommonName != id.CommonName {
+			return "", false
+		}
+		if name == "" {
+			name = cert.Subject.CommonName
+		}
+	}
+
+	return name, true
+}
+
+func matchURI(pattern, uri string) bool {
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(uri, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == uri
+}
+
+func matchDNS(pattern, name string) bool {
+	pattern, name = strings.ToLower(pattern), strings.ToLower(strings.TrimSuffix(name, "."))
+	if strings.HasPrefix(pattern, "*.") {
+		_, rest, ok := strings.Cut(name, ".")
+		return ok && rest == pattern[2:]
+	}
+	return pattern == name
+}
+
+func contains(arr []string, val string) bool {
+	for _, item := range arr {
+		if item == val {
+			return true
+		}
+	}
+	return false
+}
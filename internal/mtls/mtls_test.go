@@ -0,0 +1,124 @@
+package mtls
+
+import (
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/testcert"
+	"gotest.tools/assert"
+)
+
+func TestAuthenticate(t *testing.T) {
+	ca := testcert.NewCA("Internal CA")
+	other := testcert.NewCA("Other CA")
+
+	a := &Authenticator{
+		AllowedIssuers: []string{"Internal CA"},
+		Identities: []Identity{
+			{URI: "spiffe://example.org/ns/prod/sa/billing", Name: "billing", Roles: []string{"billing"}},
+			{URI: "spiffe://example.org/ns/dev/*", Roles: []string{"dev"}},
+			{DNSName: "*.svc.example.org", Roles: []string{"internal"}},
+			{CommonName: "backup-job", Roles: []string{"backup"}},
+		},
+	}
+
+	for _, tc := range []struct {
+		leaf  testcert.Leaf
+		name  string
+		roles []string
+	}{
+		{testcert.Leaf{CommonName: "x", URIs: []string{"spiffe://example.org/ns/prod/sa/billing"}}, "billing", []string{"billing"}},
+		{testcert.Leaf{URIs: []string{"spiffe://example.org/ns/dev/sa/api"}}, "spiffe://example.org/ns/dev/sa/api", []string{"dev"}},
+		{testcert.Leaf{DNSNames: []string{"orders.svc.example.org"}}, "orders.svc.example.org", []string{"internal"}},
+		{testcert.Leaf{CommonName: "backup-job"}, "backup-job", []string{"backup"}},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.TLS = testcert.ConnectionState(ca.Issue(tc.leaf))
+		p, err := a.Authenticate(r)
+		assert.NilError(t, err)
+		assert.Equal(t, tc.name, p.Name)
+		assert.Equal(t, "mtls", p.Scheme)
+		assert.DeepEqual(t, tc.roles, p.Roles)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	_, err := a.Authenticate(r)
+	assert.Equal(t, ErrNoCertificate, err)
+
+	r.TLS = testcert.ConnectionState(ca.Issue(testcert.Leaf{DNSNames: []string{"a.b.svc.example.org"}}))
+	_, err = a.Authenticate(r)
+	assert.Equal(t, ErrUnknown, err)
+
+	r.TLS = testcert.ConnectionState(other.Issue(testcert.Leaf{CommonName: "backup-job"}))
+	_, err = a.Authenticate(r)
+	assert.Equal(t, ErrIssuer, err)
+
+	// a server with tls.RequestClientCert passes any certificate, e.g. one with a forged issuer name
+	forged := testcert.NewCA("Internal CA").Issue(testcert.Leaf{CommonName: "backup-job"})
+	assert.Equal(t, "Internal CA", forged.Issuer.CommonName)
+	r.TLS = testcert.UnverifiedConnectionState(forged)
+	_, err = a.Authenticate(r)
+	assert.Equal(t, ErrUnverified, err)
+}
+
+func TestRevocationList(t *testing.T) {
+	ca := testcert.NewCA("Internal CA")
+	good := ca.Issue(testcert.Leaf{CommonName: "good"})
+	revoked := ca.Issue(testcert.Leaf{CommonName: "revoked"})
+
+	path := filepath.Join(t.TempDir(), "ca.crl")
+	assert.NilError(t, os.WriteFile(path, ca.CRL(time.Now().Add(time.Hour), revoked), 0o600))
+
+	a := &Authenticator{CRL: &CRL{Path: path, Issuer: ca.Cert}}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.TLS = testcert.ConnectionState(good)
+	p, err := a.Authenticate(r)
+	assert.NilError(t, err)
+	assert.Equal(t, "good", p.Name)
+
+	r.TLS = testcert.ConnectionState(revoked)
+	_, err = a.Authenticate(r)
+	assert.Equal(t, ErrRevoked, err)
+
+	// outdated list rejects everything
+	assert.NilError(t, os.WriteFile(path, ca.CRL(time.Now().Add(10*time.Minute)), 0o600))
+	a.Now = func() time.Time { return time.Now().Add(30 * time.Minute) }
+	a.CRL = &CRL{Path: path}
+	r.TLS = testcert.ConnectionState(good)
+	_, err = a.Authenticate(r)
+	assert.Assert(t, errors.Is(err, ErrCRL))
+
+	a.Now = nil
+	a.CRL = &CRL{Path: filepath.Join(t.TempDir(), "missing.crl")}
+	_, err = a.Authenticate(r)
+	assert.Assert(t, errors.Is(err, ErrCRL))
+}
+
+func TestRevocationListRefresh(t *testing.T) {
+	ca := testcert.NewCA("Internal CA")
+	cert := ca.Issue(testcert.Leaf{CommonName: "service"})
+
+	path := filepath.Join(t.TempDir(), "ca.crl")
+	assert.NilError(t, os.WriteFile(path, ca.CRL(time.Now().Add(time.Hour)), 0o600))
+	crl := &CRL{Path: path, Refresh: 50 * time.Millisecond}
+	assert.NilError(t, crl.Load())
+
+	// the file is not checked again until Refresh has passed
+	assert.NilError(t, os.WriteFile(path, ca.CRL(time.Now().Add(time.Hour), cert), 0o600))
+	assert.NilError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+	assert.NilError(t, crl.Check(cert, time.Now()))
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, ErrRevoked, crl.Check(cert, time.Now()))
+
+	// read errors are kept until the next refresh as well
+	assert.NilError(t, os.Remove(path))
+	assert.Equal(t, ErrRevoked, crl.Check(cert, time.Now()))
+	time.Sleep(60 * time.Millisecond)
+	assert.Assert(t, errors.Is(crl.Check(cert, time.Now()), ErrCRL))
+}
@@ -0,0 +1,55 @@
+// Package nonce remembers values that may be used only once, such as nonces
+// of signed requests and tokens of one-time links.
+package nonce
+
+import (
+	"sync"
+	"time"
+)
+
+// Store remembers used values to block replays.
+type Store interface {
+	// Use marks the value of the key as used until expires. It returns false if the value was already used.
+	Use(keyID, value string, expires time.Time) bool
+}
+
+// Memory keeps used values in memory. Use a shared store, e.g. backed by
+// Redis, when the service runs on several instances.
+type Memory struct {
+	// Now is used instead of time.Now when set.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	values  map[string]time.Time
+	cleaned time.Time
+}
+
+// Use marks the value as used. Expired values are removed once a minute.
+func (s *Memory) Use(keyID, value string, expires time.Time) bool {
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+	key := keyID + "\x00" + value
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.values == nil {
+		s.values = make(map[string]time.Time)
+	}
+	if now.Sub(s.cleaned) > time.Minute {
+		for k, exp := range s.values {
+			if !now.Before(exp) {
+				delete(s.values, k)
+			}
+		}
+		s.cleaned = now
+	}
+
+	if exp, ok := s.values[key]; ok && now.Before(exp) {
+		return false
+	}
+	s.values[key] = expires
+	return true
+}
@@ -0,0 +1,19 @@
+package nonce
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestMemory(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := &Memory{Now: func() time.Time { return now }}
+	assert.Assert(t, s.Use("k", "n1", now.Add(time.Minute)))
+	assert.Assert(t, !s.Use("k", "n1", now.Add(time.Minute)))
+	assert.Assert(t, s.Use("other", "n1", now.Add(time.Minute)))
+
+	now = now.Add(2 * time.Minute)
+	assert.Assert(t, s.Use("k", "n1", now.Add(time.Minute)))
+}
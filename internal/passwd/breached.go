@@ -0,0 +1,63 @@
+package passwd
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Breached returns how often the password appears in a local copy of breached password hashes, or 0.
+// The password never leaves the host: it is looked up by the upper case hex SHA-1 hash.
+//
+// The path is either a directory of range files as served by the Pwned Passwords range API, one file per
+// 5 character hash prefix named PREFIX or PREFIX.txt with SUFFIX:COUNT lines, or a single file of HASH:COUNT lines.
+// Lines without a count count once.
+func Breached(path, password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	st, err := os.Stat(path)
+	if err != nil {
+		return 0, fmt.Errorf("passwd: %w", err)
+	}
+	file, key := path, hash
+	if st.IsDir() {
+		file, key = filepath.Join(path, hash[:5]+".txt"), hash[5:]
+		if _, err := os.Stat(file); errors.Is(err, fs.ErrNotExist) {
+			file = filepath.Join(path, hash[:5])
+		}
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, fmt.Errorf("passwd: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		h, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !strings.EqualFold(h, key) {
+			continue
+		}
+		if !ok {
+			return 1, nil
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return 0, fmt.Errorf("passwd: %s: invalid count %q", file, count)
+		}
+		return n, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("passwd: %w", err)
+	}
+	return 0, nil
+}
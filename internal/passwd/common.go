@@ -0,0 +1,33 @@
+package passwd
+
+import "strings"
+
+// Common reports whether the password is in the built-in list of common passwords. Case is ignored.
+func Common(password string) bool {
+	_, ok := common[strings.ToLower(password)]
+	return ok
+}
+
+// common are the most used passwords of public breach compilations, in lower case.
+var common = func() map[string]struct{} {
+	m := make(map[string]struct{}, len(commonList))
+	for _, p := range commonList {
+		m[p] = struct{}{}
+	}
+	return m
+}()
+
+var commonList = []string{
+	"000000", "0000000000", "1111", "111111", "11111111", "112233", "121212", "123123", "123123123", "1234",
+	"12345", "123456", "1234567", "12345678", "123456789", "1234567890", "123321", "123456a", "123abc", "123qwe",
+	"1q2w3e", "1q2w3e4r", "1q2w3e4r5t", "1qaz2wsx", "147258369", "159753", "654321", "666666", "696969", "7777777",
+	"87654321", "888888", "987654321", "a123456", "aa123456", "abc123", "abcd1234", "access", "admin", "admin123",
+	"administrator", "asdf", "asdf1234", "asdfgh", "asdfghjkl", "azerty", "baseball", "batman", "changeme", "charlie",
+	"chocolate", "computer", "correcthorsebatterystaple", "dallas", "default", "dragon", "football", "freedom", "guest",
+	"hello", "hello123", "hunter2", "iloveyou", "jennifer", "jordan", "killer", "letmein", "letmein123", "login",
+	"lovely", "master", "michael", "monkey", "mustang", "nothing", "p@ssw0rd", "p@ssword", "pass", "pass123",
+	"passw0rd", "password", "password!", "password1", "password12", "password123", "password1234", "pepper",
+	"princess", "qazwsx", "qwe123", "qwerty", "qwerty1", "qwerty123", "qwertyuiop", "root", "secret", "secret123",
+	"shadow", "soccer", "starwars", "summer", "sunshine", "superman", "test", "test123", "test1234", "trustno1",
+	"welcome", "welcome1", "welcome123", "whatever", "winter", "zaq12wsx", "zxcvbn", "zxcvbnm",
+}
@@ -0,0 +1,62 @@
+package passwd
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hasher is the algorithm and cost parameters of new hashes.
+type Hasher struct {
+	// Algorithm is Bcrypt (default) or Argon2id.
+	Algorithm string `json:"algorithm"`
+	Params
+}
+
+// Validate checks the algorithm and the bcrypt cost.
+func (h Hasher) Validate() error {
+	switch h.algorithm() {
+	case Bcrypt, Argon2id:
+	default:
+		return fmt.Errorf("%w: algorithm %q", ErrUnsupported, h.Algorithm)
+	}
+	if c := h.BcryptCost; c != 0 && (c < bcrypt.MinCost || c > bcrypt.MaxCost) {
+		return fmt.Errorf("passwd: bcrypt cost %d is not in %d..%d", c, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return nil
+}
+
+// Hash hashes the password.
+func (h Hasher) Hash(password string) (string, error) {
+	return Hash(h.algorithm(), password, h.Params)
+}
+
+// Outdated reports whether the hash was made with another algorithm or other parameters than Hash uses.
+// Plain passwords and unsupported hashes are not outdated: they can not be upgraded on login.
+func (h Hasher) Outdated(hash string) bool {
+	alg := algorithm(hash)
+	if alg == "" {
+		return false
+	}
+	if alg != h.algorithm() {
+		return true
+	}
+
+	p := h.Params.withDefaults()
+	switch alg {
+	case Bcrypt:
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err == nil && cost != p.BcryptCost
+	case Argon2id:
+		a, err := parseArgon2id(hash)
+		return err == nil && (a.memory != p.Argon2Memory || a.time != p.Argon2Time || a.threads != p.Argon2Threads)
+	}
+	return false
+}
+
+func (h Hasher) algorithm() string {
+	if h.Algorithm == "" {
+		return Bcrypt
+	}
+	return h.Algorithm
+}
@@ -0,0 +1,162 @@
+// Package passwd hashes and verifies the passwords of basicauth users.
+// Hashes are in the usual modular crypt format, so they can be kept in
+// htpasswd files: bcrypt ($2a$, $2b$, $2y$) and argon2id ($argon2id$).
+package passwd
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Algorithms of new hashes.
+const (
+	Bcrypt   = "bcrypt"
+	Argon2id = "argon2id"
+)
+
+var (
+	// ErrUnsupported is returned for unknown algorithms and hash formats.
+	ErrUnsupported = errors.New("passwd: unsupported hash")
+	// ErrMalformed is returned for hashes that can not be parsed.
+	ErrMalformed = errors.New("passwd: malformed hash")
+)
+
+// Params are the cost parameters of new hashes. Zero fields use the defaults.
+type Params struct {
+	// BcryptCost is the bcrypt cost. Default is 10.
+	BcryptCost int `json:"bcrypt_cost"`
+	// Argon2Memory is the argon2id memory in KiB. Default is 64 MiB.
+	Argon2Memory uint32 `json:"argon2_memory"`
+	// Argon2Time is the number of argon2id passes. Default is 3.
+	Argon2Time uint32 `json:"argon2_time"`
+	// Argon2Threads is the argon2id parallelism. Default is 4.
+	Argon2Threads uint8 `json:"argon2_threads"`
+}
+
+func (p Params) withDefaults() Params {
+	if p.BcryptCost == 0 {
+		p.BcryptCost = bcrypt.DefaultCost
+	}
+	if p.Argon2Memory == 0 {
+		p.Argon2Memory = 64 * 1024
+	}
+	if p.Argon2Time == 0 {
+		p.Argon2Time = 3
+	}
+	if p.Argon2Threads == 0 {
+		p.Argon2Threads = 4
+	}
+	return p
+}
+
+// Hash hashes the password with the algorithm, Bcrypt or Argon2id.
+func Hash(algorithm, password string, p Params) (string, error) {
+	p = p.withDefaults()
+	switch algorithm {
+	case Bcrypt:
+		b, err := bcrypt.GenerateFromPassword([]byte(password), p.BcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("passwd: %w", err)
+		}
+		return string(b), nil
+
+	case Argon2id:
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return "", fmt.Errorf("passwd: %w", err)
+		}
+		key := argon2.IDKey([]byte(password), salt, p.Argon2Time, p.Argon2Memory, p.Argon2Threads, 32)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Argon2Memory, p.Argon2Time, p.Argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	}
+	return "", fmt.Errorf("%w: algorithm %q", ErrUnsupported, algorithm)
+}
+
+// IsHash reports whether the value looks like a hash in modular crypt format rather than a plain password.
+func IsHash(s string) bool {
+	return strings.HasPrefix(s, "$") && strings.Count(s, "$") >= 3
+}
+
+// Verify reports whether the password matches the hash.
+func Verify(hash, password string) (bool, error) {
+	switch algorithm(hash) {
+	case Bcrypt:
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		return true, nil
+
+	case Argon2id:
+		a, err := parseArgon2id(hash)
+		if err != nil {
+			return false, err
+		}
+		key := argon2.IDKey([]byte(password), a.salt, a.time, a.memory, a.threads, uint32(len(a.key)))
+		return subtle.ConstantTimeCompare(key, a.key) == 1, nil
+	}
+	return false, ErrUnsupported
+}
+
+// Supported reports whether Verify can check the hash.
+func Supported(hash string) bool {
+	return algorithm(hash) != ""
+}
+
+func algorithm(hash string) string {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return Bcrypt
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return Argon2id
+	}
+	return ""
+}
+
+type argon2Hash struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// parseArgon2id parses $argon2id$v=19$m=65536,t=3,p=4$salt$key.
+func parseArgon2id(hash string) (*argon2Hash, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return nil, ErrMalformed
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrMalformed
+	}
+
+	a := &argon2Hash{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &a.memory, &a.time, &a.threads); err != nil {
+		return nil, ErrMalformed
+	}
+	if a.time == 0 || a.threads == 0 {
+		return nil, ErrMalformed
+	}
+
+	var err error
+	if a.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrMalformed
+	}
+	if a.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(a.key) == 0 {
+		return nil, ErrMalformed
+	}
+	return a, nil
+}
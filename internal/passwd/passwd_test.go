@@ -0,0 +1,131 @@
+package passwd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestHash(t *testing.T) {
+	params := Params{BcryptCost: 4, Argon2Memory: 1024, Argon2Time: 1, Argon2Threads: 1}
+	for _, algorithm := range []string{Bcrypt, Argon2id} {
+		hash, err := Hash(algorithm, "correct horse", params)
+		assert.NilError(t, err)
+		assert.Assert(t, IsHash(hash), hash)
+		assert.Assert(t, Supported(hash), hash)
+
+		ok, err := Verify(hash, "correct horse")
+		assert.NilError(t, err)
+		assert.Assert(t, ok, algorithm)
+
+		ok, err = Verify(hash, "wrong horse")
+		assert.NilError(t, err)
+		assert.Assert(t, !ok, algorithm)
+	}
+
+	_, err := Hash("md5", "password", params)
+	assert.ErrorContains(t, err, "unsupported hash")
+}
+
+func TestVerify(t *testing.T) {
+	// htpasswd -B writes the $2y$ prefix
+	ok, err := Verify("$2y$05$oopEP8g.9wUslDQcAtnuqOdFGeHRnJHMIcWH9xKSizs5yhumWMMnq", "password")
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+
+	ok, err = Verify("$argon2id$v=19$m=1024,t=1,p=1$c29tZXNhbHQ$5OuVuIrYtkbb2JSkJMy93FYE/5+hl9s0ZR0i4c6jzDY", "password")
+	assert.NilError(t, err)
+	assert.Assert(t, !ok)
+
+	_, err = Verify("$apr1$salt$hash", "password")
+	assert.Equal(t, ErrUnsupported, err)
+	_, err = Verify("$argon2id$v=19$m=1024$salt$key", "password")
+	assert.Equal(t, ErrMalformed, err)
+
+	assert.Assert(t, !IsHash("password"))
+	assert.Assert(t, !IsHash("$ecret"))
+}
+
+func TestPolicy(t *testing.T) {
+	p := Policy{}
+	assert.NilError(t, p.Check("admin", "vivid-Otter-glides-93"))
+
+	err := p.Check("admin", "admin123")
+	assert.ErrorContains(t, err, "password has 8 characters, at least 12 are required")
+	assert.ErrorContains(t, err, "password entropy is")
+	assert.ErrorContains(t, err, "password contains the user name")
+
+	err = p.Check("user", "aaaaaaaaaaaaaaaaaaaa")
+	assert.ErrorContains(t, err, "password entropy is 5 bits")
+	assert.Assert(t, !strings.Contains(err.Error(), "characters"))
+
+	err = p.Check("admin", "Password")
+	assert.Assert(t, errors.Is(err, ErrCommon))
+	assert.Assert(t, errors.Is(fmt.Errorf("user %q: %w", "admin", err), ErrCommon))
+	var violations Violations
+	assert.Assert(t, errors.As(err, &violations))
+	assert.Equal(t, 3, len(violations))
+	assert.Assert(t, !Common("vivid-Otter-glides-93"))
+
+	assert.Equal(t, 0.0, Entropy(""))
+	assert.Assert(t, Entropy("abcdefgh") < Entropy("adgjmpsv"))
+}
+
+func TestBreached(t *testing.T) {
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	dir := t.TempDir()
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "5BAA6.txt"),
+		[]byte("003D68EB55068C33ACE09247EE4C639306B:3\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:9659365\r\n"), 0o600))
+	n, err := Breached(dir, "password")
+	assert.NilError(t, err)
+	assert.Equal(t, 9659365, n)
+
+	// prefixes without a file are an error, a partial copy must not pass every password
+	_, err = Breached(dir, "vivid-Otter-glides-93")
+	assert.ErrorContains(t, err, "no such file")
+
+	file := filepath.Join(dir, "hashes.txt")
+	assert.NilError(t, os.WriteFile(file, []byte("5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8\n"), 0o600))
+	n, err = Breached(file, "password")
+	assert.NilError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = Breached(file, "vivid-Otter-glides-93")
+	assert.NilError(t, err)
+	assert.Equal(t, 0, n)
+
+	err = Policy{BreachedRanges: dir}.Check("admin", "password")
+	assert.Assert(t, errors.Is(err, ErrBreached))
+	assert.ErrorContains(t, err, "breached passwords 9659365 times")
+}
+
+func TestHasher(t *testing.T) {
+	bcrypt4, err := Hash(Bcrypt, "pass", Params{BcryptCost: 4})
+	assert.NilError(t, err)
+	argon, err := Hash(Argon2id, "pass", Params{Argon2Memory: 1024, Argon2Time: 1, Argon2Threads: 1})
+	assert.NilError(t, err)
+
+	h := Hasher{Params: Params{BcryptCost: 4}}
+	assert.Assert(t, !h.Outdated(bcrypt4))
+	assert.Assert(t, h.Outdated(argon))
+	assert.Assert(t, !h.Outdated("pass"))
+	assert.Assert(t, Hasher{}.Outdated(bcrypt4))
+
+	h = Hasher{Algorithm: Argon2id, Params: Params{Argon2Memory: 1024, Argon2Time: 1, Argon2Threads: 1}}
+	assert.Assert(t, !h.Outdated(argon))
+	assert.Assert(t, h.Outdated(bcrypt4))
+	h.Argon2Time = 2
+	assert.Assert(t, h.Outdated(argon))
+
+	hash, err := h.Hash("pass")
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=2,p=1$"), hash)
+
+	assert.NilError(t, Hasher{}.Validate())
+	assert.ErrorContains(t, Hasher{Algorithm: "md5"}.Validate(), `passwd: unsupported hash: algorithm "md5"`)
+	assert.ErrorContains(t, Hasher{Params: Params{BcryptCost: 40}}.Validate(), "bcrypt cost 40 is not in 4..31")
+}
@@ -0,0 +1,134 @@
+package passwd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ErrSameAsUser is returned by Check for passwords that contain the user name.
+	ErrSameAsUser = errors.New("passwd: password contains the user name")
+	// ErrCommon is returned by Check for passwords of the built-in list of common passwords.
+	ErrCommon = errors.New("passwd: password is a common password")
+	// ErrBreached is returned by Check for passwords found in the breached password ranges.
+	ErrBreached = errors.New("passwd: password appears in breached passwords")
+)
+
+// Policy is the minimum strength of passwords.
+type Policy struct {
+	// MinLength is the minimum number of characters. Default is 12.
+	MinLength int `json:"min_length"`
+	// MinEntropy is the minimum estimated entropy in bits, see Entropy. Default is 50.
+	MinEntropy float64 `json:"min_entropy"`
+	// BreachedRanges is a local copy of breached password hashes the password must not be in, see Breached.
+	// Passwords are not checked against breached passwords if it is empty.
+	BreachedRanges string `json:"breached_ranges"`
+}
+
+// Check returns the reasons the password of the user is weak as Violations, or nil. Passwords must be
+// long enough, have enough entropy, not be in the built-in list of common passwords, not contain the user name
+// and not be in BreachedRanges.
+func (p Policy) Check(user, password string) error {
+	minLength, minEntropy := p.MinLength, p.MinEntropy
+	if minLength == 0 {
+		minLength = 12
+	}
+	if minEntropy == 0 {
+		minEntropy = 50
+	}
+
+	var errs Violations
+	if n := len([]rune(password)); n < minLength {
+		errs = append(errs, fmt.Errorf("passwd: password has %d characters, at least %d are required", n, minLength))
+	}
+	if e := Entropy(password); e < minEntropy {
+		errs = append(errs, fmt.Errorf("passwd: password entropy is %.0f bits, at least %.0f are required", e, minEntropy))
+	}
+	if Common(password) {
+		errs = append(errs, ErrCommon)
+	}
+	if user != "" && strings.Contains(strings.ToLower(password), strings.ToLower(user)) {
+		errs = append(errs, ErrSameAsUser)
+	}
+	if p.BreachedRanges != "" {
+		if n, err := Breached(p.BreachedRanges, password); err != nil {
+			errs = append(errs, err)
+		} else if n > 0 {
+			errs = append(errs, fmt.Errorf("%w %d times", ErrBreached, n))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Violations are the reasons a password is weak. errors.Is reports whether any of them matches.
+type Violations []error
+
+// Error returns the reasons on separate lines.
+func (v Violations) Error() string {
+	s := make([]string, len(v))
+	for i, err := range v {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+// Is reports whether one of the reasons matches target.
+func (v Violations) Is(target error) bool {
+	for _, err := range v {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap returns the reasons.
+func (v Violations) Unwrap() []error {
+	return v
+}
+
+// Entropy estimates the entropy of a password in bits from the character classes it uses and its length.
+// Repeated characters and runs such as "aaa" or "abc" count once.
+func Entropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	effective := 0
+	prev := rune(-10)
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+		if r != prev && r != prev+1 && r != prev-1 {
+			effective++
+		}
+		prev = r
+	}
+
+	pool := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.used {
+			pool += class.size
+		}
+	}
+	if pool == 0 {
+		return 0
+	}
+	return float64(effective) * math.Log2(float64(pool))
+}
@@ -0,0 +1,188 @@
+// Package proxyheader authenticates requests by identity headers set by a
+// trusted reverse proxy such as an SSO gateway. It is shared by the gin and
+// gorilla headerauth middlewares.
+package proxyheader
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/clientip"
+)
+
+// Default header names.
+const (
+	DefaultUserHeader      = "X-Forwarded-User"
+	DefaultGroupsHeader    = "X-Forwarded-Groups"
+	DefaultSignatureHeader = "X-Forwarded-Signature"
+)
+
+// ErrUntrusted is returned when identity headers come from a peer that is not trusted.
+var ErrUntrusted = errors.New("proxyheader: identity headers are not trusted")
+
+// Authenticator reads the identity from request headers. The headers are
+// trusted only if the direct peer is a trusted proxy or the headers carry a
+// valid signature, otherwise they are removed from the request.
+type Authenticator struct {
+	UserHeader      string
+	GroupsHeader    string
+	SignatureHeader string
+	// TrustedProxies are the networks of the proxies that may set identity headers.
+	TrustedProxies clientip.Set
+	// Secrets verify signatures of identity headers. Several secrets can be active during rotation.
+	Secrets []string
+	// MaxAge limits the age of a signature, and how far its time may be ahead of the clock. Default is five minutes.
+	MaxAge time.Duration
+	// Now is used instead of time.Now when set.
+	Now func() time.Time
+}
+
+// Authenticate returns the principal of the identity headers. It removes the
+// headers from the request if they are not trusted, so handlers can never read
+// an identity the middleware did not accept.
+func (a *Authenticator) Authenticate(r *http.Request) (*auth.Principal, error) {
+	user := r.Header.Get(a.userHeader())
+	if !a.trusted(r) {
+		a.Strip(r)
+		if user == "" {
+			return nil, auth.ErrNoCredentials
+		}
+		return nil, ErrUntrusted
+	}
+	if user == "" {
+		return nil, auth.ErrNoCredentials
+	}
+
+	var groups []string
+	for _, g := range strings.Split(r.Header.Get(a.groupsHeader()), ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			groups = append(groups, g)
+		}
+	}
+	return &auth.Principal{Name: user, Scheme: "proxy", Roles: groups}, nil
+}
+
+// Challenge returns empty string, identity headers have no challenge.
+func (a *Authenticator) Challenge(error) string {
+	return ""
+}
+
+// Strip removes identity headers from the request.
+func (a *Authenticator) Strip(r *http.Request) {
+	r.Header.Del(a.userHeader())
+	r.Header.Del(a.groupsHeader())
+	r.Header.Del(a.signatureHeader())
+}
+
+func (a *Authenticator) trusted(r *http.Request) bool {
+	if peer, ok := clientip.Peer(r); ok && a.TrustedProxies.Contains(peer) {
+		return true
+	}
+	return a.validSignature(r)
+}
+
+// validSignature checks "t=<unix time>,v1=<hex hmac>" signature header. The HMAC-SHA256 is
+// computed over method, request target, user, groups and the timestamp joined with new lines,
+// so a captured signature works only for the same request and only within MaxAge.
+func (a *Authenticator) validSignature(r *http.Request) bool {
+	header := r.Header.Get(a.signatureHeader())
+	if header == "" || len(a.Secrets) == 0 {
+		return false
+	}
+
+	var (
+		timestamp string
+		sigs      [][]byte
+	)
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			timestamp = v
+		case "v1":
+			if sig, err := hex.DecodeString(v); err == nil {
+				sigs = append(sigs, sig)
+			}
+		}
+	}
+
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := a.now().Sub(time.Unix(sec, 0))
+	if age < -a.maxAge() || age > a.maxAge() {
+		return false
+	}
+
+	payload := Payload(r.Method, r.URL.RequestURI(), r.Header.Get(a.userHeader()), r.Header.Get(a.groupsHeader()), timestamp)
+	for _, secret := range a.Secrets {
+		expected := sign(secret, payload)
+		for _, sig := range sigs {
+			if hmac.Equal(expected, sig) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Sign returns the signature header value for an identity sent with a request, for proxies and tests.
+// The target is the path and query of the request as the service receives it, e.g. /v1/users?page=2.
+func Sign(secret, method, target, user, groups string, t time.Time) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(sign(secret, Payload(method, target, user, groups, timestamp)))
+}
+
+// Payload returns the signed content of an identity sent with a request.
+func Payload(method, target, user, groups, timestamp string) string {
+	return method + "\n" + target + "\n" + user + "\n" + groups + "\n" + timestamp
+}
+
+func sign(secret, payload string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+func (a *Authenticator) userHeader() string {
+	if a.UserHeader != "" {
+		return a.UserHeader
+	}
+	return DefaultUserHeader
+}
+
+func (a *Authenticator) groupsHeader() string {
+	if a.GroupsHeader != "" {
+		return a.GroupsHeader
+	}
+	return DefaultGroupsHeader
+}
+
+func (a *Authenticator) signatureHeader() string {
+	if a.SignatureHeader != "" {
+		return a.SignatureHeader
+	}
+	return DefaultSignatureHeader
+}
+
+func (a *Authenticator) maxAge() time.Duration {
+	if a.MaxAge > 0 {
+		return a.MaxAge
+	}
+	return 5 * time.Minute
+}
+
+func (a *Authenticator) now() time.Time {
+	if a.Now != nil {
+		return a.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,92 @@
+package proxyheader
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/clientip"
+	"gotest.tools/assert"
+)
+
+func TestTrustedProxy(t *testing.T) {
+	trusted, _ := clientip.ParseSet([]string{"10.0.0.0/8"})
+	a := &Authenticator{TrustedProxies: trusted}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.1.1.1:4000"
+	r.Header.Set("X-Forwarded-User", "alice")
+	r.Header.Set("X-Forwarded-Groups", "admins, devs,")
+	p, err := a.Authenticate(r)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &auth.Principal{Name: "alice", Scheme: "proxy", Roles: []string{"admins", "devs"}}, p)
+
+	r.RemoteAddr = "203.0.113.5:4000"
+	_, err = a.Authenticate(r)
+	assert.Equal(t, ErrUntrusted, err)
+	assert.Equal(t, "", r.Header.Get("X-Forwarded-User"))
+	assert.Equal(t, "", r.Header.Get("X-Forwarded-Groups"))
+
+	_, err = a.Authenticate(r)
+	assert.Equal(t, auth.ErrNoCredentials, err)
+}
+
+func TestSignedHeaders(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	a := &Authenticator{Secrets: []string{"old", "new"}, Now: func() time.Time { return now }}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Forwarded-User", "alice")
+	r.Header.Set("X-Forwarded-Groups", "admins")
+	r.Header.Set("X-Forwarded-Signature", Sign("new", "GET", "/", "alice", "admins", now.Add(-time.Minute)))
+	p, err := a.Authenticate(r)
+	assert.NilError(t, err)
+	assert.Equal(t, "alice", p.Name)
+
+	// groups are covered by the signature
+	r.Header.Set("X-Forwarded-Groups", "admins,root")
+	_, err = a.Authenticate(r)
+	assert.Equal(t, ErrUntrusted, err)
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Forwarded-User", "alice")
+	r.Header.Set("X-Forwarded-Signature", Sign("new", "GET", "/", "alice", "", now.Add(-10*time.Minute)))
+	_, err = a.Authenticate(r)
+	assert.Equal(t, ErrUntrusted, err)
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Forwarded-User", "alice")
+	r.Header.Set("X-Forwarded-Signature", Sign("unknown", "GET", "/", "alice", "", now))
+	_, err = a.Authenticate(r)
+	assert.Equal(t, ErrUntrusted, err)
+
+	// signatures from the future are rejected like old ones
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Forwarded-User", "alice")
+	r.Header.Set("X-Forwarded-Signature", Sign("new", "GET", "/", "alice", "", now.Add(10*time.Minute)))
+	_, err = a.Authenticate(r)
+	assert.Equal(t, ErrUntrusted, err)
+}
+
+func TestSignatureReplay(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	a := &Authenticator{Secrets: []string{"secret"}, Now: func() time.Time { return now }}
+	signature := Sign("secret", "GET", "/reports?month=5", "alice", "", now)
+
+	for _, tc := range []struct {
+		method, target string
+		err            error
+	}{
+		{"GET", "/reports?month=5", nil},
+		{"GET", "/reports?month=6", ErrUntrusted},
+		{"GET", "/admin/users", ErrUntrusted},
+		{"DELETE", "/reports?month=5", ErrUntrusted},
+	} {
+		r := httptest.NewRequest(tc.method, tc.target, nil)
+		r.Header.Set("X-Forwarded-User", "alice")
+		r.Header.Set("X-Forwarded-Signature", signature)
+		_, err := a.Authenticate(r)
+		assert.Equal(t, tc.err, err, tc.method+" "+tc.target)
+	}
+}
@@ -0,0 +1,42 @@
+// Package reqbody buffers request bodies for middlewares that have to check
+// the body before the handler reads it.
+package reqbody
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxSize is used when the maximum size is zero.
+const DefaultMaxSize = 10 << 20
+
+// ErrTooLarge is returned for bodies larger than the maximum size.
+var ErrTooLarge = errors.New("request body is too large")
+
+// Read reads the body and replaces it with a reader of the same bytes, so the
+// handler can read it again. Zero max means DefaultMaxSize, a negative max
+// means no limit.
+func Read(r *http.Request, max int64) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	if max == 0 {
+		max = DefaultMaxSize
+	}
+	reader := io.Reader(r.Body)
+	if max > 0 {
+		reader = io.LimitReader(r.Body, max+1)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if max > 0 && int64(len(body)) > max {
+		return nil, ErrTooLarge
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
@@ -0,0 +1,30 @@
+package reqbody
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestRead(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", strings.NewReader("hello"))
+	body, err := Read(req, 0)
+	assert.NilError(t, err)
+	assert.Equal(t, "hello", string(body))
+
+	again, _ := io.ReadAll(req.Body)
+	assert.Equal(t, "hello", string(again))
+
+	req = httptest.NewRequest("POST", "/", strings.NewReader("hello"))
+	_, err = Read(req, 4)
+	assert.Assert(t, errors.Is(err, ErrTooLarge))
+
+	req = httptest.NewRequest("GET", "/", nil)
+	body, err = Read(req, 0)
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(body))
+}
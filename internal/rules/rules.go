@@ -0,0 +1,279 @@
+// Package rules holds the restricted url and method model that is shared by
+// all middlewares of this module. It mirrors the RestrictedMethods,
+// RestrictedUrls and RequireAuthForAll fields of basicauth.Config.
+package rules
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/clientip"
+	"github.com/golanguzb70/middleware/internal/schedule"
+)
+
+// Policy decides whether a request has to be authenticated.
+type Policy struct {
+	// If this field is set to true, all the requests are authenticated.
+	RequireAuthForAll bool
+	// Requests with one of these methods are authenticated.
+	RestrictedMethods []string
+	// Requests with one of these urls are authenticated.
+	// For example, /v1/user, /v1/user/{key}, /v1/admin/*
+	RestrictedUrls []string
+	// Requests that match one of these conditions are authenticated.
+	RestrictedRules []Condition
+	// TrustedProxies may tell the scheme of the request in X-Forwarded-Proto header.
+	TrustedProxies clientip.Set
+}
+
+// Required reports whether a request with given method and url path must be authenticated.
+func (p Policy) Required(method, path string) bool {
+	if p.RequireAuthForAll {
+		return true
+	}
+
+	for _, m := range p.RestrictedMethods {
+		if m == method {
+			return true
+		}
+	}
+
+	for _, e := range p.RestrictedUrls {
+		if MatchURL(e, path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Explain returns the field that requires the request to be authenticated, e.g. "restricted_urls /admin/*"
+// or "restricted_rules[1]". It is empty if the request need not be authenticated.
+func (p Policy) Explain(r *http.Request) string {
+	if p.RequireAuthForAll {
+		return "require_auth_for_all"
+	}
+	for _, m := range p.RestrictedMethods {
+		if m == r.Method {
+			return "restricted_methods " + m
+		}
+	}
+	for _, e := range p.RestrictedUrls {
+		if MatchURL(e, r.URL.Path) {
+			return "restricted_urls " + e
+		}
+	}
+	for i := range p.RestrictedRules {
+		if p.RestrictedRules[i].matches(r, p.TrustedProxies) {
+			return fmt.Sprintf("restricted_rules[%d]", i)
+		}
+	}
+	return ""
+}
+
+// Condition matches requests. Every field that is set must match, empty fields match every request.
+type Condition struct {
+	// Methods such as GET or POST.
+	Methods []string `json:"methods,omitempty"`
+	// Urls with the same patterns as RestrictedUrls: /v1/user, /v1/user/{key}, /v1/user/*
+	Urls []string `json:"urls,omitempty"`
+	// Hosts such as api.example.com. A leading wildcard matches every subdomain: *.example.com.
+	// Ports are ignored and letter case does not matter.
+	Hosts []string `json:"hosts,omitempty"`
+	// Schemes are http or https. The scheme is https if the connection uses TLS,
+	// or if a trusted proxy says so in X-Forwarded-Proto header.
+	Schemes []string `json:"schemes,omitempty"`
+	// Query parameters the request must have with the value. The value "*" matches any value.
+	Query map[string]string `json:"query,omitempty"`
+	// Headers the request must have with the value. The value "*" matches any value.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+func (c *Condition) matches(r *http.Request, trustedProxies clientip.Set) bool {
+	if len(c.Methods) > 0 && !contains(c.Methods, r.Method) {
+		return false
+	}
+	if len(c.Urls) > 0 {
+		matched := false
+		for _, u := range c.Urls {
+			if MatchURL(u, r.URL.Path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(c.Hosts) > 0 {
+		matched := false
+		for _, h := range c.Hosts {
+			if MatchHost(h, r.Host) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(c.Schemes) > 0 {
+		scheme := Scheme(r, trustedProxies)
+		matched := false
+		for _, s := range c.Schemes {
+			if strings.EqualFold(s, scheme) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	query := r.URL.Query()
+	for name, value := range c.Query {
+		if !matchValues(query[name], value) {
+			return false
+		}
+	}
+	for name, value := range c.Headers {
+		if !matchValues(r.Header.Values(name), value) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchValues(values []string, want string) bool {
+	if len(values) == 0 {
+		return false
+	}
+	if want == "*" {
+		return true
+	}
+	return contains(values, want)
+}
+
+// MatchHost checks the host of a request against a pattern such as api.example.com or *.example.com.
+func MatchHost(pattern, host string) bool {
+	pattern = strings.ToLower(stripPort(pattern))
+	host = strings.ToLower(stripPort(host))
+	if pattern == "*" {
+		return true
+	}
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, strings.TrimPrefix(pattern, "*"))
+	}
+	return pattern == host
+}
+
+func stripPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// Scheme returns https if the request came over TLS, or if the direct peer is a trusted proxy
+// that sets X-Forwarded-Proto to https. Otherwise it returns http.
+func Scheme(r *http.Request, trustedProxies clientip.Set) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	if peer, ok := clientip.Peer(r); ok && trustedProxies.Contains(peer) {
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+			return strings.ToLower(strings.TrimSpace(proto))
+		}
+	}
+	return "http"
+}
+
+// Rule limits when matching requests are allowed, e.g. endpoints that contractors may call only during business hours.
+type Rule struct {
+	// Condition selects the requests the rule applies to. Empty fields match every request.
+	// Its fields are inlined in JSON: {"urls": ["/reports/*"], "roles": ["contractor"], "window": {...}}.
+	Condition
+	// Users and Roles the rule applies to: callers with one of the names or one of the roles.
+	// If both are empty, the rule applies to every caller, authenticated or not.
+	Users []string `json:"users,omitempty"`
+	Roles []string `json:"roles,omitempty"`
+	// Window is when matching requests are allowed.
+	Window *schedule.Window `json:"window,omitempty"`
+}
+
+// Matches reports whether the rule applies to a request of the principal. The principal is nil for anonymous requests.
+func (r *Rule) Matches(req *http.Request, p *auth.Principal, trustedProxies clientip.Set) bool {
+	if !r.Condition.matches(req, trustedProxies) {
+		return false
+	}
+	if len(r.Users) == 0 && len(r.Roles) == 0 {
+		return true
+	}
+	if p == nil {
+		return false
+	}
+	if contains(r.Users, p.Name) {
+		return true
+	}
+	for _, role := range p.Roles {
+		if contains(r.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// Denied returns the index of the first rule that matches the request and does not allow it at now, or -1.
+func Denied(rules []Rule, r *http.Request, p *auth.Principal, trustedProxies clientip.Set, now time.Time) int {
+	for i := range rules {
+		if rules[i].Window != nil && rules[i].Matches(r, p, trustedProxies) && !rules[i].Window.Contains(now) {
+			return i
+		}
+	}
+	return -1
+}
+
+// MatchURL checks url path against one restricted url pattern.
+// if /v1/user is given, url is checked for equality.
+// if /v1/user/{key} is given, url is checked for the urls starting with /v1/user and one other key.
+// if /v1/user/* is given, url is checked for all the urls that contain '/v1/user'.
+func MatchURL(pattern, url string) bool {
+	switch {
+	case strings.Contains(pattern, "*"):
+		return strings.Contains(url, strings.TrimSuffix(pattern, "/*"))
+	case strings.Contains(pattern, "{"):
+		return parent(pattern) == parent(url)
+	default:
+		return pattern == url
+	}
+}
+
+// MatchPrefix checks url path against a path prefix on segment boundaries:
+// /admin matches /admin and /admin/users but not /administrator.
+func MatchPrefix(prefix, path string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+func parent(path string) string {
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		return ""
+	}
+	return path[:i]
+}
+
+func contains(arr []string, val string) bool {
+	for _, item := range arr {
+		if item == val {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package rules
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/clientip"
+	"github.com/golanguzb70/middleware/internal/schedule"
+	"gotest.tools/assert"
+)
+
+func TestMatchURL(t *testing.T) {
+	assert.Assert(t, MatchURL("/v1/user", "/v1/user"))
+	assert.Assert(t, !MatchURL("/v1/user", "/v1/user/1"))
+	assert.Assert(t, MatchURL("/v1/user/{id}", "/v1/user/1"))
+	assert.Assert(t, !MatchURL("/v1/user/{id}", "/v1/user/1/orders"))
+	assert.Assert(t, MatchURL("/v1/admin/*", "/v1/admin/users/1"))
+}
+
+func TestPolicy(t *testing.T) {
+	p := Policy{
+		RestrictedMethods: []string{"DELETE"},
+		RestrictedUrls:    []string{"/admin/*"},
+		RestrictedRules: []Condition{
+			{Hosts: []string{"*.internal.example.com"}},
+			{Urls: []string{"/reports/*"}, Query: map[string]string{"format": "csv"}},
+		},
+	}
+
+	r := httptest.NewRequest("GET", "http://www.example.com/reports/1", nil)
+	assert.Equal(t, "", p.Explain(r))
+	assert.Equal(t, "restricted_rules[0]", p.Explain(httptest.NewRequest("GET", "http://api.internal.example.com:8080/", nil)))
+	assert.Equal(t, "restricted_rules[1]", p.Explain(httptest.NewRequest("GET", "http://www.example.com/reports/1?format=csv", nil)))
+	assert.Equal(t, "restricted_methods DELETE", p.Explain(httptest.NewRequest("DELETE", "/admin/users", nil)))
+	assert.Equal(t, "restricted_urls /admin/*", p.Explain(httptest.NewRequest("GET", "/admin/users", nil)))
+}
+
+func TestCondition(t *testing.T) {
+	c := Condition{
+		Methods: []string{"POST"},
+		Hosts:   []string{"API.example.com"},
+		Schemes: []string{"https"},
+		Headers: map[string]string{"X-Internal-Call": "*"},
+	}
+
+	r := httptest.NewRequest("POST", "https://api.example.com/orders", nil)
+	assert.Assert(t, !c.matches(r, nil))
+	r.Header.Set("X-Internal-Call", "billing")
+	assert.Assert(t, c.matches(r, nil))
+
+	r.TLS = nil
+	assert.Assert(t, !c.matches(r, nil))
+
+	// a trusted proxy terminated TLS
+	trusted, _ := clientip.ParseSet([]string{"10.0.0.0/8"})
+	r.Header.Set("X-Forwarded-Proto", "https")
+	assert.Assert(t, !c.matches(r, trusted))
+	r.RemoteAddr = "10.0.0.1:1234"
+	assert.Assert(t, c.matches(r, trusted))
+
+	r.Host = "www.example.com"
+	assert.Assert(t, !c.matches(r, trusted))
+}
+
+func TestMatchHost(t *testing.T) {
+	assert.Assert(t, MatchHost("*.example.com", "a.b.example.com"))
+	assert.Assert(t, !MatchHost("*.example.com", "example.com"))
+	assert.Assert(t, !MatchHost("*.example.com", "badexample.com"))
+	assert.Assert(t, MatchHost("example.com:443", "EXAMPLE.com"))
+	assert.Assert(t, MatchHost("*", "anything"))
+}
+
+func TestDenied(t *testing.T) {
+	night := &schedule.Window{Times: []string{"22:00-06:00"}}
+	rules := []Rule{
+		{Condition: Condition{Urls: []string{"/batch/*"}, Hosts: []string{"jobs.example.com"}}, Window: night},
+		{Condition: Condition{Urls: []string{"/reports/*"}}, Roles: []string{"contractor"}, Window: &schedule.Window{Times: []string{"09:00-18:00"}}},
+	}
+	noon := time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC)
+	evening := time.Date(2025, 3, 3, 20, 0, 0, 0, time.UTC)
+	contractor := &auth.Principal{Name: "c1", Roles: []string{"contractor"}}
+
+	batch := httptest.NewRequest("POST", "http://jobs.example.com/batch/run", nil)
+	assert.Equal(t, 0, Denied(rules, batch, nil, nil, noon))
+	assert.Equal(t, -1, Denied(rules, httptest.NewRequest("POST", "http://www.example.com/batch/run", nil), nil, nil, noon))
+
+	reports := httptest.NewRequest("GET", "/reports/1", nil)
+	assert.Equal(t, -1, Denied(rules, reports, contractor, nil, noon))
+	assert.Equal(t, 1, Denied(rules, reports, contractor, nil, evening))
+	assert.Equal(t, -1, Denied(rules, reports, &auth.Principal{Name: "admin"}, nil, evening))
+	assert.Equal(t, -1, Denied(rules, reports, nil, nil, evening))
+}
+
+func TestRuleJSON(t *testing.T) {
+	var rule Rule
+	assert.NilError(t, json.Unmarshal([]byte(`{"urls":["/reports/*"],"hosts":["*.example.com"],"roles":["contractor"]}`), &rule))
+	assert.DeepEqual(t, Rule{
+		Condition: Condition{Urls: []string{"/reports/*"}, Hosts: []string{"*.example.com"}},
+		Roles:     []string{"contractor"},
+	}, rule)
+}
+
+func TestMatchPrefix(t *testing.T) {
+	assert.Assert(t, MatchPrefix("/admin", "/admin"))
+	assert.Assert(t, MatchPrefix("/admin", "/admin/users"))
+	assert.Assert(t, MatchPrefix("/admin/", "/admin/users"))
+	assert.Assert(t, !MatchPrefix("/admin", "/administrator"))
+	assert.Assert(t, !MatchPrefix("/admin", "/"))
+	assert.Assert(t, MatchPrefix("/", "/billing"))
+}
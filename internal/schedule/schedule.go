@@ -0,0 +1,173 @@
+// Package schedule decides whether a point in time is inside an access
+// window made of week days, times of day and date ranges in a timezone.
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Window is a recurring access window. Empty fields do not restrict:
+// a window with only Times is open at those times every day.
+type Window struct {
+	// Days are week days, e.g. "mon", "tue" or "saturday".
+	Days []string `json:"days,omitempty"`
+	// Times are ranges of the time of day such as "09:00-17:00". The end is exclusive.
+	// A range may cross midnight, e.g. "22:00-06:00"; the part after midnight belongs to the day it started,
+	// for Days and Dates alike.
+	Times []string `json:"times,omitempty"`
+	// Timezone is an IANA name such as "Europe/Berlin" the days, times and dates are in. Default is UTC.
+	Timezone string `json:"timezone,omitempty"`
+	// Dates are inclusive date ranges such as "2025-01-01/2025-03-31" or single days such as "2025-12-24".
+	Dates []string `json:"dates,omitempty"`
+
+	once     sync.Once
+	compiled *compiled
+	err      error
+}
+
+type compiled struct {
+	loc   *time.Location
+	days  map[time.Weekday]bool
+	times []timeRange
+	dates []dateRange
+}
+
+type timeRange struct {
+	start, end int // minutes since midnight
+}
+
+type dateRange struct {
+	from, to string // 2006-01-02, compared as strings
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Validate reports invalid days, times, dates and timezone.
+func (w *Window) Validate() error {
+	_, err := w.compile()
+	return err
+}
+
+// Contains reports whether t is inside the window. Invalid windows contain nothing.
+func (w *Window) Contains(t time.Time) bool {
+	c, err := w.compile()
+	if err != nil {
+		return false
+	}
+	t = t.In(c.loc)
+
+	if len(c.times) == 0 {
+		return c.onDay(t)
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	for _, r := range c.times {
+		if r.start < r.end {
+			if c.onDay(t) && r.start <= minute && minute < r.end {
+				return true
+			}
+			continue
+		}
+		// the range crosses midnight, the part after midnight belongs to the day before
+		if c.onDay(t) && minute >= r.start {
+			return true
+		}
+		if c.onDay(t.AddDate(0, 0, -1)) && minute < r.end {
+			return true
+		}
+	}
+	return false
+}
+
+// onDay reports whether the week day and the date of t are in the window.
+func (c *compiled) onDay(t time.Time) bool {
+	if len(c.dates) > 0 && !c.inDates(t) {
+		return false
+	}
+	return len(c.days) == 0 || c.days[t.Weekday()]
+}
+
+func (c *compiled) inDates(t time.Time) bool {
+	date := t.Format("2006-01-02")
+	for _, r := range c.dates {
+		if r.from <= date && date <= r.to {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Window) compile() (*compiled, error) {
+	w.once.Do(func() {
+		w.compiled, w.err = w.parse()
+	})
+	return w.compiled, w.err
+}
+
+func (w *Window) parse() (*compiled, error) {
+	c := &compiled{loc: time.UTC, days: map[time.Weekday]bool{}}
+
+	if w.Timezone != "" {
+		loc, err := time.LoadLocation(w.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("schedule: invalid timezone %q", w.Timezone)
+		}
+		c.loc = loc
+	}
+
+	for _, d := range w.Days {
+		name := strings.ToLower(strings.TrimSpace(d))
+		if len(name) < 3 {
+			return nil, fmt.Errorf("schedule: invalid day %q", d)
+		}
+		day, ok := weekdays[name[:3]]
+		if !ok || !strings.HasPrefix(strings.ToLower(day.String()), name) {
+			return nil, fmt.Errorf("schedule: invalid day %q", d)
+		}
+		c.days[day] = true
+	}
+
+	for _, tr := range w.Times {
+		start, end, ok := strings.Cut(strings.TrimSpace(tr), "-")
+		s, err1 := parseClock(start)
+		e, err2 := parseClock(end)
+		if !ok || err1 != nil || err2 != nil || s == e {
+			return nil, fmt.Errorf("schedule: invalid time range %q", tr)
+		}
+		c.times = append(c.times, timeRange{start: s, end: e})
+	}
+
+	for _, dr := range w.Dates {
+		from, to, ok := strings.Cut(strings.TrimSpace(dr), "/")
+		if !ok {
+			to = from
+		}
+		f, err1 := time.Parse("2006-01-02", from)
+		t, err2 := time.Parse("2006-01-02", to)
+		if err1 != nil || err2 != nil || t.Before(f) {
+			return nil, fmt.Errorf("schedule: invalid date range %q", dr)
+		}
+		c.dates = append(c.dates, dateRange{from: from, to: to})
+	}
+
+	return c, nil
+}
+
+// parseClock parses "15:04" into minutes since midnight. "24:00" is the end of the day.
+func parseClock(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,74 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestWindow(t *testing.T) {
+	w := &Window{
+		Days:     []string{"mon", "Tuesday", "wed", "thu", "fri"},
+		Times:    []string{"09:00-12:00", "13:00-17:30"},
+		Timezone: "Asia/Tashkent",
+	}
+	assert.NilError(t, w.Validate())
+
+	loc, _ := time.LoadLocation("Asia/Tashkent")
+	for _, tc := range []struct {
+		t    time.Time
+		open bool
+	}{
+		{time.Date(2025, 3, 3, 9, 0, 0, 0, loc), true},   // monday
+		{time.Date(2025, 3, 3, 12, 0, 0, 0, loc), false}, // lunch
+		{time.Date(2025, 3, 3, 17, 29, 0, 0, loc), true},
+		{time.Date(2025, 3, 3, 17, 30, 0, 0, loc), false},
+		{time.Date(2025, 3, 1, 10, 0, 0, 0, loc), false}, // saturday
+		// 04:30 UTC is 09:30 in Tashkent
+		{time.Date(2025, 3, 3, 4, 30, 0, 0, time.UTC), true},
+	} {
+		assert.Equal(t, tc.open, w.Contains(tc.t), tc.t.String())
+	}
+}
+
+func TestOvernight(t *testing.T) {
+	w := &Window{Days: []string{"fri"}, Times: []string{"22:00-06:00"}}
+
+	assert.Assert(t, w.Contains(time.Date(2025, 3, 7, 23, 0, 0, 0, time.UTC)))  // friday night
+	assert.Assert(t, w.Contains(time.Date(2025, 3, 8, 5, 59, 0, 0, time.UTC)))  // saturday morning
+	assert.Assert(t, !w.Contains(time.Date(2025, 3, 7, 5, 0, 0, 0, time.UTC)))  // friday morning
+	assert.Assert(t, !w.Contains(time.Date(2025, 3, 8, 23, 0, 0, 0, time.UTC))) // saturday night
+}
+
+func TestDates(t *testing.T) {
+	w := &Window{Dates: []string{"2025-01-01/2025-03-31", "2025-12-24"}}
+
+	assert.Assert(t, w.Contains(time.Date(2025, 3, 31, 23, 59, 0, 0, time.UTC)))
+	assert.Assert(t, !w.Contains(time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Assert(t, w.Contains(time.Date(2025, 12, 24, 12, 0, 0, 0, time.UTC)))
+}
+
+func TestOvernightDates(t *testing.T) {
+	// the night of the last day of the range ends the next morning
+	w := &Window{Times: []string{"22:00-06:00"}, Dates: []string{"2025-03-01/2025-03-31"}}
+
+	assert.Assert(t, w.Contains(time.Date(2025, 4, 1, 5, 0, 0, 0, time.UTC)))
+	assert.Assert(t, !w.Contains(time.Date(2025, 4, 1, 23, 0, 0, 0, time.UTC)))
+	assert.Assert(t, !w.Contains(time.Date(2025, 3, 1, 5, 0, 0, 0, time.UTC)))
+	assert.Assert(t, w.Contains(time.Date(2025, 3, 1, 23, 0, 0, 0, time.UTC)))
+}
+
+func TestInvalid(t *testing.T) {
+	for _, w := range []*Window{
+		{Days: []string{"funday"}},
+		{Days: []string{"mo"}},
+		{Times: []string{"9-17"}},
+		{Times: []string{"09:00-09:00"}},
+		{Timezone: "Mars/Olympus"},
+		{Dates: []string{"2025-03-31/2025-01-01"}},
+	} {
+		assert.Assert(t, w.Validate() != nil)
+		assert.Assert(t, !w.Contains(time.Now()))
+	}
+}
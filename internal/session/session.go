@@ -0,0 +1,209 @@
+// Package session keeps the signed-in user in an encrypted and authenticated
+// cookie, so browsers do not have to send Basic credentials with every request.
+//
+// Cookies are sealed with AES-256-GCM. The key of a secret is its SHA-256
+// digest. New cookies are sealed with the first secret, every secret is tried
+// when a cookie is opened, so secrets can be rotated by adding a new one in
+// front of the old one.
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+)
+
+var (
+	ErrNoSecrets = errors.New("session: no secrets are configured")
+	ErrInvalid   = errors.New("session: invalid session cookie")
+	ErrExpired   = errors.New("session: session is expired")
+)
+
+// Config of session cookies.
+type Config struct {
+	// Secrets encrypt and authenticate session cookies. New cookies use the first secret,
+	// cookies of the others are still accepted, so a secret can be rotated by adding a new one in front.
+	Secrets []string `json:"secrets"`
+	// CookieName is the name of the session cookie. Default is "session".
+	CookieName string `json:"cookie_name"`
+	// IdleTimeout ends sessions without requests for this long. Default is 30 minutes.
+	IdleTimeout time.Duration `json:"idle_timeout"`
+	// AbsoluteTimeout ends sessions this long after login whatever the activity. Default is 12 hours.
+	AbsoluteTimeout time.Duration `json:"absolute_timeout"`
+	// If this field is set to true, the cookie is sent over plain HTTP too. Use it only in development.
+	InsecureCookie bool `json:"insecure_cookie"`
+	// If this field is set to true, 401 responses carry no Basic challenge, so browsers do not show their login prompt.
+	SuppressChallenge bool `json:"suppress_challenge"`
+}
+
+// Session is the content of a session cookie.
+type Session struct {
+	User     string    `json:"user"`
+	Created  time.Time `json:"created"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// Manager issues and reads session cookies.
+type Manager struct {
+	Config Config
+	// Now is used instead of time.Now when set.
+	Now func() time.Time
+
+	once  sync.Once
+	aeads []cipher.AEAD
+}
+
+// Issue starts a session of the user.
+func (m *Manager) Issue(w http.ResponseWriter, user string) error {
+	now := m.now()
+	return m.write(w, &Session{User: user, Created: now, LastSeen: now})
+}
+
+// Read returns the valid session of the request.
+// Without a session cookie the error is auth.ErrNoCredentials.
+func (m *Manager) Read(r *http.Request) (*Session, error) {
+	cookie, err := r.Cookie(m.cookieName())
+	if err != nil || cookie.Value == "" {
+		return nil, auth.ErrNoCredentials
+	}
+	s, err := m.open(cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	now := m.now()
+	if now.Sub(s.Created) > m.absoluteTimeout() || now.Sub(s.LastSeen) > m.idleTimeout() {
+		return nil, ErrExpired
+	}
+	return s, nil
+}
+
+// Refresh extends the idle timeout of the session. The cookie is rewritten
+// at most once a minute, and then it is sealed with the current secret.
+func (m *Manager) Refresh(w http.ResponseWriter, s *Session) error {
+	now := m.now()
+	interval := m.idleTimeout() / 2
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	if now.Sub(s.LastSeen) < interval {
+		return nil
+	}
+	refreshed := *s
+	refreshed.LastSeen = now
+	return m.write(w, &refreshed)
+}
+
+// Clear removes the session cookie.
+func (m *Manager) Clear(w http.ResponseWriter) {
+	http.SetCookie(w, m.cookie("", -1))
+}
+
+func (m *Manager) write(w http.ResponseWriter, s *Session) error {
+	value, err := m.seal(s)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, m.cookie(value, int(m.absoluteTimeout().Seconds())))
+	return nil
+}
+
+func (m *Manager) cookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     m.cookieName(),
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   !m.Config.InsecureCookie,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func (m *Manager) seal(s *Session) (string, error) {
+	aeads := m.keys()
+	if len(aeads) == 0 {
+		return "", ErrNoSecrets
+	}
+	plain, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aeads[0].NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aeads[0].Seal(nonce, nonce, plain, []byte(m.cookieName()))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (m *Manager) open(value string) (*Session, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	for _, aead := range m.keys() {
+		if len(sealed) < aead.NonceSize() {
+			return nil, ErrInvalid
+		}
+		plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(m.cookieName()))
+		if err != nil {
+			continue
+		}
+		var s Session
+		if err := json.Unmarshal(plain, &s); err != nil {
+			return nil, ErrInvalid
+		}
+		return &s, nil
+	}
+	return nil, ErrInvalid
+}
+
+func (m *Manager) keys() []cipher.AEAD {
+	m.once.Do(func() {
+		for _, secret := range m.Config.Secrets {
+			key := sha256.Sum256([]byte(secret))
+			block, _ := aes.NewCipher(key[:])
+			aead, _ := cipher.NewGCM(block)
+			m.aeads = append(m.aeads, aead)
+		}
+	})
+	return m.aeads
+}
+
+func (m *Manager) cookieName() string {
+	if m.Config.CookieName != "" {
+		return m.Config.CookieName
+	}
+	return "session"
+}
+
+func (m *Manager) idleTimeout() time.Duration {
+	if m.Config.IdleTimeout > 0 {
+		return m.Config.IdleTimeout
+	}
+	return 30 * time.Minute
+}
+
+func (m *Manager) absoluteTimeout() time.Duration {
+	if m.Config.AbsoluteTimeout > 0 {
+		return m.Config.AbsoluteTimeout
+	}
+	return 12 * time.Hour
+}
+
+func (m *Manager) now() time.Time {
+	if m.Now != nil {
+		return m.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,110 @@
+package session
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"gotest.tools/assert"
+)
+
+// requestWith returns a request carrying the cookies set on w.
+func requestWith(w *httptest.ResponseRecorder) *http.Request {
+	r := httptest.NewRequest("GET", "/", nil)
+	for _, c := range w.Result().Cookies() {
+		r.AddCookie(c)
+	}
+	return r
+}
+
+func TestIssueAndRead(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	m := &Manager{Config: Config{Secrets: []string{"secret"}}, Now: func() time.Time { return now }}
+
+	_, err := m.Read(httptest.NewRequest("GET", "/", nil))
+	assert.Assert(t, errors.Is(err, auth.ErrNoCredentials))
+
+	w := httptest.NewRecorder()
+	assert.NilError(t, m.Issue(w, "alice"))
+	cookie := w.Result().Cookies()[0]
+	assert.Equal(t, "session", cookie.Name)
+	assert.Assert(t, cookie.HttpOnly && cookie.Secure)
+
+	s, err := m.Read(requestWith(w))
+	assert.NilError(t, err)
+	assert.Equal(t, "alice", s.User)
+
+	// tampered cookie
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "session", Value: cookie.Value[:len(cookie.Value)-2] + "AA"})
+	_, err = m.Read(r)
+	assert.Assert(t, errors.Is(err, ErrInvalid))
+
+	// cookie of another name can not be replayed as the session cookie
+	other := &Manager{Config: Config{Secrets: []string{"secret"}, CookieName: "other"}, Now: m.Now}
+	r = httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "other", Value: cookie.Value})
+	_, err = other.Read(r)
+	assert.Assert(t, errors.Is(err, ErrInvalid))
+}
+
+func TestTimeouts(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	m := &Manager{
+		Config: Config{Secrets: []string{"secret"}, IdleTimeout: 10 * time.Minute, AbsoluteTimeout: time.Hour},
+		Now:    func() time.Time { return now },
+	}
+
+	w := httptest.NewRecorder()
+	assert.NilError(t, m.Issue(w, "alice"))
+	r := requestWith(w)
+
+	// activity keeps the session alive until the absolute timeout
+	for i := 0; i < 6; i++ {
+		now = now.Add(9 * time.Minute)
+		s, err := m.Read(r)
+		assert.NilError(t, err)
+		w = httptest.NewRecorder()
+		assert.NilError(t, m.Refresh(w, s))
+		r = requestWith(w)
+	}
+	now = now.Add(7 * time.Minute)
+	_, err := m.Read(r)
+	assert.Assert(t, errors.Is(err, ErrExpired))
+
+	// idle session
+	now = time.Unix(1700000000, 0)
+	w = httptest.NewRecorder()
+	assert.NilError(t, m.Issue(w, "alice"))
+	now = now.Add(11 * time.Minute)
+	_, err = m.Read(requestWith(w))
+	assert.Assert(t, errors.Is(err, ErrExpired))
+}
+
+func TestRotation(t *testing.T) {
+	old := &Manager{Config: Config{Secrets: []string{"old"}}}
+	w := httptest.NewRecorder()
+	assert.NilError(t, old.Issue(w, "alice"))
+
+	rotated := &Manager{Config: Config{Secrets: []string{"new", "old"}}}
+	_, err := rotated.Read(requestWith(w))
+	assert.NilError(t, err)
+
+	removed := &Manager{Config: Config{Secrets: []string{"new"}}}
+	_, err = removed.Read(requestWith(w))
+	assert.Assert(t, errors.Is(err, ErrInvalid))
+
+	assert.Assert(t, errors.Is((&Manager{}).Issue(httptest.NewRecorder(), "alice"), ErrNoSecrets))
+}
+
+func TestClear(t *testing.T) {
+	m := &Manager{Config: Config{Secrets: []string{"secret"}}}
+	w := httptest.NewRecorder()
+	m.Clear(w)
+	cookie := w.Result().Cookies()[0]
+	assert.Equal(t, "", cookie.Value)
+	assert.Assert(t, cookie.MaxAge < 0)
+}
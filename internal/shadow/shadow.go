@@ -0,0 +1,130 @@
+// Package shadow compares the decisions of an enforced basicauth config with
+// the decisions a new config would make about the same requests.
+package shadow
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/basic"
+)
+
+// Divergence is a request the shadow config decides differently from the enforced config.
+type Divergence struct {
+	Time     time.Time       `json:"time"`
+	Enforced *basic.Decision `json:"enforced"`
+	Shadow   *basic.Decision `json:"shadow"`
+}
+
+// Kind groups divergences with the same outcome, e.g. requests the enforced config lets through
+// and the shadow config rejects with 401 status because of "restricted_urls /admin/*".
+type Kind struct {
+	EnforcedStatus      int    `json:"enforced_status"`
+	EnforcedRestriction string `json:"enforced_restriction,omitempty"`
+	EnforcedRule        int    `json:"enforced_rule"`
+	ShadowStatus        int    `json:"shadow_status"`
+	ShadowRestriction   string `json:"shadow_restriction,omitempty"`
+	ShadowRule          int    `json:"shadow_rule"`
+	Count               int    `json:"count"`
+}
+
+// Summary is what a Recorder has seen since it was created.
+type Summary struct {
+	// Requests is the number of compared requests.
+	Requests int `json:"requests"`
+	// Divergences is the number of requests the configs decided differently.
+	Divergences int `json:"divergences"`
+	// Kinds are the divergences grouped by outcome, the most frequent first.
+	Kinds []Kind `json:"kinds"`
+	// Recent are the latest divergences, the oldest first.
+	Recent []*Divergence `json:"recent"`
+}
+
+// Recorder records the requests the enforced and the shadow config decide differently.
+// It is safe for concurrent use, and it serves the summary as JSON.
+type Recorder struct {
+	// Keep is the number of the latest divergences kept in the summary. Default is 100.
+	Keep int
+	// OnDivergence is called with every divergence, e.g. to log it.
+	OnDivergence func(*Divergence)
+
+	mu          sync.Mutex
+	requests    int
+	divergences int
+	kinds       map[Kind]int
+	recent      []*Divergence
+}
+
+// Record compares two decisions about a request. It returns the divergence, or nil if the statuses are the same.
+func (r *Recorder) Record(enforced, shadow *basic.Decision) *Divergence {
+	r.mu.Lock()
+	r.requests++
+	if enforced.Status == shadow.Status {
+		r.mu.Unlock()
+		return nil
+	}
+
+	d := &Divergence{Time: time.Now(), Enforced: enforced, Shadow: shadow}
+	r.divergences++
+	if r.kinds == nil {
+		r.kinds = map[Kind]int{}
+	}
+	r.kinds[Kind{
+		EnforcedStatus:      enforced.Status,
+		EnforcedRestriction: enforced.Restriction,
+		EnforcedRule:        enforced.Rule,
+		ShadowStatus:        shadow.Status,
+		ShadowRestriction:   shadow.Restriction,
+		ShadowRule:          shadow.Rule,
+	}]++
+	r.recent = append(r.recent, d)
+	if n := len(r.recent) - r.keep(); n > 0 {
+		r.recent = append(r.recent[:0:0], r.recent[n:]...)
+	}
+	r.mu.Unlock()
+
+	if r.OnDivergence != nil {
+		r.OnDivergence(d)
+	}
+	return d
+}
+
+// Summary returns the counts and the latest divergences.
+func (r *Recorder) Summary() Summary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := Summary{
+		Requests:    r.requests,
+		Divergences: r.divergences,
+		Kinds:       make([]Kind, 0, len(r.kinds)),
+		Recent:      append([]*Divergence{}, r.recent...),
+	}
+	for k, count := range r.kinds {
+		k.Count = count
+		s.Kinds = append(s.Kinds, k)
+	}
+	sort.Slice(s.Kinds, func(i, j int) bool {
+		if s.Kinds[i].Count != s.Kinds[j].Count {
+			return s.Kinds[i].Count > s.Kinds[j].Count
+		}
+		return s.Kinds[i].ShadowRestriction < s.Kinds[j].ShadowRestriction
+	})
+	return s
+}
+
+// ServeHTTP writes the summary as JSON.
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(r.Summary())
+}
+
+func (r *Recorder) keep() int {
+	if r.Keep > 0 {
+		return r.Keep
+	}
+	return 100
+}
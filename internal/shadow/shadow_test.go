@@ -0,0 +1,46 @@
+package shadow
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golanguzb70/middleware/internal/basic"
+	"gotest.tools/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	var seen []*Divergence
+	r := &Recorder{Keep: 2, OnDivergence: func(d *Divergence) { seen = append(seen, d) }}
+
+	allow := &basic.Decision{Path: "/", Status: 200, Rule: -1}
+	assert.Assert(t, r.Record(allow, allow) == nil)
+
+	for _, path := range []string{"/admin/1", "/admin/2", "/admin/3"} {
+		d := r.Record(
+			&basic.Decision{Path: path, Status: 200, Rule: -1},
+			&basic.Decision{Path: path, Status: 401, Restriction: "restricted_urls /admin/*", Rule: -1},
+		)
+		assert.Assert(t, d != nil)
+	}
+	r.Record(
+		&basic.Decision{Path: "/reports/1", Status: 200, Rule: -1},
+		&basic.Decision{Path: "/reports/1", Status: 403, Rule: 0},
+	)
+	assert.Equal(t, 4, len(seen))
+
+	s := r.Summary()
+	assert.Equal(t, 5, s.Requests)
+	assert.Equal(t, 4, s.Divergences)
+	assert.DeepEqual(t, []Kind{
+		{EnforcedStatus: 200, EnforcedRule: -1, ShadowStatus: 401, ShadowRestriction: "restricted_urls /admin/*", ShadowRule: -1, Count: 3},
+		{EnforcedStatus: 200, EnforcedRule: -1, ShadowStatus: 403, ShadowRule: 0, Count: 1},
+	}, s.Kinds)
+	assert.Equal(t, 2, len(s.Recent))
+	assert.Equal(t, "/admin/3", s.Recent[0].Shadow.Path)
+	assert.Equal(t, "/reports/1", s.Recent[1].Shadow.Path)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Assert(t, len(w.Body.String()) > 0)
+}
@@ -0,0 +1,247 @@
+// Package signedurl signs URLs that work without credentials until they
+// expire, like pre-signed download links. It is shared by the gin and gorilla
+// signedurl middlewares.
+//
+// The signature covers the path, the signed query parameters and every
+// restriction of the link. Restrictions are query parameters of the link:
+//
+//	expires    unix time the link expires at
+//	key_id     key the link is signed with
+//	params     comma separated names of the signed query parameters
+//	method     optional HTTP method the link may be used with
+//	ip         optional client address or CIDR the link may be used from
+//	token      optional random token of a one-time link
+//	signature  base64url HMAC-SHA256 of all above
+package signedurl
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/clientip"
+	"github.com/golanguzb70/middleware/internal/nonce"
+)
+
+// Query parameters of a signed URL.
+const (
+	ExpiresParam   = "expires"
+	KeyIDParam     = "key_id"
+	ParamsParam    = "params"
+	MethodParam    = "method"
+	IPParam        = "ip"
+	TokenParam     = "token"
+	SignatureParam = "signature"
+)
+
+var (
+	ErrMalformed  = errors.New("signedurl: malformed signed url")
+	ErrUnknownKey = errors.New("signedurl: unknown key id")
+	ErrSignature  = errors.New("signedurl: signature mismatch")
+	ErrExpired    = errors.New("signedurl: link is expired")
+	ErrMethod     = errors.New("signedurl: method is not allowed")
+	ErrIP         = errors.New("signedurl: client address is not allowed")
+	ErrUsed       = errors.New("signedurl: one-time link was already used")
+)
+
+// Options of a signed URL.
+type Options struct {
+	// TTL is how long the link works.
+	TTL time.Duration
+	// Params are the names of query parameters to sign. If nil, every query parameter of the URL is signed.
+	Params []string
+	// Method restricts the link to an HTTP method.
+	Method string
+	// IP restricts the link to a client address or CIDR. It is checked against the client address
+	// ClientIP of the Verifier resolves.
+	IP string
+	// OneTime links work only once.
+	OneTime bool
+}
+
+// Sign returns the URL with the signature parameters.
+func Sign(rawURL, keyID, secret string, opts Options, now time.Time) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if opts.IP != "" {
+		if _, err := clientip.ParseSet([]string{opts.IP}); err != nil {
+			return "", err
+		}
+	}
+
+	query := u.Query()
+	names := opts.Params
+	if names == nil {
+		for name := range query {
+			names = append(names, name)
+		}
+	}
+	names = append([]string(nil), names...)
+	sort.Strings(names)
+
+	query.Set(ExpiresParam, strconv.FormatInt(now.Add(opts.TTL).Unix(), 10))
+	query.Set(KeyIDParam, keyID)
+	query.Set(ParamsParam, strings.Join(names, ","))
+	if opts.Method != "" {
+		query.Set(MethodParam, strings.ToUpper(opts.Method))
+	}
+	if opts.IP != "" {
+		query.Set(IPParam, opts.IP)
+	}
+	if opts.OneTime {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		query.Set(TokenParam, base64.RawURLEncoding.EncodeToString(b))
+	}
+	query.Set(SignatureParam, base64.RawURLEncoding.EncodeToString(signature(secret, canonical(u.EscapedPath(), query))))
+
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// Verifier checks signed URLs of requests.
+type Verifier struct {
+	// Keys maps key ids to secrets. Several keys may be active while a secret is rotated.
+	Keys map[string]string
+	// UsedTokens remembers tokens of one-time links. Default is an in-memory store.
+	UsedTokens nonce.Store
+	// ClientIP resolves the client address IP restricted links are checked against.
+	// Without trusted proxies it is the direct peer.
+	ClientIP clientip.Resolver
+	// Now is used instead of time.Now when set.
+	Now func() time.Time
+
+	once sync.Once
+}
+
+// Verify checks the signature and the restrictions of the request URL.
+// Without a signature parameter the error is auth.ErrNoCredentials.
+func (v *Verifier) Verify(r *http.Request) (*auth.Principal, error) {
+	query := r.URL.Query()
+	if query.Get(SignatureParam) == "" {
+		return nil, auth.ErrNoCredentials
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(query.Get(SignatureParam))
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	expires, err := strconv.ParseInt(query.Get(ExpiresParam), 10, 64)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	keyID := query.Get(KeyIDParam)
+	secret, ok := v.Keys[keyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if !hmac.Equal(signature(secret, canonical(r.URL.EscapedPath(), query)), sig) {
+		return nil, ErrSignature
+	}
+
+	expiresAt := time.Unix(expires, 0)
+	now := time.Now()
+	if v.Now != nil {
+		now = v.Now()
+	}
+	if !now.Before(expiresAt) {
+		return nil, ErrExpired
+	}
+	if method := query.Get(MethodParam); method != "" && method != r.Method {
+		return nil, ErrMethod
+	}
+	if ip := query.Get(IPParam); ip != "" {
+		allowed, err := clientip.ParseSet([]string{ip})
+		if err != nil {
+			return nil, ErrMalformed
+		}
+		client, ok := v.ClientIP.Client(r)
+		if !ok || !allowed.Contains(client) {
+			return nil, ErrIP
+		}
+	}
+	token := query.Get(TokenParam)
+	if token != "" && !v.usedTokens().Use(keyID, token, expiresAt) {
+		return nil, ErrUsed
+	}
+
+	principal := &auth.Principal{
+		Name:   keyID,
+		Scheme: "signedurl",
+		Attributes: map[string]string{
+			"key_id":  keyID,
+			"expires": expiresAt.UTC().Format(time.RFC3339),
+		},
+	}
+	if token != "" {
+		principal.Attributes["token"] = token
+	}
+	return principal, nil
+}
+
+// Authenticate verifies the request URL, it is the same as Verify.
+func (v *Verifier) Authenticate(r *http.Request) (*auth.Principal, error) {
+	return v.Verify(r)
+}
+
+// Challenge returns an empty value, links have no challenge.
+func (v *Verifier) Challenge(error) string {
+	return ""
+}
+
+func (v *Verifier) usedTokens() nonce.Store {
+	v.once.Do(func() {
+		if v.UsedTokens == nil {
+			v.UsedTokens = &nonce.Memory{Now: v.Now}
+		}
+	})
+	return v.UsedTokens
+}
+
+// canonical returns the signed content of a URL: the path, the signed
+// parameters and the restrictions, one per line.
+func canonical(path string, query url.Values) string {
+	var b strings.Builder
+	b.WriteString(path + "\n")
+
+	names := strings.Split(query.Get(ParamsParam), ",")
+	sort.Strings(names)
+	var params []string
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			params = append(params, url.QueryEscape(name)+"="+url.QueryEscape(value))
+		}
+	}
+	b.WriteString(strings.Join(params, "&") + "\n")
+
+	for _, name := range []string{ParamsParam, ExpiresParam, KeyIDParam, MethodParam, IPParam} {
+		b.WriteString(query.Get(name) + "\n")
+	}
+	b.WriteString(query.Get(TokenParam))
+	return b.String()
+}
+
+func signature(secret, content string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(content))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,138 @@
+package signedurl
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/clientip"
+	"gotest.tools/assert"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	v := &Verifier{
+		Keys: map[string]string{"k1": "old-secret", "k2": "new-secret"},
+		Now:  func() time.Time { return now },
+	}
+
+	link, err := Sign("https://files.example.com/downloads/report.pdf?version=2&utm=x", "k2", "new-secret", Options{
+		TTL:    time.Hour,
+		Params: []string{"version"},
+	}, now)
+	assert.NilError(t, err)
+
+	req := httptest.NewRequest("GET", link, nil)
+	principal, err := v.Verify(req)
+	assert.NilError(t, err)
+	assert.Equal(t, "k2", principal.Name)
+	assert.Equal(t, "2023-11-14T23:13:20Z", principal.Attributes["expires"])
+
+	// unsigned parameters may change, signed ones may not
+	u, _ := url.Parse(link)
+	q := u.Query()
+	q.Set("utm", "y")
+	u.RawQuery = q.Encode()
+	_, err = v.Verify(httptest.NewRequest("GET", u.String(), nil))
+	assert.NilError(t, err)
+
+	q.Set("version", "3")
+	u.RawQuery = q.Encode()
+	_, err = v.Verify(httptest.NewRequest("GET", u.String(), nil))
+	assert.Assert(t, errors.Is(err, ErrSignature))
+
+	_, err = v.Verify(httptest.NewRequest("GET", strings.Replace(link, "report.pdf", "salaries.pdf", 1), nil))
+	assert.Assert(t, errors.Is(err, ErrSignature))
+
+	now = now.Add(2 * time.Hour)
+	_, err = v.Verify(httptest.NewRequest("GET", link, nil))
+	assert.Assert(t, errors.Is(err, ErrExpired))
+}
+
+func TestRestrictions(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	v := &Verifier{Keys: map[string]string{"k1": "secret"}, Now: func() time.Time { return now }}
+
+	link, err := Sign("/downloads/report.pdf", "k1", "secret", Options{TTL: time.Minute, Method: "get", IP: "192.0.2.0/24"}, now)
+	assert.NilError(t, err)
+
+	req := httptest.NewRequest("GET", link, nil)
+	req.RemoteAddr = "192.0.2.10:4000"
+	_, err = v.Verify(req)
+	assert.NilError(t, err)
+
+	req = httptest.NewRequest("GET", link, nil)
+	req.RemoteAddr = "198.51.100.1:4000"
+	_, err = v.Verify(req)
+	assert.Assert(t, errors.Is(err, ErrIP))
+
+	req = httptest.NewRequest("DELETE", link, nil)
+	req.RemoteAddr = "192.0.2.10:4000"
+	_, err = v.Verify(req)
+	assert.Assert(t, errors.Is(err, ErrMethod))
+
+	// removing a restriction breaks the signature
+	req = httptest.NewRequest("GET", strings.Replace(link, "ip=192.0.2.0%2F24&", "", 1), nil)
+	_, err = v.Verify(req)
+	assert.Assert(t, errors.Is(err, ErrSignature))
+
+	_, err = Sign("/downloads/report.pdf", "k1", "secret", Options{IP: "not an ip"}, now)
+	assert.Assert(t, err != nil)
+}
+
+func TestIPBehindProxy(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	v := &Verifier{Keys: map[string]string{"k1": "secret"}, Now: func() time.Time { return now }}
+	link, err := Sign("/downloads/report.pdf", "k1", "secret", Options{TTL: time.Minute, IP: "192.0.2.10"}, now)
+	assert.NilError(t, err)
+
+	// without trusted proxies the header is ignored and the proxy is the client
+	req := httptest.NewRequest("GET", link, nil)
+	req.RemoteAddr = "10.0.0.1:4000"
+	req.Header.Set("X-Forwarded-For", "192.0.2.10")
+	_, err = v.Verify(req)
+	assert.Assert(t, errors.Is(err, ErrIP))
+
+	v.ClientIP = clientip.Resolver{TrustedProxies: clientip.ParseValid([]string{"10.0.0.0/8"})}
+	_, err = v.Verify(req)
+	assert.NilError(t, err)
+
+	// a client can not choose its address by sending the header itself
+	req = httptest.NewRequest("GET", link, nil)
+	req.RemoteAddr = "10.0.0.1:4000"
+	req.Header.Set("X-Forwarded-For", "192.0.2.10, 198.51.100.1")
+	_, err = v.Verify(req)
+	assert.Assert(t, errors.Is(err, ErrIP))
+}
+
+func TestOneTime(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	v := &Verifier{Keys: map[string]string{"k1": "secret"}, Now: func() time.Time { return now }}
+
+	link, err := Sign("/downloads/report.pdf", "k1", "secret", Options{TTL: time.Minute, OneTime: true}, now)
+	assert.NilError(t, err)
+
+	_, err = v.Verify(httptest.NewRequest("GET", link, nil))
+	assert.NilError(t, err)
+	_, err = v.Verify(httptest.NewRequest("GET", link, nil))
+	assert.Assert(t, errors.Is(err, ErrUsed))
+}
+
+func TestVerifyErrors(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	v := &Verifier{Keys: map[string]string{"k1": "secret"}, Now: func() time.Time { return now }}
+
+	_, err := v.Verify(httptest.NewRequest("GET", "/downloads/report.pdf", nil))
+	assert.Assert(t, errors.Is(err, auth.ErrNoCredentials))
+
+	link, _ := Sign("/downloads/report.pdf", "k9", "secret", Options{TTL: time.Minute}, now)
+	_, err = v.Verify(httptest.NewRequest("GET", link, nil))
+	assert.Assert(t, errors.Is(err, ErrUnknownKey))
+
+	_, err = v.Verify(httptest.NewRequest("GET", "/downloads/report.pdf?signature=!!", nil))
+	assert.Assert(t, errors.Is(err, ErrMalformed))
+}
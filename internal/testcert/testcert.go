@@ -0,0 +1,104 @@
+// Package testcert issues throwaway certificates for tests of the mTLS middlewares.
+package testcert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// cas are the certificates of the CAs by subject, to build the verified chains of ConnectionState.
+var cas sync.Map
+
+// CA is a certificate authority.
+type CA struct {
+	Cert   *x509.Certificate
+	Key    crypto.Signer
+	serial int64
+}
+
+// NewCA creates a self signed CA with given common name.
+func NewCA(cn string) *CA {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	cert, _ := x509.ParseCertificate(der)
+	cas.Store(string(cert.RawSubject), cert)
+	return &CA{Cert: cert, Key: key, serial: 1}
+}
+
+// Leaf describes a client certificate.
+type Leaf struct {
+	CommonName string
+	DNSNames   []string
+	URIs       []string
+}
+
+// Issue signs a client certificate.
+func (ca *CA) Issue(l Leaf) *x509.Certificate {
+	ca.serial++
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      pkix.Name{CommonName: l.CommonName},
+		DNSNames:     l.DNSNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, u := range l.URIs {
+		parsed, _ := url.Parse(u)
+		tmpl.URIs = append(tmpl.URIs, parsed)
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, tmpl, ca.Cert, key.Public(), ca.Key)
+	cert, _ := x509.ParseCertificate(der)
+	return cert
+}
+
+// CRL returns a DER encoded revocation list with given certificates revoked.
+func (ca *CA) CRL(nextUpdate time.Time, revoked ...*x509.Certificate) []byte {
+	var entries []pkix.RevokedCertificate
+	for _, c := range revoked {
+		entries = append(entries, pkix.RevokedCertificate{SerialNumber: c.SerialNumber, RevocationTime: time.Now()})
+	}
+	der, _ := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:              big.NewInt(time.Now().UnixNano()),
+		ThisUpdate:          time.Now().Add(-time.Minute),
+		NextUpdate:          nextUpdate,
+		RevokedCertificates: entries,
+	}, ca.Cert, ca.Key)
+	return der
+}
+
+// ConnectionState returns a TLS state as seen by a server that verified the client certificate,
+// with the chain to the last CA created with the name of its issuer.
+func ConnectionState(cert *x509.Certificate) *tls.ConnectionState {
+	state := UnverifiedConnectionState(cert)
+	if ca, ok := cas.Load(string(cert.RawIssuer)); ok {
+		state.VerifiedChains = [][]*x509.Certificate{{cert, ca.(*x509.Certificate)}}
+	}
+	return state
+}
+
+// UnverifiedConnectionState returns a TLS state as seen by a server that requests client certificates
+// without verifying them, e.g. with tls.RequestClientCert.
+func UnverifiedConnectionState(cert *x509.Certificate) *tls.ConnectionState {
+	return &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+}
@@ -0,0 +1,116 @@
+package tokens
+
+import (
+	"sync"
+	"time"
+)
+
+// RefreshToken is the record of an issued refresh token. Stores never see the token itself, only its ID.
+type RefreshToken struct {
+	// ID is the SHA-256 digest of the token in hex.
+	ID string
+	// Family is shared by the tokens that were rotated from the same login.
+	Family string
+	User   string
+	// Expires is when the token stops working and may be forgotten.
+	Expires time.Time
+	// Used is set when the token was exchanged for a new one.
+	Used bool
+}
+
+// Store keeps refresh tokens. Use a shared store, e.g. backed by a database or
+// Redis, when the service runs on several instances.
+type Store interface {
+	// Save stores a new refresh token.
+	Save(t RefreshToken) error
+	// Use marks the token as used and returns its record as it was before.
+	// It returns nil if the token is unknown, expired or revoked. It must be atomic,
+	// so a token can be used only once even if it is sent twice at the same time.
+	Use(id string) (*RefreshToken, error)
+	// RevokeFamily removes every token of the family.
+	RevokeFamily(family string) error
+	// RevokeUser removes every token of the user.
+	RevokeUser(user string) error
+}
+
+// MemoryStore keeps refresh tokens in memory. Tokens are lost when the process restarts.
+type MemoryStore struct {
+	// Now is used instead of time.Now when set.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	tokens  map[string]RefreshToken
+	cleaned time.Time
+}
+
+// Save stores the token. Expired tokens are removed once a minute.
+func (s *MemoryStore) Save(t RefreshToken) error {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tokens == nil {
+		s.tokens = make(map[string]RefreshToken)
+	}
+	if now.Sub(s.cleaned) > time.Minute {
+		for id, t := range s.tokens {
+			if !now.Before(t.Expires) {
+				delete(s.tokens, id)
+			}
+		}
+		s.cleaned = now
+	}
+	s.tokens[t.ID] = t
+	return nil
+}
+
+// Use marks the token as used.
+func (s *MemoryStore) Use(id string) (*RefreshToken, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tokens[id]
+	if !ok || !now.Before(t.Expires) {
+		return nil, nil
+	}
+	used := t
+	used.Used = true
+	s.tokens[id] = used
+	return &t, nil
+}
+
+// RevokeFamily removes the tokens of the family.
+func (s *MemoryStore) RevokeFamily(family string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, t := range s.tokens {
+		if t.Family == family {
+			delete(s.tokens, id)
+		}
+	}
+	return nil
+}
+
+// RevokeUser removes the tokens of the user.
+func (s *MemoryStore) RevokeUser(user string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, t := range s.tokens {
+		if t.User == user {
+			delete(s.tokens, id)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
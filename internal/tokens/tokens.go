@@ -0,0 +1,276 @@
+// Package tokens exchanges Basic credentials for short-lived signed access
+// tokens and rotating refresh tokens.
+//
+// Access tokens are HS256 JWTs verified without any lookup, so they can not
+// be revoked and should live only a few minutes. Refresh tokens are random
+// strings kept in a Store. Every refresh returns a new refresh token and
+// marks the old one as used; if a used token is presented again, it was
+// stolen or replayed, and every token rotated from the same login is revoked.
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/jwt"
+)
+
+var (
+	ErrNoSigningKey = errors.New("tokens: signing key is not set")
+	ErrInvalidToken = errors.New("tokens: invalid access token")
+
+	ErrInvalidGrant     = &Error{Code: "invalid_grant", Description: "refresh token is invalid or expired"}
+	ErrReused           = &Error{Code: "invalid_grant", Description: "refresh token was already used"}
+	ErrUnsupportedGrant = &Error{Code: "unsupported_grant_type", Description: "grant_type must be client_credentials or refresh_token"}
+)
+
+// Challenge is the WWW-Authenticate value sent when an access token is rejected.
+const Challenge = `Bearer realm="Authorization Required", error="invalid_token"`
+
+// Error is an OAuth 2.0 error response (RFC 6749 section 5.2).
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return "tokens: " + e.Description
+}
+
+// Response is an OAuth 2.0 token response (RFC 6749 section 5.1).
+type Response struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Config of issued tokens.
+type Config struct {
+	// Keys maps key ids to secrets access tokens are signed with. Tokens of any of the keys are accepted,
+	// so secrets can be rotated.
+	Keys map[string]string `json:"keys"`
+	// SigningKeyID is the key new tokens are signed with. It may be omitted if there is only one key.
+	SigningKeyID string `json:"signing_key_id"`
+	// Issuer is put in the iss claim and required from access tokens if it is set.
+	Issuer string `json:"issuer"`
+	// AccessTTL is the lifetime of access tokens. Default is 15 minutes.
+	AccessTTL time.Duration `json:"access_ttl"`
+	// RefreshTTL is the lifetime of refresh tokens. Every refresh starts a new one. Default is 7 days.
+	RefreshTTL time.Duration `json:"refresh_ttl"`
+	// Store keeps refresh tokens. Default is an in-memory store.
+	Store Store `json:"-"`
+
+	once   sync.Once
+	memory *MemoryStore
+}
+
+func (c *Config) store() Store {
+	if c.Store != nil {
+		return c.Store
+	}
+	// the default store lives in the config, so every service made of it shares the tokens
+	c.once.Do(func() {
+		c.memory = &MemoryStore{}
+	})
+	return c.memory
+}
+
+// Service issues, refreshes and verifies tokens.
+type Service struct {
+	Config *Config
+	// Now is used instead of time.Now when set.
+	Now func() time.Time
+
+	once     sync.Once
+	verifier *jwt.Verifier
+}
+
+// Issue starts a new token family of the user.
+func (s *Service) Issue(user string, roles []string) (*Response, error) {
+	return s.issue(user, roles, randomString())
+}
+
+// Refresh exchanges a refresh token for new tokens. Roles returns the current roles of the user
+// and false if the user does not exist anymore. A reused token revokes its family.
+func (s *Service) Refresh(token string, roles func(user string) ([]string, bool)) (*Response, error) {
+	store := s.Config.store()
+
+	rec, err := store.Use(tokenID(token))
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, ErrInvalidGrant
+	}
+	if rec.Used {
+		if err := store.RevokeFamily(rec.Family); err != nil {
+			return nil, err
+		}
+		return nil, ErrReused
+	}
+
+	userRoles, ok := roles(rec.User)
+	if !ok {
+		if err := store.RevokeFamily(rec.Family); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidGrant
+	}
+	return s.issue(rec.User, userRoles, rec.Family)
+}
+
+// Revoke revokes the family of a refresh token. Unknown tokens are ignored (RFC 7009).
+func (s *Service) Revoke(token string) error {
+	store := s.Config.store()
+
+	rec, err := store.Use(tokenID(token))
+	if err != nil || rec == nil {
+		return err
+	}
+	return store.RevokeFamily(rec.Family)
+}
+
+// RevokeUser revokes every refresh token of the user, e.g. after the password was changed.
+func (s *Service) RevokeUser(user string) error {
+	return s.Config.store().RevokeUser(user)
+}
+
+// Authenticate verifies the bearer access token of the request.
+func (s *Service) Authenticate(r *http.Request) (*auth.Principal, error) {
+	claims, err := s.getVerifier().VerifyRequest(r)
+	if errors.Is(err, auth.ErrNoCredentials) {
+		return nil, err
+	}
+	if err != nil || claims.String("token_use") != "access" {
+		return nil, ErrInvalidToken
+	}
+
+	principal := jwt.Principal(claims)
+	principal.Scheme = "token"
+	if roles, ok := claims.Raw["roles"].([]interface{}); ok {
+		for _, role := range roles {
+			if role, ok := role.(string); ok {
+				principal.Roles = append(principal.Roles, role)
+			}
+		}
+	}
+	return principal, nil
+}
+
+// Challenge returns the WWW-Authenticate value for a rejected access token.
+func (s *Service) Challenge(error) string {
+	return Challenge
+}
+
+func (s *Service) issue(user string, roles []string, family string) (*Response, error) {
+	keyID, secret, err := s.signingKey()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	claims := map[string]interface{}{
+		"sub":       user,
+		"iat":       now.Unix(),
+		"exp":       now.Add(s.accessTTL()).Unix(),
+		"jti":       randomString(),
+		"token_use": "access",
+	}
+	if s.Config.Issuer != "" {
+		claims["iss"] = s.Config.Issuer
+	}
+	if len(roles) > 0 {
+		claims["roles"] = roles
+	}
+	access, err := jwt.SignHMAC(jwt.HS256, keyID, []byte(secret), claims)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh := randomString()
+	err = s.Config.store().Save(RefreshToken{
+		ID:      tokenID(refresh),
+		Family:  family,
+		User:    user,
+		Expires: now.Add(s.refreshTTL()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.accessTTL().Seconds()),
+		RefreshToken: refresh,
+	}, nil
+}
+
+func (s *Service) signingKey() (string, string, error) {
+	keyID := s.Config.SigningKeyID
+	if keyID == "" && len(s.Config.Keys) == 1 {
+		for id := range s.Config.Keys {
+			keyID = id
+		}
+	}
+	secret, ok := s.Config.Keys[keyID]
+	if !ok {
+		return "", "", ErrNoSigningKey
+	}
+	return keyID, secret, nil
+}
+
+func (s *Service) getVerifier() *jwt.Verifier {
+	s.once.Do(func() {
+		s.verifier = &jwt.Verifier{
+			Keys:              &jwt.Keyring{Secrets: s.Config.Keys},
+			Algorithms:        []string{jwt.HS256},
+			RequireExpiration: true,
+			Now:               s.Now,
+		}
+		if s.Config.Issuer != "" {
+			s.verifier.Issuers = []string{s.Config.Issuer}
+		}
+	})
+	return s.verifier
+}
+
+func (s *Service) accessTTL() time.Duration {
+	if s.Config.AccessTTL > 0 {
+		return s.Config.AccessTTL
+	}
+	return 15 * time.Minute
+}
+
+func (s *Service) refreshTTL() time.Duration {
+	if s.Config.RefreshTTL > 0 {
+		return s.Config.RefreshTTL
+	}
+	return 7 * 24 * time.Hour
+}
+
+func (s *Service) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func tokenID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,100 @@
+package tokens
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"gotest.tools/assert"
+)
+
+func TestTokens(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+	s := &Service{
+		Config: &Config{
+			Keys:      map[string]string{"k1": "secret"},
+			Issuer:    "test",
+			AccessTTL: time.Minute,
+			Store:     &MemoryStore{Now: clock},
+		},
+		Now: clock,
+	}
+	roles := func(user string) ([]string, bool) {
+		return []string{"admin"}, user == "user1"
+	}
+
+	issued, err := s.Issue("user1", []string{"admin"})
+	assert.NilError(t, err)
+	assert.Equal(t, "Bearer", issued.TokenType)
+	assert.Equal(t, int64(60), issued.ExpiresIn)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+issued.AccessToken)
+	p, err := s.Authenticate(r)
+	assert.NilError(t, err)
+	assert.Equal(t, "user1", p.Name)
+	assert.Equal(t, "token", p.Scheme)
+	assert.DeepEqual(t, []string{"admin"}, p.Roles)
+
+	// refresh tokens are not access tokens
+	r.Header.Set("Authorization", "Bearer "+issued.RefreshToken)
+	_, err = s.Authenticate(r)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	now = now.Add(2 * time.Minute)
+	r.Header.Set("Authorization", "Bearer "+issued.AccessToken)
+	_, err = s.Authenticate(r)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	_, err = s.Authenticate(httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, auth.ErrNoCredentials, err)
+
+	// rotation
+	refreshed, err := s.Refresh(issued.RefreshToken, roles)
+	assert.NilError(t, err)
+	assert.Assert(t, refreshed.RefreshToken != issued.RefreshToken)
+
+	// reuse of the old token revokes the whole family
+	_, err = s.Refresh(issued.RefreshToken, roles)
+	assert.Equal(t, ErrReused, err)
+	_, err = s.Refresh(refreshed.RefreshToken, roles)
+	assert.Equal(t, ErrInvalidGrant, err)
+
+	// revocation
+	issued, err = s.Issue("user1", nil)
+	assert.NilError(t, err)
+	assert.NilError(t, s.Revoke(issued.RefreshToken))
+	_, err = s.Refresh(issued.RefreshToken, roles)
+	assert.Equal(t, ErrInvalidGrant, err)
+
+	issued, err = s.Issue("user1", nil)
+	assert.NilError(t, err)
+	assert.NilError(t, s.RevokeUser("user1"))
+	_, err = s.Refresh(issued.RefreshToken, roles)
+	assert.Equal(t, ErrInvalidGrant, err)
+
+	// removed users can not refresh
+	issued, err = s.Issue("user2", nil)
+	assert.NilError(t, err)
+	_, err = s.Refresh(issued.RefreshToken, roles)
+	assert.Equal(t, ErrInvalidGrant, err)
+
+	// expiry
+	issued, err = s.Issue("user1", nil)
+	assert.NilError(t, err)
+	now = now.Add(8 * 24 * time.Hour)
+	_, err = s.Refresh(issued.RefreshToken, roles)
+	assert.Equal(t, ErrInvalidGrant, err)
+}
+
+func TestSigningKey(t *testing.T) {
+	s := &Service{Config: &Config{Keys: map[string]string{"k1": "a", "k2": "b"}}}
+	_, err := s.Issue("user1", nil)
+	assert.Equal(t, ErrNoSigningKey, err)
+
+	s.Config.SigningKeyID = "k2"
+	_, err = s.Issue("user1", nil)
+	assert.NilError(t, err)
+}
@@ -0,0 +1,199 @@
+// Package totp implements time-based one-time passwords (RFC 6238) as the
+// second factor of basic auth users. Codes are HMAC-SHA1 of the time step,
+// which is what authenticator apps generate by default.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/nonce"
+)
+
+var (
+	ErrSecret      = errors.New("totp: invalid secret")
+	ErrInvalidCode = errors.New("totp: invalid code")
+	ErrReplay      = errors.New("totp: code was already used")
+)
+
+// DefaultHeader is the request header the code may be sent in.
+const DefaultHeader = "X-OTP"
+
+// Config of one-time codes.
+type Config struct {
+	// Header the code is read from. If the header is missing, the code is taken from the end of the password.
+	// Default is X-OTP.
+	Header string `json:"header"`
+	// Digits of a code, 6 to 8. Default is 6.
+	Digits int `json:"digits"`
+	// Period is the time step, a whole number of seconds. Default is 30 seconds.
+	// In JSON it is a number of nanoseconds, e.g. 30000000000.
+	Period time.Duration `json:"period"`
+	// Window is how many steps before and after the current one are accepted, to allow for clock drift. Default is 1.
+	Window int `json:"window"`
+	// UsedCodes remembers used codes, so a code can not be replayed. Default is an in-memory store,
+	// use a shared one if the service has several instances.
+	UsedCodes nonce.Store `json:"-"`
+
+	once   sync.Once
+	memory *nonce.Memory
+}
+
+// Validate checks the digits, the period and the window. Zero values are replaced by the defaults.
+func (c *Config) Validate() error {
+	if c.Window < 0 {
+		return fmt.Errorf("totp: window %d is negative", c.Window)
+	}
+	return check(c.Period, c.Digits)
+}
+
+// check validates the period and the digits unless they are zero.
+func check(period time.Duration, digits int) error {
+	if period != 0 && (period < time.Second || period%time.Second != 0) {
+		return fmt.Errorf("totp: period %s is not a whole number of seconds", period)
+	}
+	if digits != 0 && (digits < 6 || digits > 8) {
+		return fmt.Errorf("totp: digits %d are not in 6..8", digits)
+	}
+	return nil
+}
+
+func (c *Config) usedCodes() nonce.Store {
+	if c.UsedCodes != nil {
+		return c.UsedCodes
+	}
+	c.once.Do(func() {
+		c.memory = &nonce.Memory{}
+	})
+	return c.memory
+}
+
+// HeaderName returns the request header the code may be sent in.
+func (c *Config) HeaderName() string {
+	if c.Header != "" {
+		return c.Header
+	}
+	return DefaultHeader
+}
+
+// CodeDigits returns the number of digits of a code.
+func (c *Config) CodeDigits() int {
+	if c.Digits > 0 {
+		return c.Digits
+	}
+	return 6
+}
+
+func (c *Config) period() time.Duration {
+	if c.Period > 0 {
+		return c.Period
+	}
+	return 30 * time.Second
+}
+
+func (c *Config) window() int {
+	if c.Window > 0 {
+		return c.Window
+	}
+	return 1
+}
+
+// Verifier checks codes of users.
+type Verifier struct {
+	Config *Config
+	// Now is used instead of time.Now when set.
+	Now func() time.Time
+}
+
+// Verify checks the code of the user against the base32 secret. A code is accepted only once.
+// It fails for an invalid Config, see Config.Validate.
+func (v *Verifier) Verify(user, secret, code string) error {
+	if err := v.Config.Validate(); err != nil {
+		return err
+	}
+	key, err := DecodeSecret(secret)
+	if err != nil {
+		return err
+	}
+	if len(code) != v.Config.CodeDigits() {
+		return ErrInvalidCode
+	}
+
+	period := v.Config.period()
+	window := v.Config.window()
+	current := v.now().Unix() / int64(period.Seconds())
+
+	for i := -window; i <= window; i++ {
+		step := current + int64(i)
+		if subtle.ConstantTimeCompare([]byte(generate(key, step, v.Config.CodeDigits())), []byte(code)) != 1 {
+			continue
+		}
+		// the code stays acceptable until the last step of the window has passed
+		expires := time.Unix((step+int64(window)+1)*int64(period.Seconds()), 0)
+		if !v.Config.usedCodes().Use(user, strconv.FormatInt(step, 10), expires) {
+			return ErrReplay
+		}
+		return nil
+	}
+	return ErrInvalidCode
+}
+
+func (v *Verifier) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+	return time.Now()
+}
+
+// Generate returns the code of the secret at t. The period must be a whole number of seconds
+// and digits must be 6 to 8.
+func Generate(secret string, t time.Time, period time.Duration, digits int) (string, error) {
+	if period == 0 || digits == 0 {
+		return "", fmt.Errorf("totp: period and digits must be set")
+	}
+	if err := check(period, digits); err != nil {
+		return "", err
+	}
+	key, err := DecodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return generate(key, t.Unix()/int64(period.Seconds()), digits), nil
+}
+
+// DecodeSecret decodes a base32 secret as shown by authenticator apps. Spaces, padding and case are ignored.
+func DecodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(secret, "="))
+	if err != nil || len(key) == 0 {
+		return nil, ErrSecret
+	}
+	return key, nil
+}
+
+// generate computes HOTP (RFC 4226) of the step.
+func generate(key []byte, step int64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
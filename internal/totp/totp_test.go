@@ -0,0 +1,69 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/nonce"
+	"gotest.tools/assert"
+)
+
+// secret of the RFC 6238 test vectors
+var secret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestGenerate(t *testing.T) {
+	for unix, code := range map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1234567890:  "89005924",
+		20000000000: "65353130",
+	} {
+		got, err := Generate(secret, time.Unix(unix, 0), 30*time.Second, 8)
+		assert.NilError(t, err)
+		assert.Equal(t, code, got)
+	}
+
+	_, err := Generate("not base32!", time.Now(), 30*time.Second, 6)
+	assert.Equal(t, ErrSecret, err)
+	_, err = Generate(secret, time.Now(), 30, 6)
+	assert.ErrorContains(t, err, "not a whole number of seconds")
+	_, err = Generate(secret, time.Now(), 30*time.Second, 10)
+	assert.ErrorContains(t, err, "not in 6..8")
+}
+
+func TestValidate(t *testing.T) {
+	assert.NilError(t, (&Config{}).Validate())
+	assert.NilError(t, (&Config{Digits: 8, Period: time.Minute, Window: 2}).Validate())
+
+	// "period": 30 in JSON is 30 nanoseconds
+	assert.ErrorContains(t, (&Config{Period: 30}).Validate(), "period 30ns is not a whole number of seconds")
+	assert.ErrorContains(t, (&Config{Period: 1500 * time.Millisecond}).Validate(), "not a whole number of seconds")
+	assert.ErrorContains(t, (&Config{Digits: 10}).Validate(), "digits 10 are not in 6..8")
+	assert.ErrorContains(t, (&Config{Digits: 4}).Validate(), "not in 6..8")
+	assert.ErrorContains(t, (&Config{Window: -1}).Validate(), "window -1 is negative")
+
+	v := &Verifier{Config: &Config{Period: 30}}
+	assert.ErrorContains(t, v.Verify("user1", secret, "123456"), "not a whole number of seconds")
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+	v := &Verifier{Config: &Config{UsedCodes: &nonce.Memory{Now: clock}}, Now: clock}
+
+	code, _ := Generate(secret, now, 30*time.Second, 6)
+	assert.NilError(t, v.Verify("user1", secret, code))
+	assert.Equal(t, ErrReplay, v.Verify("user1", secret, code))
+
+	// the previous step is in the window
+	previous, _ := Generate(secret, now.Add(-30*time.Second), 30*time.Second, 6)
+	assert.NilError(t, v.Verify("user1", secret, previous))
+
+	old, _ := Generate(secret, now.Add(-2*time.Minute), 30*time.Second, 6)
+	assert.Equal(t, ErrInvalidCode, v.Verify("user1", secret, old))
+	assert.Equal(t, ErrInvalidCode, v.Verify("user1", secret, "12345"))
+
+	v.Config.Window = 4
+	assert.NilError(t, v.Verify("user1", secret, old))
+}
@@ -0,0 +1,272 @@
+// Package userstore reads and writes basicauth users in htpasswd and JSON files.
+package userstore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/golanguzb70/middleware/internal/basic"
+	"github.com/golanguzb70/middleware/internal/passwd"
+)
+
+// Load reads the users of a file. Files with the .json extension hold a JSON array of users.
+// Other files are htpasswd files: one user:hash line per user and # comments.
+// A ! before the hash disables the user.
+func Load(path string) ([]basic.User, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("userstore: %w", err)
+	}
+
+	var users []basic.User
+	if isJSON(path) {
+		err = json.Unmarshal(b, &users)
+	} else {
+		users, err = ParseHtpasswd(b)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("userstore: %s: %w", path, err)
+	}
+	return users, nil
+}
+
+// Save writes the users to a file in the format Load reads. The file is replaced atomically
+// and keeps its permissions; new files are readable by the owner only.
+func Save(path string, users []basic.User) error {
+	var (
+		b   []byte
+		err error
+	)
+	if isJSON(path) {
+		b, err = json.MarshalIndent(users, "", "  ")
+		b = append(b, '\n')
+	} else {
+		b, err = FormatHtpasswd(users)
+	}
+	if err != nil {
+		return fmt.Errorf("userstore: %s: %w", path, err)
+	}
+
+	if err := writeFile(path, b); err != nil {
+		return fmt.Errorf("userstore: %w", err)
+	}
+	return nil
+}
+
+// writeFile replaces the file atomically, keeping its permissions.
+func writeFile(path string, b []byte) error {
+	mode := fs.FileMode(0o600)
+	if st, err := os.Stat(path); err == nil {
+		mode = st.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// File is a users file that keeps the hashes upgraded on login, see basic.CredentialStore.
+type File struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// Rehash replaces oldHash of the user in the file with newHash. The rest of the file is kept as it is,
+// including comments of htpasswd files and fields of JSON files users do not have.
+func (f *File) Rehash(username, oldHash, newHash string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b, err := os.ReadFile(f.Path)
+	if err != nil {
+		return fmt.Errorf("userstore: %w", err)
+	}
+	var ok bool
+	if isJSON(f.Path) {
+		b, ok, err = rehashJSON(b, username, oldHash, newHash)
+	} else {
+		b, ok = rehashHtpasswd(b, username, oldHash, newHash)
+	}
+	if err != nil {
+		return fmt.Errorf("userstore: %s: %w", f.Path, err)
+	}
+	if !ok {
+		return fmt.Errorf("userstore: %s: user %q has no such hash", f.Path, username)
+	}
+	if err := writeFile(f.Path, b); err != nil {
+		return fmt.Errorf("userstore: %w", err)
+	}
+	return nil
+}
+
+// rehashHtpasswd replaces the hash on the line of the user.
+func rehashHtpasswd(b []byte, username, oldHash, newHash string) ([]byte, bool) {
+	lines := bytes.SplitAfter(b, []byte("\n"))
+	for i, line := range lines {
+		text := strings.TrimSpace(string(line))
+		name, hash, found := strings.Cut(text, ":")
+		if !found || name != username || strings.TrimPrefix(hash, "!") != oldHash {
+			continue
+		}
+		colon := bytes.IndexByte(line, ':')
+		lines[i] = append(line[:colon+1:colon+1], bytes.Replace(line[colon+1:], []byte(oldHash), []byte(newHash), 1)...)
+		return bytes.Join(lines, nil), true
+	}
+	return b, false
+}
+
+// rehashJSON replaces the hash in the object of the user, the first string equal to it.
+func rehashJSON(b []byte, username, oldHash, newHash string) ([]byte, bool, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if _, err := dec.Token(); err != nil {
+		return nil, false, err
+	}
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, false, err
+		}
+		end := int(dec.InputOffset())
+		start := end - len(raw)
+
+		var user basic.User
+		if err := json.Unmarshal(raw, &user); err != nil {
+			return nil, false, err
+		}
+		if user.UserName != username {
+			continue
+		}
+		if from, to, ok := findString(raw, oldHash); ok {
+			quoted, err := json.Marshal(newHash)
+			if err != nil {
+				return nil, false, err
+			}
+			var out []byte
+			out = append(out, b[:start+from]...)
+			out = append(out, quoted...)
+			out = append(out, b[start+to:]...)
+			return out, true, nil
+		}
+	}
+	return b, false, nil
+}
+
+// findString returns the position of the first JSON string in raw that decodes to s.
+func findString(raw []byte, s string) (from, to int, ok bool) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	for {
+		prev := int(dec.InputOffset())
+		tok, err := dec.Token()
+		if err != nil {
+			return 0, 0, false
+		}
+		if str, isString := tok.(string); isString && str == s {
+			end := int(dec.InputOffset())
+			// the token starts at its quote, after white space and separators
+			return prev + bytes.IndexByte(raw[prev:end], '"'), end, true
+		}
+	}
+}
+
+// ParseHtpasswd parses htpasswd lines. Only bcrypt and argon2id hashes are accepted.
+func ParseHtpasswd(b []byte) ([]basic.User, error) {
+	var users []basic.User
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		name, hash, ok := strings.Cut(text, ":")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("line %d: not user:hash", line)
+		}
+		user := basic.User{UserName: name}
+		if strings.HasPrefix(hash, "!") {
+			user.Disabled = true
+			hash = hash[1:]
+		}
+		if !passwd.Supported(hash) {
+			return nil, fmt.Errorf("line %d: user %q: %w", line, name, passwd.ErrUnsupported)
+		}
+		user.Password = hash
+		users = append(users, user)
+	}
+	return users, scanner.Err()
+}
+
+// FormatHtpasswd formats users as htpasswd lines. Users must have a bcrypt or argon2id hash and
+// no other fields than Disabled; keep roles, credentials and restrictions in JSON files.
+func FormatHtpasswd(users []basic.User) ([]byte, error) {
+	var b bytes.Buffer
+	for _, u := range users {
+		if err := ValidName(u.UserName); err != nil {
+			return nil, err
+		}
+		if !passwd.Supported(u.Password) {
+			return nil, fmt.Errorf("user %q: %w", u.UserName, passwd.ErrUnsupported)
+		}
+		if len(u.Roles) > 0 || u.TOTPSecret != "" || len(u.Credentials) > 0 || u.ExpiresAt != (time.Time{}) ||
+			len(u.AllowedCIDRs) > 0 || u.AccessWindow != nil {
+			return nil, fmt.Errorf("user %q has fields htpasswd files can not hold, use a JSON file", u.UserName)
+		}
+
+		b.WriteString(u.UserName)
+		b.WriteByte(':')
+		if u.Disabled {
+			b.WriteByte('!')
+		}
+		b.WriteString(u.Password)
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+// ValidName rejects user names an htpasswd line can not hold: empty names, names with a colon,
+// control characters or spaces around them, and names starting with #, which are read as comments.
+func ValidName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("user name is empty")
+	case strings.HasPrefix(name, "#"):
+		return fmt.Errorf("user %q: name starts with #", name)
+	case strings.TrimSpace(name) != name:
+		return fmt.Errorf("user %q: name starts or ends with a space", name)
+	case strings.Contains(name, ":"):
+		return fmt.Errorf("user %q: name contains a colon", name)
+	case strings.IndexFunc(name, unicode.IsControl) >= 0:
+		return fmt.Errorf("user %q: name contains a control character", name)
+	}
+	return nil
+}
+
+func isJSON(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
@@ -0,0 +1,116 @@
+package userstore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golanguzb70/middleware/internal/basic"
+	"gotest.tools/assert"
+)
+
+const hash = "$2y$05$oopEP8g.9wUslDQcAtnuqOdFGeHRnJHMIcWH9xKSizs5yhumWMMnq"
+
+func TestHtpasswd(t *testing.T) {
+	users, err := ParseHtpasswd([]byte("# ops users\nadmin:" + hash + "\n\nold:!" + hash + "\n"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []basic.User{
+		{UserName: "admin", Password: hash},
+		{UserName: "old", Password: hash, Disabled: true},
+	}, users)
+
+	b, err := FormatHtpasswd(users)
+	assert.NilError(t, err)
+	assert.Equal(t, "admin:"+hash+"\nold:!"+hash+"\n", string(b))
+
+	_, err = ParseHtpasswd([]byte("admin:$apr1$salt$hash\n"))
+	assert.ErrorContains(t, err, `line 1: user "admin": passwd: unsupported hash`)
+	_, err = ParseHtpasswd([]byte("admin:password\n"))
+	assert.ErrorContains(t, err, "unsupported hash")
+
+	_, err = FormatHtpasswd([]basic.User{{UserName: "admin", Password: hash, Roles: []string{"admin"}}})
+	assert.ErrorContains(t, err, "use a JSON file")
+}
+
+func TestValidName(t *testing.T) {
+	assert.NilError(t, ValidName("ops.admin@example.com"))
+	for _, name := range []string{"", "a:b", "admin\nroot:" + hash, "#admin", " admin", "ad\tmin"} {
+		assert.Assert(t, ValidName(name) != nil, name)
+		_, err := FormatHtpasswd([]basic.User{{UserName: name, Password: hash}})
+		assert.Assert(t, err != nil, name)
+	}
+}
+
+func TestSave(t *testing.T) {
+	dir := t.TempDir()
+	users := []basic.User{{UserName: "admin", Password: hash, Roles: []string{"admin"}}}
+
+	path := filepath.Join(dir, "users.json")
+	assert.NilError(t, Save(path, users))
+	loaded, err := Load(path)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, users, loaded)
+
+	st, err := os.Stat(path)
+	assert.NilError(t, err)
+	assert.Equal(t, os.FileMode(0o600), st.Mode().Perm())
+
+	// permissions of existing files are kept
+	path = filepath.Join(dir, "users.htpasswd")
+	assert.NilError(t, os.WriteFile(path, nil, 0o640))
+	assert.NilError(t, Save(path, []basic.User{{UserName: "admin", Password: hash}}))
+	st, err = os.Stat(path)
+	assert.NilError(t, err)
+	assert.Equal(t, os.FileMode(0o640), st.Mode().Perm())
+
+	_, err = Load(filepath.Join(dir, "missing"))
+	assert.ErrorContains(t, err, "userstore:")
+}
+
+func TestFileRehash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	assert.NilError(t, Save(path, []basic.User{
+		{UserName: "admin", Password: hash},
+		{UserName: "ops", Credentials: []basic.Credential{{Password: "$2y$05$old"}, {Password: hash}}},
+	}))
+
+	f := &File{Path: path}
+	assert.NilError(t, f.Rehash("admin", hash, "$argon2id$new"))
+	assert.NilError(t, f.Rehash("ops", hash, "$argon2id$new"))
+	assert.ErrorContains(t, f.Rehash("ops", hash, "$argon2id$new"), `user "ops" has no such hash`)
+
+	users, err := Load(path)
+	assert.NilError(t, err)
+	assert.Equal(t, "$argon2id$new", users[0].Password)
+	assert.Equal(t, "$2y$05$old", users[1].Credentials[0].Password)
+	assert.Equal(t, "$argon2id$new", users[1].Credentials[1].Password)
+}
+
+func TestFileRehashKeepsFile(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "users.htpasswd")
+	assert.NilError(t, os.WriteFile(path, []byte("# ops users\nadmin:"+hash+"\n\n# disabled\nold:!"+hash+"\n"), 0o600))
+	f := &File{Path: path}
+	assert.NilError(t, f.Rehash("old", hash, "$argon2id$new"))
+	b, err := os.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Equal(t, "# ops users\nadmin:"+hash+"\n\n# disabled\nold:!$argon2id$new\n", string(b))
+
+	path = filepath.Join(dir, "users.json")
+	content := `[
+  {"user_name": "admin", "password": "` + hash + `", "team": "ops"},
+  {"user_name": "ops", "password": "` + hash + `", "team": "ops"}
+]
+`
+	assert.NilError(t, os.WriteFile(path, []byte(content), 0o600))
+	f = &File{Path: path}
+	assert.NilError(t, f.Rehash("ops", hash, "$argon2id$new"))
+	b, err = os.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Equal(t, `[
+  {"user_name": "admin", "password": "`+hash+`", "team": "ops"},
+  {"user_name": "ops", "password": "$argon2id$new", "team": "ops"}
+]
+`, string(b))
+}
@@ -0,0 +1,148 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Preset describes how a provider signs its webhooks.
+type Preset struct {
+	Name string
+	// Timestamped presets sign the time of delivery, it is checked against the tolerance.
+	Timestamped bool
+
+	// parse returns the signed content, candidate signatures and the signing time.
+	parse func(h http.Header, body []byte) (signed []byte, sigs [][]byte, t time.Time, err error)
+	// sign sets the signature headers.
+	sign func(h http.Header, secret string, body []byte, t time.Time)
+}
+
+// GitHub signs the body with HMAC-SHA256, X-Hub-Signature-256: sha256=<hex>.
+var GitHub = Preset{
+	Name: "github",
+	parse: func(h http.Header, body []byte) ([]byte, [][]byte, time.Time, error) {
+		value := h.Get("X-Hub-Signature-256")
+		if value == "" {
+			return nil, nil, time.Time{}, errNoSignature
+		}
+		sig, err := decodeHex(value, "sha256=")
+		if err != nil {
+			return nil, nil, time.Time{}, err
+		}
+		return body, [][]byte{sig}, time.Time{}, nil
+	},
+	sign: func(h http.Header, secret string, body []byte, t time.Time) {
+		h.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac(secret, body)))
+	},
+}
+
+// Stripe signs "<timestamp>.<body>", Stripe-Signature: t=<unix>,v1=<hex>[,v1=<hex>].
+// Several v1 signatures are sent while the endpoint secret is rolled.
+var Stripe = Preset{
+	Name:        "stripe",
+	Timestamped: true,
+	parse: func(h http.Header, body []byte) ([]byte, [][]byte, time.Time, error) {
+		value := h.Get("Stripe-Signature")
+		if value == "" {
+			return nil, nil, time.Time{}, errNoSignature
+		}
+		var (
+			timestamp string
+			sigs      [][]byte
+		)
+		for _, part := range strings.Split(value, ",") {
+			k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch k {
+			case "t":
+				timestamp = v
+			case "v1":
+				sig, err := decodeHex(v, "")
+				if err != nil {
+					return nil, nil, time.Time{}, err
+				}
+				sigs = append(sigs, sig)
+			}
+		}
+		t, err := parseUnix(timestamp)
+		if err != nil || len(sigs) == 0 {
+			return nil, nil, time.Time{}, ErrMalformed
+		}
+		return append([]byte(timestamp+"."), body...), sigs, t, nil
+	},
+	sign: func(h http.Header, secret string, body []byte, t time.Time) {
+		timestamp := strconv.FormatInt(t.Unix(), 10)
+		sig := mac(secret, append([]byte(timestamp+"."), body...))
+		h.Set("Stripe-Signature", "t="+timestamp+",v1="+hex.EncodeToString(sig))
+	},
+}
+
+// Slack signs "v0:<timestamp>:<body>", X-Slack-Signature: v0=<hex> and
+// X-Slack-Request-Timestamp: <unix>.
+var Slack = Preset{
+	Name:        "slack",
+	Timestamped: true,
+	parse: func(h http.Header, body []byte) ([]byte, [][]byte, time.Time, error) {
+		value := h.Get("X-Slack-Signature")
+		if value == "" {
+			return nil, nil, time.Time{}, errNoSignature
+		}
+		sig, err := decodeHex(value, "v0=")
+		if err != nil {
+			return nil, nil, time.Time{}, err
+		}
+		timestamp := h.Get("X-Slack-Request-Timestamp")
+		t, err := parseUnix(timestamp)
+		if err != nil {
+			return nil, nil, time.Time{}, ErrMalformed
+		}
+		return append([]byte("v0:"+timestamp+":"), body...), [][]byte{sig}, t, nil
+	},
+	sign: func(h http.Header, secret string, body []byte, t time.Time) {
+		timestamp := strconv.FormatInt(t.Unix(), 10)
+		h.Set("X-Slack-Request-Timestamp", timestamp)
+		h.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac(secret, append([]byte("v0:"+timestamp+":"), body...))))
+	},
+}
+
+// Presets are the known providers by name.
+var Presets = map[string]Preset{
+	GitHub.Name: GitHub,
+	Stripe.Name: Stripe,
+	Slack.Name:  Slack,
+}
+
+// Sign sets the signature headers of the preset, e.g. to test a handler.
+func (p Preset) Sign(h http.Header, secret string, body []byte, t time.Time) {
+	p.sign(h, secret, body, t)
+}
+
+func mac(secret string, content []byte) []byte {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write(content)
+	return m.Sum(nil)
+}
+
+// decodeHex decodes a hex SHA-256 signature that must start with prefix.
+func decodeHex(value, prefix string) ([]byte, error) {
+	if !strings.HasPrefix(value, prefix) {
+		return nil, ErrMalformed
+	}
+	b, err := hex.DecodeString(strings.TrimPrefix(value, prefix))
+	if err != nil || len(b) != sha256.Size {
+		return nil, ErrMalformed
+	}
+	return b, nil
+}
+
+func parseUnix(s string) (time.Time, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(n, 0), nil
+}
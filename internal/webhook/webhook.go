@@ -0,0 +1,100 @@
+// Package webhook verifies HMAC signatures of webhooks sent by providers such
+// as GitHub, Stripe and Slack. It is shared by the gin and gorilla webhookauth
+// middlewares.
+package webhook
+
+import (
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"github.com/golanguzb70/middleware/internal/reqbody"
+)
+
+var (
+	ErrUnknownProvider = errors.New("webhook: unknown provider")
+	ErrMalformed       = errors.New("webhook: malformed signature")
+	ErrSignature       = errors.New("webhook: signature mismatch")
+	ErrTimestamp       = errors.New("webhook: timestamp is outside of the tolerance")
+	ErrBodyTooLarge    = reqbody.ErrTooLarge
+
+	errNoSignature = fmt.Errorf("webhook: no signature: %w", auth.ErrNoCredentials)
+)
+
+// Verifier checks webhook signatures of one provider.
+type Verifier struct {
+	Preset Preset
+	// Secrets are tried in order, so a new secret can be added before the provider starts using it.
+	Secrets []string
+	// Tolerance is the maximum age of a timestamped delivery. Default is five minutes.
+	Tolerance time.Duration
+	// MaxBodySize limits the body that is read. Default is 10 MB.
+	MaxBodySize int64
+	// Now is used instead of time.Now when set.
+	Now func() time.Time
+}
+
+// NewVerifier returns a verifier of the named preset.
+func NewVerifier(provider string, secrets []string) (*Verifier, error) {
+	preset, ok := Presets[provider]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, provider)
+	}
+	return &Verifier{Preset: preset, Secrets: secrets}, nil
+}
+
+// Verify checks the signature of the request. The body is read and replaced,
+// so the handler can still read it.
+func (v *Verifier) Verify(r *http.Request) error {
+	if v.Preset.parse == nil {
+		return ErrUnknownProvider
+	}
+	body, err := reqbody.Read(r, v.MaxBodySize)
+	if err != nil {
+		return err
+	}
+	signed, sigs, t, err := v.Preset.parse(r.Header, body)
+	if err != nil {
+		return err
+	}
+
+	if v.Preset.Timestamped {
+		now := time.Now()
+		if v.Now != nil {
+			now = v.Now()
+		}
+		tolerance := v.Tolerance
+		if tolerance <= 0 {
+			tolerance = 5 * time.Minute
+		}
+		if d := now.Sub(t); d > tolerance || d < -tolerance {
+			return ErrTimestamp
+		}
+	}
+
+	for _, secret := range v.Secrets {
+		expected := mac(secret, signed)
+		for _, sig := range sigs {
+			if hmac.Equal(expected, sig) {
+				return nil
+			}
+		}
+	}
+	return ErrSignature
+}
+
+// Authenticate verifies the request and returns the provider as principal.
+func (v *Verifier) Authenticate(r *http.Request) (*auth.Principal, error) {
+	if err := v.Verify(r); err != nil {
+		return nil, err
+	}
+	return &auth.Principal{Name: v.Preset.Name, Scheme: "webhook"}, nil
+}
+
+// Challenge returns an empty value, webhook providers do not react to challenges.
+func (v *Verifier) Challenge(error) string {
+	return ""
+}
@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/middleware/internal/auth"
+	"gotest.tools/assert"
+)
+
+func TestGitHub(t *testing.T) {
+	// example of the GitHub documentation
+	v, err := NewVerifier("github", []string{"It's a Secret to Everybody"})
+	assert.NilError(t, err)
+
+	req := httptest.NewRequest("POST", "/webhooks/github", strings.NewReader("Hello, World!"))
+	req.Header.Set("X-Hub-Signature-256", "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17")
+	assert.NilError(t, v.Verify(req))
+	body, _ := io.ReadAll(req.Body)
+	assert.Equal(t, "Hello, World!", string(body))
+
+	req = httptest.NewRequest("POST", "/webhooks/github", strings.NewReader("Hello, World?"))
+	req.Header.Set("X-Hub-Signature-256", "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17")
+	assert.Assert(t, errors.Is(v.Verify(req), ErrSignature))
+
+	req = httptest.NewRequest("POST", "/webhooks/github", strings.NewReader("Hello, World!"))
+	req.Header.Set("X-Hub-Signature-256", "757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17")
+	assert.Assert(t, errors.Is(v.Verify(req), ErrMalformed))
+
+	req = httptest.NewRequest("POST", "/webhooks/github", strings.NewReader("Hello, World!"))
+	assert.Assert(t, errors.Is(v.Verify(req), auth.ErrNoCredentials))
+}
+
+func TestTimestamped(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	for _, preset := range []Preset{Stripe, Slack} {
+		v := &Verifier{
+			Preset:  preset,
+			Secrets: []string{"new-secret", "old-secret"},
+			Now:     func() time.Time { return now },
+		}
+
+		// secret rotation: both secrets are accepted
+		for _, secret := range []string{"old-secret", "new-secret"} {
+			req := httptest.NewRequest("POST", "/", strings.NewReader(`{"id":1}`))
+			preset.Sign(req.Header, secret, []byte(`{"id":1}`), now.Add(-time.Minute))
+			assert.NilError(t, v.Verify(req), preset.Name)
+		}
+
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"id":1}`))
+		preset.Sign(req.Header, "new-secret", []byte(`{"id":1}`), now.Add(-10*time.Minute))
+		assert.Assert(t, errors.Is(v.Verify(req), ErrTimestamp), preset.Name)
+
+		req = httptest.NewRequest("POST", "/", strings.NewReader(`{"id":1}`))
+		preset.Sign(req.Header, "unknown", []byte(`{"id":1}`), now)
+		assert.Assert(t, errors.Is(v.Verify(req), ErrSignature), preset.Name)
+	}
+}
+
+func TestStripeSeveralSignatures(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	v := &Verifier{Preset: Stripe, Secrets: []string{"secret"}, Now: func() time.Time { return now }}
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader("{}"))
+	Stripe.Sign(req.Header, "secret", []byte("{}"), now)
+	valid := req.Header.Get("Stripe-Signature")
+	req.Header.Set("Stripe-Signature", "t=1700000000,v1="+strings.Repeat("ab", 32)+valid[len("t=1700000000"):]+",v0=ignored")
+	assert.NilError(t, v.Verify(req))
+}
+
+func TestUnknownProvider(t *testing.T) {
+	_, err := NewVerifier("bitbucket", nil)
+	assert.Assert(t, errors.Is(err, ErrUnknownProvider))
+}